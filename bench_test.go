@@ -3,8 +3,12 @@ package cache
 import (
 	"fmt"
 	"math/rand"
+	"runtime"
+	"runtime/debug"
+	"strconv"
 	"strings"
 	"testing"
+	"time"
 )
 
 func Benchmark_Get(b *testing.B) {
@@ -21,6 +25,18 @@ func Benchmark_Get(b *testing.B) {
 	b.ReportAllocs()
 }
 
+// Expiration reads the time from the coarse clock, so Get and Put don't allocate (run with -benchmem).
+func Benchmark_GetPutWithExpireAfterWrite(b *testing.B) {
+	cache := NewCache(WithExpireAfterWrite[int, int](time.Minute))
+	defer cache.Close()
+
+	b.ReportAllocs()
+	for n := 0; n < b.N; n++ {
+		cache.Put(n&1023, n)
+		cache.Get(n & 1023)
+	}
+}
+
 func Benchmark_GetPutMultipleConcurrent(b *testing.B) {
 	cache := NewCache[string, string]()
 	data := map[string]string{
@@ -115,3 +131,93 @@ func Benchmark_CountConcurrently(b *testing.B) {
 	})
 	b.ReportAllocs()
 }
+
+// The duration of a forced GC with a million items in the cache, mostly spent on marking.
+func Benchmark_GCPauseCache(b *testing.B) {
+	cache := NewCache[string, []byte]()
+	defer cache.Close()
+
+	benchmarkGCPause(b, cache)
+}
+
+func Benchmark_GCPauseByteCache(b *testing.B) {
+	cache, _ := NewByteCache(256 << 20)
+	defer cache.Close()
+
+	benchmarkGCPause(b, cache)
+}
+
+func Benchmark_GetConcurrentlyByteCache(b *testing.B) {
+	cache, _ := NewByteCache(256 << 20)
+	defer cache.Close()
+
+	n := 100000
+	value := []byte(strings.Repeat("a", 256))
+	for i := 0; i < n; i++ {
+		cache.Put(strconv.Itoa(i), value)
+	}
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			key := strconv.Itoa(rand.Intn(n))
+			if _, ok := cache.Get(key); !ok {
+				b.Errorf("key: %v; not found", key)
+			}
+		}
+	})
+	b.ReportAllocs()
+}
+
+func benchmarkGCPause(b *testing.B, cache Cache[string, []byte]) {
+	const n = 1_000_000
+
+	value := make([]byte, 64)
+	for i := 0; i < n; i++ {
+		cache.Put(strconv.Itoa(i), value)
+	}
+	runtime.GC()
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		runtime.GC()
+	}
+	b.StopTimer()
+
+	var stats debug.GCStats
+	debug.ReadGCStats(&stats)
+	b.ReportMetric(float64(stats.Pause[0].Nanoseconds()), "last-pause-ns")
+
+	runtime.KeepAlive(cache)
+}
+
+func Benchmark_Stores(b *testing.B) {
+	const n = 100000
+
+	for _, storeType := range storeTypes {
+		cache := NewCache(WithStore[int, int](storeType))
+		for i := 0; i < n; i++ {
+			cache.Put(i, i)
+		}
+
+		b.Run(storeType.String()+"/read", func(b *testing.B) {
+			b.RunParallel(func(pb *testing.PB) {
+				for pb.Next() {
+					cache.Get(rand.Intn(n))
+				}
+			})
+			b.ReportAllocs()
+		})
+
+		b.Run(storeType.String()+"/write", func(b *testing.B) {
+			b.RunParallel(func(pb *testing.PB) {
+				for pb.Next() {
+					key := rand.Intn(n)
+					cache.Put(key, key)
+				}
+			})
+			b.ReportAllocs()
+		})
+
+		cache.Close()
+	}
+}
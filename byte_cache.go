@@ -0,0 +1,437 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash/maphash"
+	"io"
+	"log/slog"
+	"math"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	byteCacheShardCount = 64
+
+	minByteCacheShardSize = 64 << 10
+	// offsets are stored as uint32, an int is smaller on 32-bit platforms
+	maxByteCacheShardSize = min(math.MaxUint32, math.MaxInt)
+)
+
+// Returned by 'TryPut' of a 'ByteCache' when the item is larger than a shard.
+var ErrEntryTooLarge = errors.New("entry is too large")
+
+// A cache for millions of items that keeps GC pauses short.
+//
+// Items are copied into large preallocated ring buffers that contain no pointers, so the GC
+// doesn't need to scan them. Once a shard is full, its oldest items are evicted (EventEvict).
+// 'Get' returns a copy of the value, changing the returned slice does not change the cache.
+type ByteCache interface {
+	TTLCache[string, []byte]
+}
+
+type byteCache struct {
+	shards [byteCacheShardCount]*byteShard
+	seed   maphash.Seed
+
+	// time.Duration, changed at runtime by 'Policy'
+	expireAfterWrite atomic.Int64
+	cleanupInterval  atomic.Int64
+
+	clock        Clock
+	events       *eventHub[string, []byte]
+	errorHandler func(err error)
+
+	// items can expire, so the cleaner runs whenever the cleanup interval is not disabled
+	expiring    atomic.Bool
+	stopCleaner func()
+
+	lifecycle sync.Mutex
+	closed    atomic.Bool
+}
+
+// Create a new byte cache that holds up to maxBytes of items, including a small header per item.
+//
+// The memory is allocated upfront and split into 64 shards, an item can't be larger than a shard.
+// A shard is at least 64 KiB, so at least 4 MiB is allocated: a smaller maxBytes (including 0 and
+// negative values) allocates 4 MiB.
+// Only 'WithExpireAfterWrite', 'WithCleanupInterval', 'WithClock' and 'WithErrorHandler' are supported,
+// other options return ErrUnsupportedOption.
+func NewByteCache(maxBytes int, options ...Option[string, []byte]) (ByteCache, error) {
+	settings, err := newSettings("NewByteCache", options)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &byteCache{
+		seed:         maphash.MakeSeed(),
+		clock:        settings.clock,
+		events:       newEventHub[string, []byte](),
+		errorHandler: settings.errorHandler,
+	}
+	c.expireAfterWrite.Store(settings.expireAfterWrite.Load())
+	c.cleanupInterval.Store(settings.cleanupInterval.Load())
+
+	shardSize := min(max(maxBytes/byteCacheShardCount, minByteCacheShardSize), maxByteCacheShardSize)
+	for i := range c.shards {
+		c.shards[i] = newByteShard(shardSize, c.events)
+	}
+
+	if c.getExpireAfterWrite() > 0 {
+		c.startCleaner()
+	}
+
+	return newByteCacheHandle(c), nil
+}
+
+// The handle that is returned to the user, see 'cacheHandle'.
+type byteCacheHandle struct {
+	*byteCache
+}
+
+func newByteCacheHandle(c *byteCache) *byteCacheHandle {
+	h := &byteCacheHandle{c}
+	runtime.SetFinalizer(h, func(h *byteCacheHandle) {
+		h.byteCache.Close()
+	})
+	return h
+}
+
+func (c *byteCache) Get(key string) ([]byte, bool) {
+	if c.closed.Load() {
+		return nil, false
+	}
+	hash, shard := c.shard(key)
+	return shard.get(hash, key, c.now())
+}
+
+func (c *byteCache) Put(key string, value []byte) {
+	if err := c.TryPut(key, value); err != nil && err != ErrClosed {
+		c.handleError(fmt.Errorf("put %v: %w", key, err))
+	}
+}
+
+func (c *byteCache) TryPut(key string, value []byte) error {
+	return c.tryPut(key, value, c.getExpireAfterWrite())
+}
+
+func (c *byteCache) PutWithTTL(key string, value []byte, ttl time.Duration) {
+	if ttl > 0 {
+		c.startCleaner()
+	}
+	if err := c.tryPut(key, value, ttl); err != nil && err != ErrClosed {
+		c.handleError(fmt.Errorf("put %v: %w", key, err))
+	}
+}
+
+func (c *byteCache) tryPut(key string, value []byte, ttl time.Duration) error {
+	if c.closed.Load() {
+		return ErrClosed
+	}
+
+	hash, shard := c.shard(key)
+	if recordSize(key, value) > len(shard.buf) {
+		return ErrEntryTooLarge
+	}
+
+	now := c.now()
+	var expireAt int64
+	if ttl > 0 {
+		expireAt = now + int64(ttl)
+	}
+	shard.set(hash, key, value, expireAt, now)
+	return nil
+}
+
+func (c *byteCache) TTL(key string) (time.Duration, bool) {
+	if c.closed.Load() {
+		return 0, false
+	}
+	now := c.now()
+	hash, shard := c.shard(key)
+	expireAt, found := shard.ttl(hash, key, now)
+	if !found || expireAt == 0 {
+		return 0, found
+	}
+	return time.Duration(expireAt - now), true
+}
+
+func (c *byteCache) Has(key string) bool {
+	if c.closed.Load() {
+		return false
+	}
+	hash, shard := c.shard(key)
+	_, found := shard.ttl(hash, key, c.now())
+	return found
+}
+
+func (c *byteCache) IsEmpty() bool {
+	return c.Count() == 0
+}
+
+func (c *byteCache) Count() int {
+	if c.closed.Load() {
+		return 0
+	}
+	now := c.now()
+	count := 0
+	for _, shard := range c.shards {
+		count += shard.count(now)
+	}
+	return count
+}
+
+func (c *byteCache) ForEach(fn func(key string, value []byte)) {
+	if c.closed.Load() {
+		return
+	}
+	now := c.now()
+	for _, shard := range c.shards {
+		shard.forEach(now, func(key string, value []byte, _ int64) {
+			fn(key, value)
+		})
+	}
+}
+
+func (c *byteCache) Delete(key string) {
+	_ = c.TryDelete(key)
+}
+
+func (c *byteCache) TryDelete(key string) error {
+	if c.closed.Load() {
+		return ErrClosed
+	}
+	hash, shard := c.shard(key)
+	shard.delete(hash, key, c.now())
+	return nil
+}
+
+func (c *byteCache) Clear() {
+	if c.closed.Load() {
+		return
+	}
+	if !c.events.active() {
+		for _, shard := range c.shards {
+			shard.clear()
+		}
+		return
+	}
+
+	// every shard stays locked until EventClear is published, so no event of a key follows it out of order
+	for _, shard := range c.shards {
+		shard.mu.Lock()
+		defer shard.mu.Unlock()
+		shard.clearLocked()
+	}
+	c.events.publish(Event[string, []byte]{Type: EventClear})
+}
+
+func (c *byteCache) Subscribe(buffer int) (<-chan Event[string, []byte], func()) {
+	return c.events.subscribe(buffer)
+}
+
+func (c *byteCache) SaveTo(w io.Writer, codec Codec[string, []byte]) error {
+	if c.closed.Load() {
+		return ErrClosed
+	}
+
+	sw, err := NewSnapshotWriter(w, codec)
+	if err != nil {
+		return err
+	}
+
+	now := c.now()
+	for _, shard := range c.shards {
+		shard.forEach(now, func(key string, value []byte, expireAt int64) {
+			if err != nil {
+				return
+			}
+			var t time.Time
+			if expireAt != 0 {
+				t = time.Unix(0, expireAt)
+			}
+			err = sw.Write(key, value, t)
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return sw.Flush()
+}
+
+func (c *byteCache) LoadFrom(r io.Reader, codec Codec[string, []byte]) error {
+	if c.closed.Load() {
+		return ErrClosed
+	}
+
+	sr, err := NewSnapshotReader(r, codec)
+	if err != nil {
+		return err
+	}
+
+	for {
+		key, value, expireAt, err := sr.Read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		now := c.now()
+		var expireAtNano int64
+		if !expireAt.IsZero() {
+			if expireAtNano = expireAt.UnixNano(); now >= expireAtNano {
+				continue
+			}
+			c.startCleaner()
+		}
+
+		hash, shard := c.shard(key)
+		if recordSize(key, value) > len(shard.buf) {
+			return ErrEntryTooLarge
+		}
+		shard.set(hash, key, value, expireAtNano, now)
+	}
+}
+
+func (c *byteCache) CleanUp() int {
+	if c.closed.Load() {
+		return 0
+	}
+	return c.removeExpired()
+}
+
+func (c *byteCache) Close() {
+	c.lifecycle.Lock()
+	defer c.lifecycle.Unlock()
+
+	if c.closed.Swap(true) {
+		return
+	}
+	if c.stopCleaner != nil {
+		c.stopCleaner()
+		c.stopCleaner = nil
+	}
+	for _, shard := range c.shards {
+		shard.clear()
+	}
+	c.events.close()
+}
+
+func (c *byteCache) Shutdown(context.Context) error {
+	c.Close()
+	return nil
+}
+
+func (c *byteCache) Policy() Policy {
+	return byteCachePolicy{c}
+}
+
+type byteCachePolicy struct {
+	c *byteCache
+}
+
+func (p byteCachePolicy) ExpireAfterWrite() time.Duration {
+	return p.c.getExpireAfterWrite()
+}
+
+func (p byteCachePolicy) SetExpireAfterWrite(expireAfterWrite time.Duration, restamp bool) {
+	c := p.c
+	expireAfterWrite = max(expireAfterWrite, 0)
+	c.expireAfterWrite.Store(int64(expireAfterWrite))
+
+	if expireAfterWrite > 0 {
+		c.startCleaner()
+	}
+	if !restamp || c.closed.Load() {
+		return
+	}
+
+	now := c.now()
+	var expireAt int64
+	if expireAfterWrite > 0 {
+		expireAt = now + int64(expireAfterWrite)
+	}
+	for _, shard := range c.shards {
+		shard.restamp(expireAt, now)
+	}
+}
+
+func (p byteCachePolicy) CleanupInterval() time.Duration {
+	return time.Duration(p.c.cleanupInterval.Load())
+}
+
+func (p byteCachePolicy) SetCleanupInterval(cleanupInterval time.Duration) {
+	c := p.c
+	c.lifecycle.Lock()
+	defer c.lifecycle.Unlock()
+
+	c.cleanupInterval.Store(int64(max(cleanupInterval, 0)))
+	c.restartCleaner()
+}
+
+// Starts the cleaner once items can expire.
+func (c *byteCache) startCleaner() {
+	if c.expiring.Load() {
+		return
+	}
+
+	c.lifecycle.Lock()
+	defer c.lifecycle.Unlock()
+
+	if !c.expiring.Swap(true) {
+		c.restartCleaner()
+	}
+}
+
+// Stops the cleaner and starts it with the current cleanup interval, the lifecycle lock must be held.
+func (c *byteCache) restartCleaner() {
+	if c.stopCleaner != nil {
+		c.stopCleaner()
+		c.stopCleaner = nil
+	}
+
+	interval := time.Duration(c.cleanupInterval.Load())
+	if c.closed.Load() || !c.expiring.Load() || interval <= 0 {
+		return
+	}
+	c.stopCleaner = c.clock.NewTicker(interval, func() {
+		c.removeExpired()
+	})
+}
+
+func (c *byteCache) removeExpired() int {
+	now := c.now()
+	removed := 0
+	for _, shard := range c.shards {
+		removed += shard.removeExpired(now)
+	}
+	return removed
+}
+
+func (c *byteCache) shard(key string) (uint64, *byteShard) {
+	hash := maphash.String(c.seed, key)
+	return hash, c.shards[hash&(byteCacheShardCount-1)]
+}
+
+func (c *byteCache) now() int64 {
+	return c.clock.Now().UnixNano()
+}
+
+func (c *byteCache) getExpireAfterWrite() time.Duration {
+	return time.Duration(c.expireAfterWrite.Load())
+}
+
+func (c *byteCache) handleError(err error) {
+	if c.errorHandler != nil {
+		c.errorHandler(err)
+		return
+	}
+	slog.Error("go-cache", "error", err)
+}
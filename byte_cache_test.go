@@ -0,0 +1,339 @@
+package cache
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/larscom/go-cache/cachetest"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestByteCache(t *testing.T, maxBytes int, options ...Option[string, []byte]) ByteCache {
+	cache, err := NewByteCache(maxBytes, options...)
+	assert.NoError(t, err)
+	return cache
+}
+
+func TestByteCache(t *testing.T) {
+	const (
+		defaultTTL = time.Minute
+		maxBytes   = 8 << 20
+	)
+
+	TestPutAndGet := func(t *testing.T) {
+		cache := newTestByteCache(t, maxBytes)
+		defer cache.Close()
+
+		value := []byte("value")
+		cache.Put("a", value)
+		cache.Put("b", nil)
+
+		// the cache holds a copy
+		value[0] = 'x'
+
+		actual, found := cache.Get("a")
+		assert.True(t, found)
+		assert.Equal(t, []byte("value"), actual)
+
+		actual[0] = 'x'
+		actual, _ = cache.Get("a")
+		assert.Equal(t, []byte("value"), actual)
+
+		actual, found = cache.Get("b")
+		assert.True(t, found)
+		assert.Empty(t, actual)
+
+		_, found = cache.Get("c")
+		assert.False(t, found)
+
+		cache.Put("a", []byte("updated"))
+		actual, _ = cache.Get("a")
+		assert.Equal(t, []byte("updated"), actual)
+
+		assert.True(t, cache.Has("b"))
+		assert.Equal(t, 2, cache.Count())
+
+		cache.Delete("a")
+		assert.False(t, cache.Has("a"))
+
+		cache.Clear()
+		assert.True(t, cache.IsEmpty())
+	}
+	t.Run("TestPutAndGet", TestPutAndGet)
+
+	TestExpireAfterWrite := func(t *testing.T) {
+		clock := cachetest.NewFakeClock(time.Now())
+		cache := newTestByteCache(t,
+			maxBytes,
+			WithExpireAfterWrite[string, []byte](defaultTTL),
+			WithCleanupInterval[string, []byte](0),
+			WithClock[string, []byte](clock),
+		)
+		defer cache.Close()
+
+		cache.Put("a", []byte("a"))
+		cache.PutWithTTL("b", []byte("b"), 0)
+		cache.PutWithTTL("c", []byte("c"), defaultTTL*2)
+
+		ttl, found := cache.TTL("a")
+		assert.True(t, found)
+		assert.Equal(t, defaultTTL, ttl)
+
+		ttl, found = cache.TTL("b")
+		assert.True(t, found)
+		assert.Zero(t, ttl)
+
+		clock.Advance(defaultTTL + time.Second)
+
+		assert.False(t, cache.Has("a"))
+		assert.True(t, cache.Has("b"))
+		assert.True(t, cache.Has("c"))
+		assert.Equal(t, 2, cache.Count())
+
+		assert.Equal(t, 1, cache.CleanUp())
+	}
+	t.Run("TestExpireAfterWrite", TestExpireAfterWrite)
+
+	TestCleaner := func(t *testing.T) {
+		clock := cachetest.NewFakeClock(time.Now())
+		cache := newTestByteCache(t,
+			maxBytes,
+			WithCleanupInterval[string, []byte](time.Second),
+			WithClock[string, []byte](clock),
+		)
+		defer cache.Close()
+
+		eventchn, cancel := cache.Subscribe(1)
+		defer cancel()
+
+		cache.PutWithTTL("a", []byte("a"), defaultTTL)
+		<-eventchn
+
+		clock.Advance(defaultTTL + time.Second)
+
+		event := <-eventchn
+		assert.Equal(t, EventExpire, event.Type)
+		assert.Equal(t, "a", event.Key)
+		assert.Equal(t, []byte("a"), event.OldValue)
+	}
+	t.Run("TestCleaner", TestCleaner)
+
+	TestEvict := func(t *testing.T) {
+		cache := newTestByteCache(t, 0)
+		defer cache.Close()
+
+		eventchn, cancel := cache.Subscribe(1 << 16)
+		defer cancel()
+
+		const count = 20_000
+		value := bytes.Repeat([]byte("a"), 1024)
+		for i := range count {
+			cache.Put(fmt.Sprint(i), value)
+		}
+
+		evicted := 0
+		for len(eventchn) > 0 {
+			if event := <-eventchn; event.Type == EventEvict {
+				evicted++
+			}
+		}
+
+		assert.Less(t, cache.Count(), count)
+		assert.Equal(t, count, cache.Count()+evicted)
+
+		actual, found := cache.Get(fmt.Sprint(count - 1))
+		assert.True(t, found)
+		assert.Equal(t, value, actual)
+	}
+	t.Run("TestEvict", TestEvict)
+
+	TestSize := func(t *testing.T) {
+		size := func(c ByteCache) int {
+			total := 0
+			for _, shard := range c.(*byteCacheHandle).shards {
+				total += len(shard.buf)
+			}
+			return total
+		}
+
+		for _, maxBytes := range []int{-1, 0, 1 << 20} {
+			cache := newTestByteCache(t, maxBytes)
+			assert.Equal(t, 4<<20, size(cache), maxBytes)
+			cache.Close()
+		}
+
+		cache := newTestByteCache(t, 64<<20)
+		defer cache.Close()
+		assert.Equal(t, 64<<20, size(cache))
+	}
+	t.Run("TestSize", TestSize)
+
+	TestEntryTooLarge := func(t *testing.T) {
+		cache := newTestByteCache(t, 0)
+		defer cache.Close()
+
+		err := cache.TryPut("a", make([]byte, minByteCacheShardSize))
+		assert.ErrorIs(t, err, ErrEntryTooLarge)
+		assert.False(t, cache.Has("a"))
+	}
+	t.Run("TestEntryTooLarge", TestEntryTooLarge)
+
+	TestEvents := func(t *testing.T) {
+		cache := newTestByteCache(t, maxBytes)
+		defer cache.Close()
+
+		eventchn, cancel := cache.Subscribe(10)
+		defer cancel()
+
+		cache.Put("a", []byte("1"))
+		cache.Put("a", []byte("2"))
+		cache.Delete("a")
+		cache.Clear()
+
+		assert.Equal(t, Event[string, []byte]{Type: EventPut, Key: "a", NewValue: []byte("1")}, <-eventchn)
+		assert.Equal(t, Event[string, []byte]{Type: EventUpdate, Key: "a", OldValue: []byte("1"), NewValue: []byte("2")}, <-eventchn)
+		assert.Equal(t, Event[string, []byte]{Type: EventDelete, Key: "a", OldValue: []byte("2")}, <-eventchn)
+		assert.Equal(t, Event[string, []byte]{Type: EventClear}, <-eventchn)
+	}
+	t.Run("TestEvents", TestEvents)
+
+	TestSaveToAndLoadFrom := func(t *testing.T) {
+		clock := cachetest.NewFakeClock(time.Now())
+		cache := newTestByteCache(t, maxBytes, WithClock[string, []byte](clock))
+		defer cache.Close()
+
+		cache.Put("a", []byte("a"))
+		cache.PutWithTTL("b", []byte("b"), defaultTTL)
+
+		var buf bytes.Buffer
+		assert.NoError(t, cache.SaveTo(&buf, GobCodec[string, []byte]{}))
+
+		restored := newTestByteCache(t, maxBytes, WithClock[string, []byte](clock))
+		defer restored.Close()
+
+		assert.NoError(t, restored.LoadFrom(&buf, GobCodec[string, []byte]{}))
+
+		value, found := restored.Get("a")
+		assert.True(t, found)
+		assert.Equal(t, []byte("a"), value)
+
+		ttl, found := restored.TTL("b")
+		assert.True(t, found)
+		assert.Equal(t, defaultTTL, ttl)
+	}
+	t.Run("TestSaveToAndLoadFrom", TestSaveToAndLoadFrom)
+
+	TestPolicy := func(t *testing.T) {
+		clock := cachetest.NewFakeClock(time.Now())
+		cache := newTestByteCache(t, maxBytes, WithClock[string, []byte](clock))
+		defer cache.Close()
+
+		cache.Put("a", []byte("a"))
+		cache.Policy().SetExpireAfterWrite(defaultTTL, true)
+
+		assert.Equal(t, defaultTTL, cache.Policy().ExpireAfterWrite())
+		ttl, _ := cache.TTL("a")
+		assert.Equal(t, defaultTTL, ttl)
+
+		cache.Policy().SetCleanupInterval(time.Second)
+		assert.Equal(t, time.Second, cache.Policy().CleanupInterval())
+
+		clock.Advance(defaultTTL + time.Second)
+		assert.Zero(t, cache.CleanUp())
+	}
+	t.Run("TestPolicy", TestPolicy)
+
+	TestClose := func(t *testing.T) {
+		cache := newTestByteCache(t, maxBytes)
+
+		cache.Put("a", []byte("a"))
+		cache.Close()
+		cache.Close()
+
+		assert.ErrorIs(t, cache.TryPut("a", []byte("a")), ErrClosed)
+		assert.False(t, cache.Has("a"))
+		assert.Zero(t, cache.Count())
+	}
+	t.Run("TestClose", TestClose)
+
+	TestUnsupportedOption := func(t *testing.T) {
+		_, err := NewByteCache(maxBytes, WithSnapshot(filepath.Join(t.TempDir(), "cache"), GobCodec[string, []byte]{}, 0))
+		assert.ErrorIs(t, err, ErrUnsupportedOption)
+		assert.EqualError(t, err, "unsupported option: NewByteCache does not support WithSnapshot")
+
+		_, err = NewByteCache(maxBytes, WithStore[string, []byte](StoreShardedMap))
+		assert.ErrorIs(t, err, ErrUnsupportedOption)
+	}
+	t.Run("TestUnsupportedOption", TestUnsupportedOption)
+}
+
+func TestByteShard(t *testing.T) {
+	TestWrap := func(t *testing.T) {
+		shard := newByteShard(100, newEventHub[string, []byte]())
+
+		// each record is 30 bytes, so 3 records fit
+		for i := range 3 {
+			shard.set(uint64(i), fmt.Sprint(i), []byte("abcde"), 0, 0)
+		}
+		assert.Equal(t, 3, shard.count(0))
+
+		// wraps and evicts the oldest record
+		shard.set(3, "3", []byte("abcde"), 0, 0)
+		assert.Equal(t, 3, shard.count(0))
+
+		_, found := shard.get(0, "0", 0)
+		assert.False(t, found)
+
+		for i := 1; i <= 3; i++ {
+			value, found := shard.get(uint64(i), fmt.Sprint(i), 0)
+			assert.True(t, found)
+			assert.Equal(t, []byte("abcde"), value)
+		}
+
+		// a record of the whole buffer evicts everything
+		shard.set(4, "4", make([]byte, 100-recordHeaderSize-1), 0, 0)
+		assert.Equal(t, 1, shard.count(0))
+
+		shard.set(5, "5", nil, 0, 0)
+		assert.Equal(t, 1, shard.count(0))
+		_, found = shard.get(5, "5", 0)
+		assert.True(t, found)
+	}
+	t.Run("TestWrap", TestWrap)
+
+	TestDeletedRecordsAreSkipped := func(t *testing.T) {
+		shard := newByteShard(100, newEventHub[string, []byte]())
+
+		shard.set(0, "0", []byte("abcde"), 0, 0)
+		shard.set(1, "1", []byte("abcde"), 0, 0)
+		shard.delete(0, "0", 0)
+		shard.set(1, "1", []byte("fghij"), 0, 0)
+		shard.set(2, "2", []byte("abcde"), 0, 0)
+
+		assert.Equal(t, 2, shard.count(0))
+
+		value, found := shard.get(1, "1", 0)
+		assert.True(t, found)
+		assert.Equal(t, []byte("fghij"), value)
+	}
+	t.Run("TestDeletedRecordsAreSkipped", TestDeletedRecordsAreSkipped)
+
+	TestHashCollision := func(t *testing.T) {
+		shard := newByteShard(100, newEventHub[string, []byte]())
+
+		shard.set(0, "a", []byte("a"), 0, 0)
+		shard.set(0, "b", []byte("b"), 0, 0)
+
+		_, found := shard.get(0, "a", 0)
+		assert.False(t, found)
+
+		value, found := shard.get(0, "b", 0)
+		assert.True(t, found)
+		assert.Equal(t, []byte("b"), value)
+		assert.Equal(t, 1, shard.count(0))
+	}
+	t.Run("TestHashCollision", TestHashCollision)
+}
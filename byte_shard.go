@@ -0,0 +1,313 @@
+package cache
+
+import (
+	"bytes"
+	"encoding/binary"
+	"sync"
+)
+
+// hash, expiration, key length and value length
+const recordHeaderSize = 24
+
+// A ring buffer of records with an index of key hash to record offset.
+//
+// Records are appended at 'head', whenever a record does not fit the oldest records at 'tail'
+// are evicted. Records never wrap around the end of the buffer: when a record does not fit
+// before the end, the rest of the lap (from 'wrapEnd') is left unused and writing continues at 0.
+//
+// Neither the buffer nor the index contain pointers, so the GC doesn't need to scan them.
+type byteShard struct {
+	mu     sync.RWMutex
+	index  map[uint64]uint32
+	buf    []byte
+	events *eventHub[string, []byte]
+
+	head    int
+	tail    int
+	wrapEnd int
+	// the records are in [tail, wrapEnd) and [0, head) instead of [tail, head)
+	wrapped bool
+}
+
+func newByteShard(size int, events *eventHub[string, []byte]) *byteShard {
+	return &byteShard{
+		index:  make(map[uint64]uint32),
+		buf:    make([]byte, size),
+		events: events,
+	}
+}
+
+// A record in the buffer, the slice starts at the record and may extend beyond it.
+type byteRecord []byte
+
+func (r byteRecord) hash() uint64 {
+	return binary.LittleEndian.Uint64(r)
+}
+
+// Unix nano, 0 means the record does not expire.
+func (r byteRecord) expireAt() int64 {
+	return int64(binary.LittleEndian.Uint64(r[8:]))
+}
+
+func (r byteRecord) setExpireAt(expireAt int64) {
+	binary.LittleEndian.PutUint64(r[8:], uint64(expireAt))
+}
+
+func (r byteRecord) keyLen() int {
+	return int(binary.LittleEndian.Uint32(r[16:]))
+}
+
+func (r byteRecord) valueLen() int {
+	return int(binary.LittleEndian.Uint32(r[20:]))
+}
+
+func (r byteRecord) key() []byte {
+	return r[recordHeaderSize : recordHeaderSize+r.keyLen()]
+}
+
+func (r byteRecord) value() []byte {
+	start := recordHeaderSize + r.keyLen()
+	return r[start : start+r.valueLen()]
+}
+
+func (r byteRecord) size() int {
+	return recordHeaderSize + r.keyLen() + r.valueLen()
+}
+
+func (r byteRecord) isExpired(now int64) bool {
+	expireAt := r.expireAt()
+	return expireAt != 0 && now > expireAt
+}
+
+func recordSize(key string, value []byte) int {
+	return recordHeaderSize + len(key) + len(value)
+}
+
+func (s *byteShard) record(offset uint32) byteRecord {
+	return byteRecord(s.buf[offset:])
+}
+
+// Returns the record of the key, found is false when the key does not exist or another key has the same hash.
+func (s *byteShard) lookup(hash uint64, key string) (record byteRecord, found bool) {
+	offset, found := s.index[hash]
+	if !found {
+		return nil, false
+	}
+	record = s.record(offset)
+	return record, string(record.key()) == key
+}
+
+func (s *byteShard) get(hash uint64, key string, now int64) ([]byte, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	record, found := s.lookup(hash, key)
+	if !found || record.isExpired(now) {
+		return nil, false
+	}
+	return bytes.Clone(record.value()), true
+}
+
+func (s *byteShard) ttl(hash uint64, key string, now int64) (expireAt int64, found bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	record, found := s.lookup(hash, key)
+	if !found || record.isExpired(now) {
+		return 0, false
+	}
+	return record.expireAt(), true
+}
+
+// Writes the record and publishes EventPut or EventUpdate, the record must fit into the buffer.
+func (s *byteShard) set(hash uint64, key string, value []byte, expireAt int64, now int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var (
+		old      []byte
+		replaced bool
+	)
+	if offset, found := s.index[hash]; found {
+		record := s.record(offset)
+		if string(record.key()) == key {
+			if s.events.active() && !record.isExpired(now) {
+				old, replaced = bytes.Clone(record.value()), true
+			}
+		} else {
+			// another key with the same hash
+			s.publishRemoved(record, now)
+		}
+		delete(s.index, hash)
+	}
+
+	offset := s.alloc(recordSize(key, value), now)
+	record := s.record(uint32(offset))
+	binary.LittleEndian.PutUint64(record, hash)
+	record.setExpireAt(expireAt)
+	binary.LittleEndian.PutUint32(record[16:], uint32(len(key)))
+	binary.LittleEndian.PutUint32(record[20:], uint32(len(value)))
+	copy(record[recordHeaderSize:], key)
+	copy(record[recordHeaderSize+len(key):], value)
+	s.index[hash] = uint32(offset)
+
+	if !s.events.active() {
+		return
+	}
+	if replaced {
+		s.events.publish(Event[string, []byte]{Type: EventUpdate, Key: key, OldValue: old, NewValue: bytes.Clone(value)})
+	} else {
+		s.events.publish(Event[string, []byte]{Type: EventPut, Key: key, NewValue: bytes.Clone(value)})
+	}
+}
+
+// Returns the offset of size free bytes, evicts the oldest records until they fit.
+func (s *byteShard) alloc(size int, now int64) int {
+	for {
+		if !s.wrapped {
+			if s.tail == s.head {
+				// empty, start over to use the whole buffer
+				s.head, s.tail = 0, 0
+			}
+			if s.head+size <= len(s.buf) {
+				offset := s.head
+				s.head += size
+				return offset
+			}
+			s.wrapEnd = s.head
+			s.head = 0
+			s.wrapped = true
+			continue
+		}
+
+		if s.head+size <= s.tail {
+			offset := s.head
+			s.head += size
+			return offset
+		}
+		s.evictOldest(now)
+	}
+}
+
+// Removes the record at 'tail', only called while wrapped.
+func (s *byteShard) evictOldest(now int64) {
+	if s.tail < s.wrapEnd {
+		record := s.record(uint32(s.tail))
+		if offset, found := s.index[record.hash()]; found && int(offset) == s.tail {
+			delete(s.index, record.hash())
+			s.publishRemoved(record, now)
+		}
+		s.tail += record.size()
+	}
+
+	if s.tail >= s.wrapEnd {
+		s.tail = 0
+		s.wrapped = false
+	}
+}
+
+// Publishes EventEvict for a record that has been removed to make room, EventExpire when it was expired anyway.
+func (s *byteShard) publishRemoved(record byteRecord, now int64) {
+	if !s.events.active() {
+		return
+	}
+	eventType := EventEvict
+	if record.isExpired(now) {
+		eventType = EventExpire
+	}
+	s.events.publish(Event[string, []byte]{Type: eventType, Key: string(record.key()), OldValue: bytes.Clone(record.value())})
+}
+
+// Removes the record of the key and publishes EventDelete, the space is reclaimed once the ring buffer wraps.
+func (s *byteShard) delete(hash uint64, key string, now int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	record, found := s.lookup(hash, key)
+	if !found {
+		return
+	}
+	delete(s.index, hash)
+
+	if !record.isExpired(now) {
+		s.events.publish(Event[string, []byte]{Type: EventDelete, Key: key, OldValue: bytes.Clone(record.value())})
+	}
+}
+
+func (s *byteShard) count(now int64) int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	count := 0
+	for _, offset := range s.index {
+		if !s.record(offset).isExpired(now) {
+			count++
+		}
+	}
+	return count
+}
+
+// Calls fn with a copy of every record that is not expired, fn is called without holding the lock.
+func (s *byteShard) forEach(now int64, fn func(key string, value []byte, expireAt int64)) {
+	type item struct {
+		key      string
+		value    []byte
+		expireAt int64
+	}
+
+	s.mu.RLock()
+	items := make([]item, 0, len(s.index))
+	for _, offset := range s.index {
+		record := s.record(offset)
+		if !record.isExpired(now) {
+			items = append(items, item{string(record.key()), bytes.Clone(record.value()), record.expireAt()})
+		}
+	}
+	s.mu.RUnlock()
+
+	for _, item := range items {
+		fn(item.key, item.value, item.expireAt)
+	}
+}
+
+// Removes the expired records, publishes EventExpire for each of them and returns the count of removed records.
+func (s *byteShard) removeExpired(now int64) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	removed := 0
+	for hash, offset := range s.index {
+		record := s.record(offset)
+		if record.isExpired(now) {
+			delete(s.index, hash)
+			s.events.publish(Event[string, []byte]{Type: EventExpire, Key: string(record.key()), OldValue: bytes.Clone(record.value())})
+			removed++
+		}
+	}
+	return removed
+}
+
+// Sets the expiration of every record that is not expired, 0 means the record does not expire.
+func (s *byteShard) restamp(expireAt int64, now int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, offset := range s.index {
+		record := s.record(offset)
+		if !record.isExpired(now) {
+			record.setExpireAt(expireAt)
+		}
+	}
+}
+
+func (s *byteShard) clear() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.clearLocked()
+}
+
+func (s *byteShard) clearLocked() {
+	clear(s.index)
+	s.head, s.tail, s.wrapEnd = 0, 0, 0
+	s.wrapped = false
+}
@@ -1,20 +1,40 @@
 package cache
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"runtime"
+	"slices"
+	"sync"
+	"sync/atomic"
 	"time"
-
-	csmap "github.com/mhmtszr/concurrent-swiss-map"
 )
 
+const defaultCleanupInterval = time.Second * 5
+
+// Returned by 'Load' and 'Reload' when the cache has been closed.
+var ErrClosed = errors.New("cache is closed")
+
+// Returned (wrapped) by constructors that don't support every option, e.g. 'NewByteCache'.
+var ErrUnsupportedOption = errors.New("unsupported option")
+
 type Option[K comparable, V any] func(c *cache[K, V])
 
-type Cache[K comparable, V any] interface {
+type Cache[K any, V any] interface {
 	// Get an item from the cache.
 	Get(key K) (V, bool)
 
 	// Put an item into cache.
 	Put(key K, value V)
 
+	// Put an item into cache and return the error of the write-through writer (see 'WithWriteThrough').
+	//
+	// Returns ErrClosed when the cache has been closed.
+	TryPut(key K, value V) error
+
 	// Returns true when the item exist in cache.
 	Has(key K) bool
 
@@ -30,59 +50,255 @@ type Cache[K comparable, V any] interface {
 	// Deletes an item from the cache.
 	Delete(key K)
 
+	// Deletes an item from the cache and return the error of the write-through writer (see 'WithWriteThrough').
+	//
+	// Returns ErrClosed when the cache has been closed.
+	TryDelete(key K) error
+
 	// Clear all items from cache.
 	Clear()
 
+	// Subscribe to changes of the cache, the returned channel receives an Event for every change.
+	// The events of a key are received in the order its changes were applied.
+	//
+	// Publishing never blocks the cache: whenever the buffer of a subscriber is full, the event is
+	// dropped for that subscriber. Use a buffer that fits the expected burst of changes.
+	//
+	// The channel gets closed when the returned cancel function is called or when the cache is closed.
+	Subscribe(buffer int) (<-chan Event[K, V], func())
+
+	// Writes all items that are not expired, including their expiration, to w using the codec.
+	SaveTo(w io.Writer, codec Codec[K, V]) error
+
+	// Reads items that were written by 'SaveTo' from r and puts them into the cache.
+	//
+	// Items keep their original expiration, items that expired in the meantime are skipped.
+	LoadFrom(r io.Reader, codec Codec[K, V]) error
+
+	// Removes all expired items from the cache and returns the count of removed items.
+	//
+	// This happens automatically at the cleanup interval, use this to clean up on demand.
+	CleanUp() int
+
 	// Cleanup resources and timers.
+	//
+	// A closed cache behaves like an empty cache, 'Put' is a no-op and 'Load' returns ErrClosed.
+	// Calling Close more than once is a no-op.
 	Close()
+
+	// Closes the cache and waits until all in-flight loads are done or the context is done.
+	Shutdown(ctx context.Context) error
+
+	// Returns the policy to change the expiration and cleanup of the cache at runtime.
+	Policy() Policy
+}
+
+// A Cache with a 'TTL' per item, the caches of this package implement it.
+//
+// Use a type assertion to get it, e.g. 'c.(cache.TTLCache[int, string])'.
+type TTLCache[K any, V any] interface {
+	// Put an item into cache that expires after ttl, this overrides 'WithExpireAfterWrite' for the item.
+	//
+	// A ttl of 0 means the item does not expire.
+	PutWithTTL(key K, value V, ttl time.Duration)
+
+	// Returns the remaining 'TTL' of an item, the 'TTL' is 0 when the item does not expire.
+	TTL(key K) (time.Duration, bool)
+
+	// Embed Cache
+	Cache[K, V]
 }
 
 // The 'TTL' after it has been written to the cache.
+//
+// On the system clock expiration is accurate to 100ms, the time is read from a shared clock that ticks every 100ms.
 func WithExpireAfterWrite[K comparable, V any](
 	expireAfterWrite time.Duration,
 ) Option[K, V] {
 	return func(c *cache[K, V]) {
-		c.expireAfterWrite = expireAfterWrite
+		c.expireAfterWrite.Store(int64(expireAfterWrite))
+	}
+}
+
+// The interval at which expired items are removed from the cache in the background.
+//
+// Defaults to 5 seconds, an interval of 0 disables the background cleanup. Expired items
+// are never returned either way, use 'CleanUp' to remove them on demand.
+func WithCleanupInterval[K comparable, V any](
+	cleanupInterval time.Duration,
+) Option[K, V] {
+	return func(c *cache[K, V]) {
+		c.cleanupInterval.Store(int64(cleanupInterval))
+	}
+}
+
+// The clock used for expiration and background cleanup, defaults to the system clock.
+func WithClock[K comparable, V any](
+	clock Clock,
+) Option[K, V] {
+	return func(c *cache[K, V]) {
+		c.clock = clock
+	}
+}
+
+// Handles errors that happen in the background (e.g. writing a snapshot), defaults to logging them with slog.
+func WithErrorHandler[K comparable, V any](
+	errorHandler func(err error),
+) Option[K, V] {
+	return func(c *cache[K, V]) {
+		c.errorHandler = errorHandler
+	}
+}
+
+// Applies the options to a cache that is only used to read the settings of caches that aren't a cache[K, V]
+// (e.g. 'NewByteCache'), returns ErrUnsupportedOption whenever an option is applied that is not one of supported.
+func newSettings[K comparable, V any](constructor string, options []Option[K, V], supported ...string) (*cache[K, V], error) {
+	settings := &cache[K, V]{clock: systemClock{}}
+	settings.cleanupInterval.Store(int64(defaultCleanupInterval))
+	for _, option := range options {
+		option(settings)
+	}
+	for _, option := range settings.appliedOptions() {
+		if !slices.Contains(supported, option) {
+			return nil, fmt.Errorf("%w: %s does not support %s", ErrUnsupportedOption, constructor, option)
+		}
 	}
+	return settings, nil
+}
+
+// The options that have been applied, except the options that every cache supports.
+func (c *cache[K, V]) appliedOptions() []string {
+	var applied []string
+	add := func(set bool, option string) {
+		if set {
+			applied = append(applied, option)
+		}
+	}
+	add(c.storeType != nil, "WithStore")
+	add(c.shardCount != 0, "WithShardCount")
+	add(c.initialCapacity != 0, "WithInitialCapacity")
+	add(c.weakValues != nil, "WithWeakValues")
+	add(c.snapshot != nil, "WithSnapshot")
+	add(c.journal != nil, "WithJournal")
+	add(c.journalCompactSize != 0, "WithJournalCompactSize")
+	add(c.writeThrough != nil, "WithWriteThrough")
+	add(c.writeBehind != nil, "WithWriteBehind")
+	add(c.invalidation != nil, "WithInvalidationBus")
+	return applied
 }
 
 type cache[K comparable, V any] struct {
-	data *csmap.CsMap[K, *entry[K, V]]
+	data            store[K, entry[K, V]]
+	storeType       *StoreType
+	shardCount      int
+	initialCapacity int
 
 	mu         loaderMutex[K]
 	loaderFunc LoaderFunc[K, V]
+	// in-flight loads by key, see 'loadWith'
+	loadCalls sync.Map
+
+	// time.Duration, changed at runtime by 'Policy'
+	expireAfterWrite atomic.Int64
+	cleanupInterval  atomic.Int64
 
-	expireAfterWrite time.Duration
+	// set by 'WithWeakValues'
+	weakValues func(key K, value V) weakValue[V]
 
+	clock Clock
+	// the clock is the system clock, so the shared coarse clock is used once items can expire
+	onSystemClock bool
+	// reads the time from the shared coarse clock instead of the system clock
+	coarse  atomic.Bool
 	cleaner cleaner[K, V]
+	// items can expire, so the cleaner runs whenever the cleanup interval is not disabled
+	expiring       atomic.Bool
+	cleanerStarted atomic.Bool
+	events         *eventHub[K, V]
+
+	snapshot           *snapshotFile[K, V]
+	journal            *journal[K, V]
+	journalCompactSize int64
+	errorHandler       func(err error)
+
+	writeThrough Writer[K, V]
+	writeBehind  *writeBehind[K, V]
+	invalidation *invalidation[K]
+
+	// guards 'closed' against in-flight loads
+	lifecycle sync.RWMutex
+	closed    atomic.Bool
+	loads     sync.WaitGroup
 }
 
 func NewCache[K comparable, V any](
 	options ...Option[K, V],
 ) Cache[K, V] {
-	data := csmap.Create[K, *entry[K, V]]()
-	cleaner := newCacheCleaner(data, time.Second*5)
-	return newCache(data, cleaner, options...)
+	return newCacheHandle(newCache(options...))
+}
+
+// The handle that is returned to the user.
+//
+// The cleaner goroutine only references the underlying cache, so whenever the handle
+// becomes unreachable (the user forgot to call 'Close') the finalizer closes the cache
+// which stops the cleaner goroutine.
+type cacheHandle[K comparable, V any] struct {
+	*cache[K, V]
+}
+
+func newCacheHandle[K comparable, V any](c *cache[K, V]) *cacheHandle[K, V] {
+	h := &cacheHandle[K, V]{c}
+	runtime.SetFinalizer(h, func(h *cacheHandle[K, V]) {
+		h.cache.Close()
+	})
+	return h
 }
 
 func newCache[K comparable, V any](
-	data *csmap.CsMap[K, *entry[K, V]],
-	cleaner cleaner[K, V],
 	options ...Option[K, V],
 ) *cache[K, V] {
 	c := &cache[K, V]{
-		data:    data,
-		cleaner: cleaner,
+		clock:  systemClock{},
+		events: newEventHub[K, V](),
 	}
+	c.cleanupInterval.Store(int64(defaultCleanupInterval))
 
 	for _, option := range options {
 		option(c)
 	}
 
+	storeType := defaultStoreType
+	if c.storeType != nil {
+		storeType = *c.storeType
+	}
+	c.data = newStore[K, entry[K, V]](storeType, c.shardCount, c.initialCapacity)
+	if c.weakValues != nil {
+		c.data = weakStore[K, V]{c.data}
+	}
+
+	_, c.onSystemClock = c.clock.(systemClock)
+
+	if c.cleaner == nil {
+		cleaner := newCacheCleaner(c.data, c.events, c.clock, c.getCleanupInterval())
+		// the cleaner and the cache decide on expiration with the same time
+		cleaner.now = c.now
+		c.cleaner = cleaner
+	}
+
 	if c.hasExpireAfterWrite() {
-		c.cleaner.Start()
+		c.startCleaner()
 	}
 
+	if c.journal != nil {
+		if err := c.openJournal(); err != nil {
+			c.handleError(fmt.Errorf("open journal %s: %w", c.journal.path, err))
+		}
+	}
+
+	c.startSnapshots()
+	c.startWriteBehind()
+	c.subscribeInvalidations()
+
 	return c
 }
 
@@ -91,7 +307,36 @@ func (c *cache[K, V]) Get(key K) (V, bool) {
 }
 
 func (c *cache[K, V]) Put(key K, value V) {
-	c.data.Store(key, c.newEntry(key, value))
+	if err := c.TryPut(key, value); err != nil && err != ErrClosed {
+		c.handleError(fmt.Errorf("put %v: %w", key, err))
+	}
+}
+
+func (c *cache[K, V]) PutWithTTL(key K, value V, ttl time.Duration) {
+	if ttl > 0 {
+		c.startCleaner()
+	}
+	if err := c.tryPut(c.newEntryWithTTL(key, value, ttl)); err != nil && err != ErrClosed {
+		c.handleError(fmt.Errorf("put %v: %w", key, err))
+	}
+}
+
+func (c *cache[K, V]) TTL(key K) (time.Duration, bool) {
+	if c.closed.Load() {
+		return 0, false
+	}
+	now := c.now()
+	entry, found := c.data.Load(key)
+	if !found {
+		return 0, false
+	}
+	if _, valid := entry.loadAt(now); !valid {
+		return 0, false
+	}
+	if entry.expireAt == 0 {
+		return 0, true
+	}
+	return time.Duration(entry.expireAt - now), true
 }
 
 func (c *cache[K, V]) Has(key K) bool {
@@ -104,9 +349,13 @@ func (c *cache[K, V]) IsEmpty() bool {
 }
 
 func (c *cache[K, V]) Count() int {
+	if c.closed.Load() {
+		return 0
+	}
 	count := 0
-	c.forEachEntry(func(key K, entry *entry[K, V]) {
-		if entry.isValid() {
+	now := c.now()
+	c.forEachEntry(func(key K, entry entry[K, V]) {
+		if _, valid := entry.loadAt(now); valid {
 			count++
 		}
 	})
@@ -114,52 +363,275 @@ func (c *cache[K, V]) Count() int {
 }
 
 func (c *cache[K, V]) ForEach(fn func(key K, value V)) {
-	c.forEachEntry(func(key K, entry *entry[K, V]) {
-		if entry.isValid() {
-			fn(key, entry.value)
+	if c.closed.Load() {
+		return
+	}
+	now := c.now()
+	c.forEachEntry(func(key K, entry entry[K, V]) {
+		if value, valid := entry.loadAt(now); valid {
+			fn(key, value)
 		}
 	})
 }
 
 func (c *cache[K, V]) Delete(key K) {
-	c.data.Delete(key)
+	if err := c.TryDelete(key); err != nil && err != ErrClosed {
+		c.handleError(fmt.Errorf("delete %v: %w", key, err))
+	}
+}
+
+// Removes the item and publishes EventDelete, or EventExpire if the item had expired but was not cleaned up yet.
+func (c *cache[K, V]) remove(key K) {
+	if c.journal == nil && !c.events.active() {
+		c.data.Delete(key)
+		return
+	}
+
+	if c.journal != nil {
+		c.journal.mu.Lock()
+		defer c.journal.mu.Unlock()
+	}
+	defer c.events.lockKey(key)()
+
+	var old entry[K, V]
+	deleted := c.data.DeleteIf(key, func(entry entry[K, V]) bool {
+		old = entry
+		return true
+	})
+	if !deleted {
+		return
+	}
+
+	if c.journal != nil {
+		c.journalDelete(key)
+	}
+	eventType := EventDelete
+	if old.isExpired(c.now()) {
+		eventType = EventExpire
+	}
+	oldValue, _ := old.load()
+	c.events.publish(Event[K, V]{Type: eventType, Key: key, OldValue: oldValue})
 }
 
 func (c *cache[K, V]) Clear() {
+	if c.closed.Load() {
+		return
+	}
+	c.clear()
+	c.broadcastAll()
+}
+
+// Removes all items and publishes EventClear.
+func (c *cache[K, V]) clear() {
+	if c.journal != nil {
+		c.journal.mu.Lock()
+		defer c.journal.mu.Unlock()
+	}
+	defer c.events.lockAll()()
+
 	c.data.Clear()
+
+	if c.journal != nil {
+		c.journalClear()
+	}
+	c.events.publish(Event[K, V]{Type: EventClear})
+}
+
+func (c *cache[K, V]) Subscribe(buffer int) (<-chan Event[K, V], func()) {
+	return c.events.subscribe(buffer)
+}
+
+func (c *cache[K, V]) CleanUp() int {
+	if c.closed.Load() {
+		return 0
+	}
+	return removeExpired(c.data, c.events, c.now())
 }
 
 func (c *cache[K, V]) Close() {
-	if c.hasExpireAfterWrite() {
+	c.lifecycle.Lock()
+	defer c.lifecycle.Unlock()
+
+	if c.closed.Swap(true) {
+		return
+	}
+	if c.cleanerStarted.Load() {
 		c.cleaner.Stop()
 	}
+	c.unsubscribeInvalidations()
+	c.stopWriteBehind()
+	c.stopSnapshots()
+	c.closeJournal()
 	c.data.Clear()
+	c.events.close()
+
+	if c.coarse.Swap(false) {
+		sharedCoarseClock.release()
+	}
+}
+
+func (c *cache[K, V]) Shutdown(ctx context.Context) error {
+	c.Close()
+
+	donechn := make(chan struct{})
+	go func() {
+		c.loads.Wait()
+		close(donechn)
+	}()
+
+	select {
+	case <-donechn:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
 }
 
 func (c *cache[K, V]) get(key K) (V, bool) {
-	if entry, found := c.data.Load(key); found && entry.isValid() {
-		return entry.value, true
+	if c.closed.Load() {
+		var value V
+		return value, false
+	}
+	if entry, found := c.data.Load(key); found {
+		// entries that don't expire don't need the time
+		if entry.expireAt == 0 {
+			return entry.load()
+		}
+		return entry.loadAt(c.now())
 	}
 
 	var value V
 	return value, false
 }
 
+// Stores the entry and publishes EventPut or EventUpdate.
+func (c *cache[K, V]) storeEntry(created entry[K, V]) {
+	key, value := created.key, created.value
+	created = c.weaken(created)
+	if c.journal != nil {
+		c.journal.mu.Lock()
+		defer c.journal.mu.Unlock()
+		defer c.journalPut(key, value, created.expireAtTime())
+	}
+	if !c.events.active() {
+		c.data.Store(key, created)
+		return
+	}
+	defer c.events.lockKey(key)()
+
+	var (
+		old   entry[K, V]
+		found bool
+	)
+	c.data.SetIf(key, func(previous entry[K, V], previousFound bool) (entry[K, V], bool) {
+		old, found = previous, previousFound
+		return created, true
+	})
+
+	if oldValue, valid := old.loadAt(c.now()); found && valid {
+		c.events.publish(Event[K, V]{Type: EventUpdate, Key: key, OldValue: oldValue, NewValue: value})
+	} else {
+		c.events.publish(Event[K, V]{Type: EventPut, Key: key, NewValue: value})
+	}
+}
+
 // Loop over each entry, including expired entries
-func (c *cache[K, V]) forEachEntry(fn func(key K, entry *entry[K, V])) {
-	c.data.Range(func(key K, entry *entry[K, V]) (stop bool) {
+func (c *cache[K, V]) forEachEntry(fn func(key K, entry entry[K, V])) {
+	c.data.Range(func(key K, entry entry[K, V]) (stop bool) {
 		fn(key, entry)
 		return false
 	})
 }
 
-func (c *cache[K, V]) newEntry(key K, value V) *entry[K, V] {
+func (c *cache[K, V]) newEntry(key K, value V) entry[K, V] {
 	if c.hasExpireAfterWrite() {
-		return newEntry(key, value, time.Now().Add(c.expireAfterWrite))
+		return newEntry(key, value, c.now()+int64(c.getExpireAfterWrite()))
+	}
+	return newEntry(key, value, 0)
+}
+
+// Creates an entry that expires after ttl, 'expireAfterWriteTTL' uses 'WithExpireAfterWrite' instead.
+func (c *cache[K, V]) newEntryWithTTL(key K, value V, ttl time.Duration) entry[K, V] {
+	switch {
+	case ttl == expireAfterWriteTTL:
+		return c.newEntry(key, value)
+	case ttl > 0:
+		return newEntry(key, value, c.now()+int64(ttl))
 	}
-	return newEntry(key, value, zeroTime)
+	return newEntry(key, value, 0)
+}
+
+// Returns the current time as unix nano.
+func (c *cache[K, V]) now() int64 {
+	if c.coarse.Load() {
+		return sharedCoarseClock.now()
+	}
+	return c.clock.Now().UnixNano()
+}
+
+func (c *cache[K, V]) handleError(err error) {
+	if c.errorHandler != nil {
+		c.errorHandler(err)
+		return
+	}
+	slog.Error("go-cache", "error", err)
 }
 
 func (c *cache[K, V]) hasExpireAfterWrite() bool {
-	return c.expireAfterWrite > 0
+	return c.getExpireAfterWrite() > 0
+}
+
+func (c *cache[K, V]) getExpireAfterWrite() time.Duration {
+	return time.Duration(c.expireAfterWrite.Load())
+}
+
+func (c *cache[K, V]) getCleanupInterval() time.Duration {
+	return time.Duration(c.cleanupInterval.Load())
+}
+
+// Marks that items can expire and starts the cleaner, unless the cleanup interval is disabled.
+//
+// Caches on the system clock read the time from the shared coarse clock from then on.
+func (c *cache[K, V]) startCleaner() {
+	if c.expiring.Load() {
+		return
+	}
+
+	c.lifecycle.Lock()
+	defer c.lifecycle.Unlock()
+
+	if !c.expiring.Swap(true) {
+		c.restartCleaner()
+		if c.onSystemClock && !c.closed.Load() {
+			sharedCoarseClock.acquire()
+			c.coarse.Store(true)
+		}
+	}
+}
+
+// (Re)starts the cleaner with the current cleanup interval, the lifecycle lock must be held.
+func (c *cache[K, V]) restartCleaner() {
+	if c.cleanerStarted.Load() {
+		c.cleaner.Stop()
+		c.cleanerStarted.Store(false)
+	}
+
+	interval := c.getCleanupInterval()
+	if cleaner, ok := c.cleaner.(*cacheCleaner[K, V]); ok {
+		cleaner.cleanupInterval = interval
+	}
+
+	if c.closed.Load() || !c.expiring.Load() || interval <= 0 {
+		return
+	}
+	c.cleaner.Start()
+	c.cleanerStarted.Store(true)
+}
+
+func withCleaner[K comparable, V any](
+	cleaner cleaner[K, V],
+) Option[K, V] {
+	return func(c *cache[K, V]) {
+		c.cleaner = cleaner
+	}
 }
@@ -1,10 +1,6 @@
 package cache
 
-import (
-	"time"
-
-	csmap "github.com/mhmtszr/concurrent-swiss-map"
-)
+import "time"
 
 type mockCleaner struct {
 	started bool
@@ -23,55 +19,86 @@ type cleaner[K comparable, V any] interface {
 	// Start cleaning at intervals.
 	Start()
 
-	// Stop cleaning.
+	// Stop cleaning and wait until a running cleanup is done.
 	Stop()
 }
 
 type cacheCleaner[K comparable, V any] struct {
-	data            *csmap.CsMap[K, *entry[K, V]]
+	data   store[K, entry[K, V]]
+	events *eventHub[K, V]
+	clock  Clock
+	// returns the current time as unix nano, the cache replaces it to share its time source
+	now             func() int64
 	cleanupInterval time.Duration
-	donechn         chan (struct{})
+	stop            func()
 }
 
 func newCacheCleaner[K comparable, V any](
-	data *csmap.CsMap[K, *entry[K, V]],
+	data store[K, entry[K, V]],
+	events *eventHub[K, V],
+	clock Clock,
 	cleanupInterval time.Duration,
-) cleaner[K, V] {
+) *cacheCleaner[K, V] {
 	return &cacheCleaner[K, V]{
-		data:            data,
+		data:   data,
+		events: events,
+		clock:  clock,
+		now: func() int64 {
+			return clock.Now().UnixNano()
+		},
 		cleanupInterval: cleanupInterval,
-		donechn:         make(chan struct{}),
 	}
 }
 
 func (c *cacheCleaner[K, V]) Start() {
-	go func() {
-		ticker := time.NewTicker(c.cleanupInterval)
-		defer ticker.Stop()
-		for {
-			select {
-			case <-ticker.C:
-				c.cleanup()
-			case <-c.donechn:
-				return
-			}
-		}
-	}()
+	c.stop = c.clock.NewTicker(c.cleanupInterval, func() {
+		removeExpired(c.data, c.events, c.now())
+	})
 }
 
 func (c *cacheCleaner[K, V]) Stop() {
-	c.donechn <- struct{}{}
+	if c.stop != nil {
+		c.stop()
+	}
 }
 
-func (c *cacheCleaner[K, V]) cleanup() {
+// Removes all entries that are expired at 'now', publishes EventExpire for each of them
+// and returns the count of removed entries.
+func removeExpired[K comparable, V any](
+	data store[K, entry[K, V]],
+	events *eventHub[K, V],
+	now int64,
+) int {
 	keys := make([]K, 0)
-	c.data.Range(func(key K, entry *entry[K, V]) (stop bool) {
-		if entry.isExpired() {
+	data.Range(func(key K, entry entry[K, V]) (stop bool) {
+		if entry.isExpired(now) {
 			keys = append(keys, key)
 		}
 		return false
 	})
+
+	removed := 0
 	for _, key := range keys {
-		c.data.Delete(key)
+		if removeExpiredKey(data, events, key, now) {
+			removed++
+		}
+	}
+	return removed
+}
+
+// Removes the entry of the key if it is expired at 'now' and publishes EventExpire.
+func removeExpiredKey[K comparable, V any](data store[K, entry[K, V]], events *eventHub[K, V], key K, now int64) bool {
+	defer events.lockKey(key)()
+
+	var expired entry[K, V]
+	// the entry might have been replaced in the meantime
+	deleted := data.DeleteIf(key, func(entry entry[K, V]) bool {
+		expired = entry
+		return entry.isExpired(now)
+	})
+	if deleted {
+		oldValue, _ := expired.load()
+		events.publish(Event[K, V]{Type: EventExpire, Key: key, OldValue: oldValue})
 	}
+	return deleted
 }
@@ -4,17 +4,18 @@ import (
 	"testing"
 	"time"
 
+	"github.com/larscom/go-cache/cachetest"
 	csmap "github.com/mhmtszr/concurrent-swiss-map"
 	"github.com/stretchr/testify/assert"
 )
 
 func TestStartCleaner(t *testing.T) {
-	data := csmap.Create[int, *entry[int, int]]()
+	data := csmap.Create[int, entry[int, int]]()
 
-	data.Store(1, newEntry(1, 100, time.Now()))
-	data.Store(2, newEntry(2, 200, time.Now().Add(time.Millisecond*20)))
+	data.Store(1, newEntry(1, 100, time.Now().UnixNano()))
+	data.Store(2, newEntry(2, 200, time.Now().Add(time.Millisecond*20).UnixNano()))
 
-	cleaner := newCacheCleaner(data, time.Millisecond)
+	cleaner := newCacheCleaner(data, newEventHub[int, int](), systemClock{}, time.Millisecond)
 	defer cleaner.Stop()
 
 	cleaner.Start()
@@ -26,13 +27,13 @@ func TestStartCleaner(t *testing.T) {
 }
 
 func TestStopCleaner(t *testing.T) {
-	data := csmap.Create[int, *entry[int, int]]()
+	data := csmap.Create[int, entry[int, int]]()
 
 	const key = 1
 
-	data.Store(key, newEntry(key, 100, time.Now().Add(time.Millisecond*20)))
+	data.Store(key, newEntry(key, 100, time.Now().Add(time.Millisecond*20).UnixNano()))
 
-	cleaner := newCacheCleaner(data, time.Millisecond)
+	cleaner := newCacheCleaner(data, newEventHub[int, int](), systemClock{}, time.Millisecond)
 	cleaner.Start()
 
 	<-time.After(time.Millisecond * 5)
@@ -43,3 +44,40 @@ func TestStopCleaner(t *testing.T) {
 
 	assert.True(t, data.Has(key))
 }
+
+func TestCleanerWithFakeClock(t *testing.T) {
+	data := csmap.Create[int, entry[int, int]]()
+	clock := cachetest.NewFakeClock(time.Now())
+
+	data.Store(1, newEntry(1, 100, clock.Now().Add(time.Second).UnixNano()))
+	data.Store(2, newEntry(2, 200, clock.Now().Add(time.Minute).UnixNano()))
+
+	cleaner := newCacheCleaner(data, newEventHub[int, int](), clock, time.Second*5)
+	defer cleaner.Stop()
+
+	cleaner.Start()
+
+	clock.Advance(time.Second * 4)
+	assert.True(t, data.Has(1))
+
+	clock.Advance(time.Second)
+	assert.False(t, data.Has(1))
+	assert.True(t, data.Has(2))
+}
+
+func TestStopCleanerTwice(t *testing.T) {
+	data := csmap.Create[int, entry[int, int]]()
+
+	cleaner := newCacheCleaner(data, newEventHub[int, int](), systemClock{}, time.Millisecond)
+	cleaner.Start()
+
+	cleaner.Stop()
+	cleaner.Stop()
+}
+
+func TestStopCleanerNotStarted(t *testing.T) {
+	data := csmap.Create[int, entry[int, int]]()
+
+	cleaner := newCacheCleaner(data, newEventHub[int, int](), systemClock{}, time.Millisecond)
+	cleaner.Stop()
+}
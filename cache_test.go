@@ -1,11 +1,13 @@
 package cache
 
 import (
+	"context"
+	"runtime"
 	"sync"
 	"testing"
 	"time"
 
-	csmap "github.com/mhmtszr/concurrent-swiss-map"
+	"github.com/larscom/go-cache/cachetest"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -31,7 +33,8 @@ func TestCache(t *testing.T) {
 	t.Run("TestGet", TestGet)
 
 	TestGetWithExpireAfterWrite := func(t *testing.T) {
-		cache := NewCache(WithExpireAfterWrite[int, int](defaultTTL))
+		clock := cachetest.NewFakeClock(time.Now())
+		cache := NewCache(WithExpireAfterWrite[int, int](defaultTTL), WithClock[int, int](clock))
 		defer cache.Close()
 
 		const key = 1
@@ -42,7 +45,7 @@ func TestCache(t *testing.T) {
 		assert.True(t, found)
 		assert.Equal(t, 100, value)
 
-		<-time.After(defaultTTL + 5)
+		clock.Advance(defaultTTL + 5)
 
 		value, found = cache.Get(key)
 		assert.False(t, found)
@@ -68,7 +71,8 @@ func TestCache(t *testing.T) {
 	t.Run("TestPut", TestPut)
 
 	TestPutWithExpireAfterWrite := func(t *testing.T) {
-		cache := NewCache(WithExpireAfterWrite[int, int](defaultTTL))
+		clock := cachetest.NewFakeClock(time.Now())
+		cache := NewCache(WithExpireAfterWrite[int, int](defaultTTL), WithClock[int, int](clock))
 		defer cache.Close()
 
 		const key = 1
@@ -76,12 +80,69 @@ func TestCache(t *testing.T) {
 		cache.Put(key, 100)
 		assert.True(t, cache.Has(key))
 
-		<-time.After(defaultTTL + 5)
+		clock.Advance(defaultTTL + 5)
 
 		assert.False(t, cache.Has(key))
 	}
 	t.Run("TestPutWithExpireAfterWrite", TestPutWithExpireAfterWrite)
 
+	TestPutWithTTL := func(t *testing.T) {
+		clock := cachetest.NewFakeClock(time.Now())
+		cache := NewCache(WithExpireAfterWrite[int, int](defaultTTL), WithClock[int, int](clock)).(TTLCache[int, int])
+		defer cache.Close()
+
+		cache.PutWithTTL(1, 100, defaultTTL*2)
+		cache.PutWithTTL(2, 200, 0)
+		cache.Put(3, 300)
+
+		ttl, found := cache.TTL(1)
+		assert.True(t, found)
+		assert.Equal(t, defaultTTL*2, ttl)
+
+		ttl, found = cache.TTL(2)
+		assert.True(t, found)
+		assert.Zero(t, ttl)
+
+		ttl, found = cache.TTL(3)
+		assert.True(t, found)
+		assert.Equal(t, defaultTTL, ttl)
+
+		clock.Advance(defaultTTL + 5)
+
+		assert.True(t, cache.Has(1))
+		assert.True(t, cache.Has(2))
+		assert.False(t, cache.Has(3))
+
+		ttl, _ = cache.TTL(1)
+		assert.Equal(t, defaultTTL-5, ttl)
+
+		clock.Advance(defaultTTL)
+
+		assert.False(t, cache.Has(1))
+		_, found = cache.TTL(1)
+		assert.False(t, found)
+		assert.True(t, cache.Has(2))
+	}
+	t.Run("TestPutWithTTL", TestPutWithTTL)
+
+	TestPutWithTTLStartsCleaner := func(t *testing.T) {
+		clock := cachetest.NewFakeClock(time.Now())
+		cache := newCache(
+			WithCleanupInterval[int, int](defaultTTL),
+			WithClock[int, int](clock),
+		)
+		defer cache.Close()
+
+		assert.False(t, cache.cleanerStarted.Load())
+
+		cache.PutWithTTL(1, 100, defaultTTL)
+		assert.True(t, cache.cleanerStarted.Load())
+
+		clock.Advance(defaultTTL * 2)
+		assert.False(t, cache.data.Has(1))
+	}
+	t.Run("TestPutWithTTLStartsCleaner", TestPutWithTTLStartsCleaner)
+
 	TestHas := func(t *testing.T) {
 		cache := NewCache[int, int]()
 		defer cache.Close()
@@ -95,7 +156,8 @@ func TestCache(t *testing.T) {
 	t.Run("TestHas", TestHas)
 
 	TestHasWithExpireAfterWrite := func(t *testing.T) {
-		cache := NewCache(WithExpireAfterWrite[int, int](defaultTTL))
+		clock := cachetest.NewFakeClock(time.Now())
+		cache := NewCache(WithExpireAfterWrite[int, int](defaultTTL), WithClock[int, int](clock))
 		defer cache.Close()
 
 		const key = 1
@@ -103,7 +165,7 @@ func TestCache(t *testing.T) {
 		cache.Put(key, 100)
 		assert.True(t, cache.Has(key))
 
-		<-time.After(defaultTTL + 5)
+		clock.Advance(defaultTTL + 5)
 
 		assert.False(t, cache.Has(key))
 	}
@@ -124,13 +186,14 @@ func TestCache(t *testing.T) {
 	t.Run("TestIsEmpty", TestIsEmpty)
 
 	TestIsEmptyWithExpireAfterWrite := func(t *testing.T) {
-		cache := NewCache(WithExpireAfterWrite[int, int](defaultTTL))
+		clock := cachetest.NewFakeClock(time.Now())
+		cache := NewCache(WithExpireAfterWrite[int, int](defaultTTL), WithClock[int, int](clock))
 		defer cache.Close()
 
 		cache.Put(1, 100)
 		assert.False(t, cache.IsEmpty())
 
-		<-time.After(defaultTTL + 5)
+		clock.Advance(defaultTTL + 5)
 		assert.True(t, cache.IsEmpty())
 	}
 	t.Run("TestIsEmptyWithExpireAfterWrite", TestIsEmptyWithExpireAfterWrite)
@@ -148,7 +211,8 @@ func TestCache(t *testing.T) {
 	t.Run("TestCount", TestCount)
 
 	TestCountWithExpireAfterWrite := func(t *testing.T) {
-		cache := NewCache(WithExpireAfterWrite[int, int](defaultTTL))
+		clock := cachetest.NewFakeClock(time.Now())
+		cache := NewCache(WithExpireAfterWrite[int, int](defaultTTL), WithClock[int, int](clock))
 		defer cache.Close()
 
 		for i := 0; i < 5; i++ {
@@ -156,7 +220,7 @@ func TestCache(t *testing.T) {
 		}
 		assert.Equal(t, 5, cache.Count())
 
-		<-time.After(defaultTTL + 5)
+		clock.Advance(defaultTTL + 5)
 
 		assert.Zero(t, cache.Count())
 	}
@@ -186,11 +250,12 @@ func TestCache(t *testing.T) {
 	t.Run("TestForEach", TestForEach)
 
 	TestForEachWithExpireAfterWrite := func(t *testing.T) {
-		cache := NewCache(WithExpireAfterWrite[int, int](defaultTTL))
+		clock := cachetest.NewFakeClock(time.Now())
+		cache := NewCache(WithExpireAfterWrite[int, int](defaultTTL), WithClock[int, int](clock))
 		defer cache.Close()
 
 		cache.Put(1, 100)
-		<-time.After(defaultTTL + 5)
+		clock.Advance(defaultTTL + 5)
 
 		cache.Put(2, 200)
 
@@ -257,12 +322,82 @@ func TestCache(t *testing.T) {
 	}
 	t.Run("TestCloseShouldClear", TestCloseShouldClear)
 
+	TestCloseTwice := func(t *testing.T) {
+		cache := NewCache(WithExpireAfterWrite[int, int](defaultTTL))
+
+		cache.Put(1, 100)
+
+		cache.Close()
+		cache.Close()
+
+		assert.Zero(t, cache.Count())
+	}
+	t.Run("TestCloseTwice", TestCloseTwice)
+
+	TestUseAfterClose := func(t *testing.T) {
+		cache := NewCache[int, int]()
+
+		cache.Put(1, 100)
+		cache.Close()
+
+		cache.Put(2, 200)
+
+		value, found := cache.Get(2)
+		assert.False(t, found)
+		assert.Zero(t, value)
+		assert.False(t, cache.Has(2))
+		assert.True(t, cache.IsEmpty())
+		assert.Zero(t, cache.CleanUp())
+
+		cache.ForEach(func(key, value int) {
+			t.Errorf("unexpected item: %d", key)
+		})
+	}
+	t.Run("TestUseAfterClose", TestUseAfterClose)
+
+	TestPutWhileClosing := func(t *testing.T) {
+		cache := newCache[int, int]()
+
+		var wg sync.WaitGroup
+		for i := range 10 {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for j := range 100 {
+					cache.Put(i*100+j, j)
+				}
+			}()
+		}
+		cache.Close()
+		wg.Wait()
+
+		cache.data.Range(func(key int, _ entry[int, int]) bool {
+			t.Errorf("unexpected item after close: %d", key)
+			return true
+		})
+	}
+	t.Run("TestPutWhileClosing", TestPutWhileClosing)
+
+	TestShutdown := func(t *testing.T) {
+		cache := NewCache[int, int]()
+
+		cache.Put(1, 100)
+
+		assert.NoError(t, cache.Shutdown(context.Background()))
+		assert.NoError(t, cache.Shutdown(context.Background()))
+		assert.Zero(t, cache.Count())
+	}
+	t.Run("TestShutdown", TestShutdown)
+
 	TestStartAndStopCleaner := func(t *testing.T) {
 		cleaner := &mockCleaner{
 			started: false,
 			stopped: false,
 		}
-		cache := newCache(csmap.Create[int, *entry[int, int]](), cleaner, WithExpireAfterWrite[int, int](time.Millisecond))
+		cache := newCache(
+			withCleaner[int, int](cleaner),
+			WithExpireAfterWrite[int, int](time.Millisecond),
+		)
 
 		assert.True(t, cleaner.started)
 		assert.False(t, cleaner.stopped)
@@ -272,4 +407,88 @@ func TestCache(t *testing.T) {
 		assert.True(t, cleaner.stopped)
 	}
 	t.Run("TestStartAndStopCleaner", TestStartAndStopCleaner)
+
+	TestCleanupIntervalDisabled := func(t *testing.T) {
+		cleaner := &mockCleaner{
+			started: false,
+			stopped: false,
+		}
+		cache := newCache(
+			withCleaner[int, int](cleaner),
+			WithExpireAfterWrite[int, int](time.Millisecond),
+			WithCleanupInterval[int, int](0),
+		)
+
+		assert.False(t, cleaner.started)
+
+		cache.Close()
+
+		assert.False(t, cleaner.stopped)
+	}
+	t.Run("TestCleanupIntervalDisabled", TestCleanupIntervalDisabled)
+
+	TestCleanUp := func(t *testing.T) {
+		clock := cachetest.NewFakeClock(time.Now())
+		cache := NewCache(
+			WithExpireAfterWrite[int, int](defaultTTL),
+			WithCleanupInterval[int, int](0),
+			WithClock[int, int](clock),
+		)
+		defer cache.Close()
+
+		cache.Put(1, 100)
+		cache.Put(2, 200)
+		assert.Zero(t, cache.CleanUp())
+
+		clock.Advance(defaultTTL + 5)
+
+		cache.Put(3, 300)
+
+		assert.Equal(t, 2, cache.CleanUp())
+		assert.Equal(t, 1, cache.Count())
+		assert.True(t, cache.Has(3))
+	}
+	t.Run("TestCleanUp", TestCleanUp)
+
+	TestCleanupWithClock := func(t *testing.T) {
+		clock := cachetest.NewFakeClock(time.Now())
+		cache := newCache(
+			WithExpireAfterWrite[int, int](defaultTTL),
+			WithCleanupInterval[int, int](defaultTTL*2),
+			WithClock[int, int](clock),
+		)
+		defer cache.Close()
+
+		cache.Put(1, 100)
+
+		clock.Advance(defaultTTL + 5)
+		assert.True(t, cache.data.Has(1))
+
+		clock.Advance(defaultTTL)
+		assert.False(t, cache.data.Has(1))
+	}
+	t.Run("TestCleanupWithClock", TestCleanupWithClock)
+}
+
+func TestCacheFinalizerStopsCleaner(t *testing.T) {
+	before := runtime.NumGoroutine()
+
+	func() {
+		for i := 0; i < 10; i++ {
+			cache := NewCache(WithExpireAfterWrite[int, int](time.Minute))
+			cache.Put(i, i)
+
+			loadingCache := NewLoadingCache(NoopLoaderFunc[int, int], WithExpireAfterWrite[int, int](time.Minute))
+			loadingCache.Put(i, i)
+		}
+	}()
+	assert.Greater(t, runtime.NumGoroutine(), before)
+
+	deadline := time.Now().Add(time.Second * 2)
+	for runtime.NumGoroutine() > before && time.Now().Before(deadline) {
+		runtime.GC()
+		time.Sleep(time.Millisecond * 10)
+	}
+
+	assert.LessOrEqual(t, runtime.NumGoroutine(), before)
 }
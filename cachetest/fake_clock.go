@@ -0,0 +1,101 @@
+// Package cachetest provides utilities for testing code that uses a cache.
+package cachetest
+
+import (
+	"sync"
+	"time"
+)
+
+// FakeClock is a clock that only moves forward when advanced manually.
+//
+// It can be passed to 'cache.WithClock' to test expiration without sleeping.
+type FakeClock struct {
+	mu      sync.Mutex
+	now     time.Time
+	tickers []*fakeTicker
+}
+
+type fakeTicker struct {
+	interval time.Duration
+	next     time.Time
+	fn       func()
+}
+
+// Create a new fake clock starting at the given time.
+func NewFakeClock(now time.Time) *FakeClock {
+	return &FakeClock{now: now}
+}
+
+// Returns the current (fake) time.
+func (c *FakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+// Registers fn to be called at every interval while the clock gets advanced.
+//
+// Panics when interval is not positive, like 'time.NewTicker' does.
+func (c *FakeClock) NewTicker(interval time.Duration, fn func()) func() {
+	if interval <= 0 {
+		panic("non-positive interval for NewTicker")
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	ticker := &fakeTicker{
+		interval: interval,
+		next:     c.now.Add(interval),
+		fn:       fn,
+	}
+	c.tickers = append(c.tickers, ticker)
+
+	return func() {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		for i, t := range c.tickers {
+			if t == ticker {
+				c.tickers = append(c.tickers[:i], c.tickers[i+1:]...)
+				return
+			}
+		}
+	}
+}
+
+// Moves the clock forward by d.
+//
+// Every ticker that is due gets called synchronously (in order) before Advance returns,
+// so a cache cleanup has completed once this function returns.
+func (c *FakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	target := c.now.Add(d)
+	for {
+		ticker := c.nextTicker(target)
+		if ticker == nil {
+			break
+		}
+		c.now = ticker.next
+		ticker.next = ticker.next.Add(ticker.interval)
+
+		c.mu.Unlock()
+		ticker.fn()
+		c.mu.Lock()
+	}
+	c.now = target
+	c.mu.Unlock()
+}
+
+// Returns the ticker that is due first (not after target) or nil if there is none.
+func (c *FakeClock) nextTicker(target time.Time) *fakeTicker {
+	var next *fakeTicker
+	for _, t := range c.tickers {
+		if t.next.After(target) {
+			continue
+		}
+		if next == nil || t.next.Before(next.next) {
+			next = t
+		}
+	}
+	return next
+}
@@ -0,0 +1,63 @@
+package cachetest
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFakeClockAdvance(t *testing.T) {
+	start := time.Now()
+	clock := NewFakeClock(start)
+
+	assert.Equal(t, start, clock.Now())
+
+	clock.Advance(time.Minute)
+
+	assert.Equal(t, start.Add(time.Minute), clock.Now())
+}
+
+func TestFakeClockTicker(t *testing.T) {
+	clock := NewFakeClock(time.Now())
+
+	ticks := make([]time.Time, 0)
+	stop := clock.NewTicker(time.Second, func() {
+		ticks = append(ticks, clock.Now())
+	})
+
+	clock.Advance(time.Millisecond * 500)
+	assert.Empty(t, ticks)
+
+	clock.Advance(time.Millisecond * 2600)
+	assert.Len(t, ticks, 3)
+
+	stop()
+	clock.Advance(time.Minute)
+	assert.Len(t, ticks, 3)
+}
+
+func TestFakeClockTickerNonPositiveInterval(t *testing.T) {
+	clock := NewFakeClock(time.Now())
+
+	assert.PanicsWithValue(t, "non-positive interval for NewTicker", func() {
+		clock.NewTicker(0, func() {})
+	})
+	assert.Panics(t, func() {
+		clock.NewTicker(-time.Second, func() {})
+	})
+
+	clock.Advance(time.Minute)
+}
+
+func TestFakeClockTickersInOrder(t *testing.T) {
+	clock := NewFakeClock(time.Now())
+
+	order := make([]string, 0)
+	defer clock.NewTicker(time.Second*2, func() { order = append(order, "slow") })()
+	defer clock.NewTicker(time.Second, func() { order = append(order, "fast") })()
+
+	clock.Advance(time.Second * 2)
+
+	assert.Equal(t, []string{"fast", "slow", "fast"}, order)
+}
@@ -0,0 +1,96 @@
+package cache
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// How often the coarse clock reads the system time, the accuracy of expiration on the system clock.
+const coarseClockResolution = time.Millisecond * 100
+
+// Source of time for expiration and background cleanup.
+//
+// The default clock uses the system time, replace it using 'WithClock' to control time in tests.
+type Clock interface {
+	// Returns the current time.
+	Now() time.Time
+
+	// Calls fn at every interval until the returned stop function is called.
+	//
+	// The stop function must be safe to call more than once.
+	NewTicker(interval time.Duration, fn func()) (stop func())
+}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
+
+// The returned stop function waits for the ticker goroutine to exit.
+func (systemClock) NewTicker(interval time.Duration, fn func()) func() {
+	donechn := make(chan struct{})
+	exitchn := make(chan struct{})
+	go func() {
+		defer close(exitchn)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				fn()
+			case <-donechn:
+				return
+			}
+		}
+	}()
+	var once sync.Once
+	return func() {
+		once.Do(func() { close(donechn) })
+		<-exitchn
+	}
+}
+
+// A shared clock that reads the system time once per resolution, so reading the time
+// for expiration on every 'Get' and 'Put' is an atomic load instead of a call to time.Now.
+//
+// Only caches that use the system clock use it once their items can expire (e.g. 'WithExpireAfterWrite'
+// or 'PutWithTTL'), the ticker runs while at least one of them is open.
+type coarseClock struct {
+	mu    sync.Mutex
+	users int
+	stop  func()
+	nanos atomic.Int64
+}
+
+var sharedCoarseClock = &coarseClock{}
+
+func (c *coarseClock) acquire() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.users++
+	if c.users == 1 {
+		c.nanos.Store(time.Now().UnixNano())
+		c.stop = systemClock{}.NewTicker(coarseClockResolution, func() {
+			c.nanos.Store(time.Now().UnixNano())
+		})
+	}
+}
+
+func (c *coarseClock) release() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.users--
+	if c.users == 0 {
+		c.stop()
+		c.stop = nil
+	}
+}
+
+// Returns the time of the last tick as unix nano.
+func (c *coarseClock) now() int64 {
+	return c.nanos.Load()
+}
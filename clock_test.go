@@ -0,0 +1,97 @@
+package cache
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCoarseClock(t *testing.T) {
+	clock := &coarseClock{}
+
+	clock.acquire()
+	clock.acquire()
+	assert.InDelta(t, time.Now().UnixNano(), clock.now(), float64(time.Second))
+
+	start := clock.now()
+	assert.Eventually(t, func() bool {
+		return clock.now() > start
+	}, time.Second, coarseClockResolution)
+
+	clock.release()
+	assert.NotNil(t, clock.stop)
+
+	clock.release()
+	assert.Nil(t, clock.stop)
+}
+
+func TestCoarseClockOnlyForSystemClock(t *testing.T) {
+	cache := newCache(WithExpireAfterWrite[int, int](time.Minute))
+	assert.True(t, cache.coarse.Load())
+	cache.Close()
+	assert.False(t, cache.coarse.Load())
+
+	fake := newCache(WithClock[int, int](systemClockWrapper{}), WithExpireAfterWrite[int, int](time.Minute))
+	defer fake.Close()
+	assert.False(t, fake.coarse.Load())
+}
+
+func TestCoarseClockOnlyWhenItemsExpire(t *testing.T) {
+	cache := newCache[int, int]()
+	defer cache.Close()
+
+	cache.Put(1, 1)
+	cache.PutWithTTL(2, 2, 0)
+	assert.False(t, cache.coarse.Load())
+
+	cache.PutWithTTL(3, 3, time.Minute)
+	assert.True(t, cache.coarse.Load())
+
+	ttl, found := cache.TTL(3)
+	assert.True(t, found)
+	assert.InDelta(t, time.Minute, ttl, float64(time.Second))
+
+	loading := newCache[int, int]()
+	defer loading.Close()
+
+	_, err := loading.loadWith(1, NoopLoaderFunc[int, int], time.Minute)
+	assert.NoError(t, err)
+	assert.True(t, loading.coarse.Load())
+}
+
+func TestExpireWithCoarseClock(t *testing.T) {
+	ttl := coarseClockResolution * 3
+	cache := newCache(WithExpireAfterWrite[int, int](ttl), WithCleanupInterval[int, int](coarseClockResolution))
+	defer cache.Close()
+	assert.True(t, cache.coarse.Load())
+
+	eventchn, cancel := cache.Subscribe(10)
+	defer cancel()
+
+	start := time.Now()
+	cache.Put(1, 1)
+	assert.True(t, cache.Has(1))
+
+	remaining, found := cache.TTL(1)
+	assert.True(t, found)
+	assert.InDelta(t, ttl, remaining, float64(coarseClockResolution))
+
+	assert.Eventually(t, func() bool {
+		return !cache.Has(1)
+	}, ttl*3, time.Millisecond*10)
+	assert.InDelta(t, ttl, time.Since(start), float64(coarseClockResolution*2))
+
+	// the cleaner uses the same time as the cache
+	assert.Equal(t, EventPut, (<-eventchn).Type)
+	select {
+	case event := <-eventchn:
+		assert.Equal(t, EventExpire, event.Type)
+	case <-time.After(ttl):
+		t.Fatal("expected the cleaner to remove the expired item")
+	}
+}
+
+type systemClockWrapper struct {
+	systemClock
+}
@@ -0,0 +1,67 @@
+// Command go-cache-server serves a cache over the Redis protocol, so any Redis client (e.g. redis-cli) can use it.
+//
+// Supported commands: GET, SET (with EX/PX), DEL, EXISTS, DBSIZE, FLUSHALL, TTL, PTTL, SCAN and PING.
+//
+//	go-cache-server -addr :6379 -expire-after-write 10m
+//	redis-cli -p 6379 SET greeting "Hello World" EX 60
+package main
+
+import (
+	"context"
+	"flag"
+	"log/slog"
+	"net"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/larscom/go-cache"
+)
+
+func main() {
+	if err := run(); err != nil {
+		slog.Error("go-cache-server", "error", err)
+		os.Exit(1)
+	}
+}
+
+func run() error {
+	var (
+		addr             = flag.String("addr", ":6379", "the address to listen on")
+		expireAfterWrite = flag.Duration("expire-after-write", 0, "the TTL of items that are set without EX/PX, 0 means no expiration")
+		cleanupInterval  = flag.Duration("cleanup-interval", time.Second*5, "the interval at which expired items are removed")
+		snapshot         = flag.String("snapshot", "", "the file to restore from at startup and to save to at the snapshot interval and on exit")
+		snapshotInterval = flag.Duration("snapshot-interval", time.Minute, "the interval at which the snapshot is saved, 0 saves on exit only")
+		maxBulkLen       = flag.Int("max-bulk-len", defaultMaxBulkLen, "the maximum length in bytes of a key or value in a request")
+	)
+	flag.Parse()
+
+	options := []cache.Option[string, []byte]{
+		cache.WithExpireAfterWrite[string, []byte](*expireAfterWrite),
+		cache.WithCleanupInterval[string, []byte](*cleanupInterval),
+	}
+	if *snapshot != "" {
+		options = append(options, cache.WithSnapshot(*snapshot, cache.GobCodec[string, []byte]{}, *snapshotInterval))
+	}
+
+	c := cache.NewCache(options...).(cache.TTLCache[string, []byte])
+	defer c.Close()
+
+	l, err := net.Listen("tcp", *addr)
+	if err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	s := newServer(c, *maxBulkLen)
+	go func() {
+		<-ctx.Done()
+		s.Close()
+	}()
+
+	slog.Info("go-cache-server", "addr", l.Addr().String())
+	return s.Serve(l)
+}
@@ -0,0 +1,165 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+const (
+	maxArgs           = 1024 * 1024
+	defaultMaxBulkLen = 8 * 1024 * 1024
+	readerSize        = 64 * 1024
+)
+
+var errProtocol = errors.New("protocol error")
+
+// Reads RESP requests, both arrays of bulk strings (what clients send) and inline commands (telnet).
+type respReader struct {
+	r          *bufio.Reader
+	maxBulkLen int
+}
+
+func newRESPReader(r io.Reader, maxBulkLen int) *respReader {
+	return &respReader{r: bufio.NewReaderSize(r, readerSize), maxBulkLen: maxBulkLen}
+}
+
+// Returns the next command with its arguments, an empty command is skipped.
+func (r *respReader) readCommand() ([][]byte, error) {
+	for {
+		line, err := r.readLine()
+		if err != nil {
+			return nil, err
+		}
+		if len(line) == 0 {
+			continue
+		}
+		if line[0] != '*' {
+			args := bytes.Fields(line)
+			if len(args) == 0 {
+				continue
+			}
+			// the line points into the read buffer
+			for i := range args {
+				args[i] = bytes.Clone(args[i])
+			}
+			return args, nil
+		}
+
+		n, err := strconv.Atoi(string(line[1:]))
+		if err != nil || n > maxArgs {
+			return nil, fmt.Errorf("%w: invalid multibulk length", errProtocol)
+		}
+		if n <= 0 {
+			continue
+		}
+
+		// the lengths are sent before the data, so memory only grows with the data that arrives
+		args := make([][]byte, 0, min(n, 16))
+		for range n {
+			arg, err := r.readBulk()
+			if err != nil {
+				return nil, err
+			}
+			args = append(args, arg)
+		}
+		return args, nil
+	}
+}
+
+func (r *respReader) readBulk() ([]byte, error) {
+	line, err := r.readLine()
+	if err != nil {
+		return nil, err
+	}
+	if len(line) == 0 || line[0] != '$' {
+		return nil, fmt.Errorf("%w: expected '$', got '%s'", errProtocol, line)
+	}
+
+	n, err := strconv.Atoi(string(line[1:]))
+	if err != nil || n < 0 || n > r.maxBulkLen {
+		return nil, fmt.Errorf("%w: invalid bulk length", errProtocol)
+	}
+
+	buf := bytes.NewBuffer(make([]byte, 0, min(n+2, readerSize)))
+	if _, err := io.CopyN(buf, r.r, int64(n+2)); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+	data := buf.Bytes()
+	if data[n] != '\r' || data[n+1] != '\n' {
+		return nil, fmt.Errorf("%w: invalid bulk terminator", errProtocol)
+	}
+	return data[:n], nil
+}
+
+// Reads a line without the trailing "\r\n" (or "\n" for inline commands).
+func (r *respReader) readLine() ([]byte, error) {
+	line, err := r.r.ReadSlice('\n')
+	if err == bufio.ErrBufferFull {
+		return nil, fmt.Errorf("%w: too big inline request", errProtocol)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return bytes.TrimRight(line, "\r\n"), nil
+}
+
+// Returns true when the next command is already buffered, used to flush replies of pipelined commands at once.
+func (r *respReader) buffered() bool {
+	return r.r.Buffered() > 0
+}
+
+// Writes RESP2 replies.
+type respWriter struct {
+	w *bufio.Writer
+}
+
+func newRESPWriter(w io.Writer) *respWriter {
+	return &respWriter{w: bufio.NewWriter(w)}
+}
+
+func (w *respWriter) writeSimple(s string) {
+	w.w.WriteByte('+')
+	w.w.WriteString(s)
+	w.w.WriteString("\r\n")
+}
+
+func (w *respWriter) writeError(s string) {
+	w.w.WriteByte('-')
+	w.w.WriteString(s)
+	w.w.WriteString("\r\n")
+}
+
+func (w *respWriter) writeInt(n int64) {
+	w.w.WriteByte(':')
+	w.w.WriteString(strconv.FormatInt(n, 10))
+	w.w.WriteString("\r\n")
+}
+
+func (w *respWriter) writeBulk(data []byte) {
+	w.w.WriteByte('$')
+	w.w.WriteString(strconv.Itoa(len(data)))
+	w.w.WriteString("\r\n")
+	w.w.Write(data)
+	w.w.WriteString("\r\n")
+}
+
+func (w *respWriter) writeNull() {
+	w.w.WriteString("$-1\r\n")
+}
+
+func (w *respWriter) writeArrayLen(n int) {
+	w.w.WriteByte('*')
+	w.w.WriteString(strconv.Itoa(n))
+	w.w.WriteString("\r\n")
+}
+
+func (w *respWriter) flush() error {
+	return w.w.Flush()
+}
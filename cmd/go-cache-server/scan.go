@@ -0,0 +1,184 @@
+package main
+
+import (
+	"hash/fnv"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+const defaultScanCount = 10
+
+// SCAN cursor [MATCH pattern] [COUNT count]
+//
+// The cache has no stable iteration order, so keys are returned ordered by their hash and the cursor
+// is the hash of the next key. Like Redis, every key that exists during the full iteration is returned,
+// each call does a full pass over the cache though.
+func scan(s *server, w *respWriter, args [][]byte) bool {
+	cursor, err := strconv.ParseUint(string(args[1]), 10, 64)
+	if err != nil {
+		w.writeError("ERR invalid cursor")
+		return false
+	}
+
+	pattern, count := "", defaultScanCount
+	for i := 2; i < len(args); i += 2 {
+		if i+1 == len(args) {
+			w.writeError("ERR syntax error")
+			return false
+		}
+		switch strings.ToUpper(string(args[i])) {
+		case "MATCH":
+			pattern = string(args[i+1])
+		case "COUNT":
+			count, err = strconv.Atoi(string(args[i+1]))
+			if err != nil {
+				w.writeError("ERR value is not an integer or out of range")
+				return false
+			}
+			if count < 1 {
+				w.writeError("ERR syntax error")
+				return false
+			}
+		default:
+			w.writeError("ERR syntax error")
+			return false
+		}
+	}
+
+	next, keys := scanKeys(s, cursor, pattern, count)
+
+	w.writeArrayLen(2)
+	w.writeBulk([]byte(strconv.FormatUint(next, 10)))
+	w.writeArrayLen(len(keys))
+	for _, key := range keys {
+		w.writeBulk([]byte(key))
+	}
+	return false
+}
+
+type scanKey struct {
+	hash uint64
+	key  string
+}
+
+// Returns up to count keys (more when hashes collide) with a hash >= cursor and the next cursor, 0 when done.
+func scanKeys(s *server, cursor uint64, pattern string, count int) (uint64, []string) {
+	var candidates []scanKey
+	s.cache.ForEach(func(key string, _ []byte) {
+		hash := hashKey(key)
+		if hash >= cursor && (pattern == "" || matchGlob(pattern, key)) {
+			candidates = append(candidates, scanKey{hash, key})
+		}
+	})
+
+	slices.SortFunc(candidates, func(a, b scanKey) int {
+		if a.hash != b.hash {
+			if a.hash < b.hash {
+				return -1
+			}
+			return 1
+		}
+		return strings.Compare(a.key, b.key)
+	})
+
+	// keys with the same hash share a cursor, so they are returned together
+	end := min(count, len(candidates))
+	for end < len(candidates) && candidates[end].hash == candidates[end-1].hash {
+		end++
+	}
+
+	keys := make([]string, end)
+	for i := range keys {
+		keys[i] = candidates[i].key
+	}
+
+	if end == len(candidates) {
+		return 0, keys
+	}
+	return candidates[end].hash, keys
+}
+
+func hashKey(key string) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(key))
+	return h.Sum64()
+}
+
+// Reports whether s matches the glob-style pattern of Redis: '*', '?', '[abc]', '[^a]', '[a-z]' and '\' to escape.
+func matchGlob(pattern, s string) bool {
+	for len(pattern) > 0 {
+		switch pattern[0] {
+		case '*':
+			for len(pattern) > 1 && pattern[1] == '*' {
+				pattern = pattern[1:]
+			}
+			if len(pattern) == 1 {
+				return true
+			}
+			for i := 0; i <= len(s); i++ {
+				if matchGlob(pattern[1:], s[i:]) {
+					return true
+				}
+			}
+			return false
+		case '?':
+			if len(s) == 0 {
+				return false
+			}
+		case '[':
+			if len(s) == 0 {
+				return false
+			}
+			matched, rest := matchClass(pattern[1:], s[0])
+			if !matched {
+				return false
+			}
+			pattern, s = rest, s[1:]
+			continue
+		case '\\':
+			if len(pattern) > 1 {
+				pattern = pattern[1:]
+			}
+			fallthrough
+		default:
+			if len(s) == 0 || pattern[0] != s[0] {
+				return false
+			}
+		}
+		pattern, s = pattern[1:], s[1:]
+	}
+	return len(s) == 0
+}
+
+// Matches c against a character class, pattern starts after '[', returns the pattern after ']'.
+func matchClass(pattern string, c byte) (bool, string) {
+	negate := len(pattern) > 0 && pattern[0] == '^'
+	if negate {
+		pattern = pattern[1:]
+	}
+
+	matched := false
+	for len(pattern) > 0 && pattern[0] != ']' {
+		switch {
+		case pattern[0] == '\\' && len(pattern) > 1:
+			matched = matched || pattern[1] == c
+			pattern = pattern[2:]
+		case len(pattern) > 2 && pattern[1] == '-' && pattern[2] != ']':
+			lo, hi := pattern[0], pattern[2]
+			if lo > hi {
+				lo, hi = hi, lo
+			}
+			matched = matched || (c >= lo && c <= hi)
+			pattern = pattern[3:]
+		default:
+			matched = matched || pattern[0] == c
+			pattern = pattern[1:]
+		}
+	}
+	if len(pattern) > 0 {
+		pattern = pattern[1:]
+	}
+
+	return matched != negate, pattern
+}
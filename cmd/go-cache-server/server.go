@@ -0,0 +1,340 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/larscom/go-cache"
+)
+
+// A command handler, arity is the exact count of arguments (including the name) or the minimum when negative.
+type command struct {
+	arity int
+	fn    func(s *server, w *respWriter, args [][]byte) (quit bool)
+}
+
+var commands map[string]command
+
+func init() {
+	commands = map[string]command{
+		"PING":     {-1, ping},
+		"ECHO":     {2, echo},
+		"QUIT":     {1, quit},
+		"HELLO":    {-1, hello},
+		"CLIENT":   {-2, client},
+		"COMMAND":  {-1, commandInfo},
+		"SELECT":   {2, selectDB},
+		"GET":      {2, get},
+		"SET":      {-3, set},
+		"DEL":      {-2, del},
+		"UNLINK":   {-2, del},
+		"EXISTS":   {-2, exists},
+		"DBSIZE":   {1, dbsize},
+		"FLUSHALL": {-1, flushall},
+		"FLUSHDB":  {-1, flushall},
+		"TTL":      {2, ttl},
+		"PTTL":     {2, pttl},
+		"SCAN":     {-2, scan},
+	}
+}
+
+// Serves a cache over the Redis protocol (RESP2), every client shares the same cache.
+type server struct {
+	cache      cache.TTLCache[string, []byte]
+	maxBulkLen int
+
+	mu        sync.Mutex
+	listeners map[net.Listener]struct{}
+	conns     map[net.Conn]struct{}
+	closed    bool
+	wg        sync.WaitGroup
+}
+
+// Arguments of commands (e.g. the value of SET) can't be longer than maxBulkLen bytes.
+func newServer(c cache.TTLCache[string, []byte], maxBulkLen int) *server {
+	return &server{
+		cache:      c,
+		maxBulkLen: maxBulkLen,
+		listeners:  make(map[net.Listener]struct{}),
+		conns:      make(map[net.Conn]struct{}),
+	}
+}
+
+// Accepts connections until the server is closed, returns nil after 'Close'.
+func (s *server) Serve(l net.Listener) error {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return l.Close()
+	}
+	s.listeners[l] = struct{}{}
+	s.mu.Unlock()
+
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			if s.isClosed() {
+				return nil
+			}
+			return err
+		}
+
+		s.mu.Lock()
+		if s.closed {
+			s.mu.Unlock()
+			conn.Close()
+			return nil
+		}
+		s.conns[conn] = struct{}{}
+		s.wg.Add(1)
+		s.mu.Unlock()
+
+		go s.serveConn(conn)
+	}
+}
+
+// Closes the listeners and all connections and waits until the connections are done.
+func (s *server) Close() error {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return nil
+	}
+	s.closed = true
+
+	var errs []error
+	for l := range s.listeners {
+		errs = append(errs, l.Close())
+	}
+	for conn := range s.conns {
+		conn.Close()
+	}
+	s.mu.Unlock()
+
+	s.wg.Wait()
+	return errors.Join(errs...)
+}
+
+func (s *server) isClosed() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.closed
+}
+
+func (s *server) serveConn(conn net.Conn) {
+	defer func() {
+		s.mu.Lock()
+		delete(s.conns, conn)
+		s.mu.Unlock()
+		conn.Close()
+		s.wg.Done()
+	}()
+
+	r := newRESPReader(conn, s.maxBulkLen)
+	w := newRESPWriter(conn)
+
+	for {
+		args, err := r.readCommand()
+		if err != nil {
+			if errors.Is(err, errProtocol) {
+				w.writeError("ERR " + strings.Replace(err.Error(), "protocol error", "Protocol error", 1))
+				w.flush()
+			} else if !errors.Is(err, io.EOF) && !s.isClosed() {
+				slog.Debug("go-cache-server", "remote", conn.RemoteAddr(), "error", err)
+			}
+			return
+		}
+
+		quit := s.execute(w, args)
+
+		if !r.buffered() || quit {
+			if err := w.flush(); err != nil || quit {
+				return
+			}
+		}
+	}
+}
+
+func (s *server) execute(w *respWriter, args [][]byte) (quit bool) {
+	name := strings.ToUpper(string(args[0]))
+	cmd, found := commands[name]
+	if !found {
+		w.writeError(fmt.Sprintf("ERR unknown command '%s'", args[0]))
+		return false
+	}
+	if (cmd.arity > 0 && len(args) != cmd.arity) || (cmd.arity < 0 && len(args) < -cmd.arity) {
+		w.writeError(fmt.Sprintf("ERR wrong number of arguments for '%s' command", strings.ToLower(name)))
+		return false
+	}
+	return cmd.fn(s, w, args)
+}
+
+func ping(_ *server, w *respWriter, args [][]byte) bool {
+	switch len(args) {
+	case 1:
+		w.writeSimple("PONG")
+	case 2:
+		w.writeBulk(args[1])
+	default:
+		w.writeError("ERR wrong number of arguments for 'ping' command")
+	}
+	return false
+}
+
+func echo(_ *server, w *respWriter, args [][]byte) bool {
+	w.writeBulk(args[1])
+	return false
+}
+
+func quit(_ *server, w *respWriter, _ [][]byte) bool {
+	w.writeSimple("OK")
+	return true
+}
+
+// Only RESP2 is supported, clients fall back to RESP2 on this error.
+func hello(_ *server, w *respWriter, _ [][]byte) bool {
+	w.writeError("NOPROTO unsupported protocol version")
+	return false
+}
+
+// Accepts client metadata (e.g. 'CLIENT SETNAME') that clients send on connect.
+func client(_ *server, w *respWriter, _ [][]byte) bool {
+	w.writeSimple("OK")
+	return false
+}
+
+// Clients (e.g. redis-cli) ask for command docs on connect, which are not available.
+func commandInfo(_ *server, w *respWriter, _ [][]byte) bool {
+	w.writeArrayLen(0)
+	return false
+}
+
+func selectDB(_ *server, w *respWriter, args [][]byte) bool {
+	if string(args[1]) != "0" {
+		w.writeError("ERR DB index is out of range")
+		return false
+	}
+	w.writeSimple("OK")
+	return false
+}
+
+func get(s *server, w *respWriter, args [][]byte) bool {
+	if value, found := s.cache.Get(string(args[1])); found {
+		w.writeBulk(value)
+	} else {
+		w.writeNull()
+	}
+	return false
+}
+
+// SET key value [EX seconds | PX milliseconds]
+func set(s *server, w *respWriter, args [][]byte) bool {
+	key, value := string(args[1]), args[2]
+
+	var ttl time.Duration
+	for i := 3; i < len(args); i++ {
+		option := strings.ToUpper(string(args[i]))
+		if (option != "EX" && option != "PX") || ttl != 0 || i+1 == len(args) {
+			w.writeError("ERR syntax error")
+			return false
+		}
+
+		i++
+		n, err := strconv.ParseInt(string(args[i]), 10, 64)
+		if err != nil {
+			w.writeError("ERR value is not an integer or out of range")
+			return false
+		}
+		unit := time.Second
+		if option == "PX" {
+			unit = time.Millisecond
+		}
+		if n <= 0 || n > int64(time.Duration(1<<63-1)/unit) {
+			w.writeError("ERR invalid expire time in 'set' command")
+			return false
+		}
+		ttl = time.Duration(n) * unit
+	}
+
+	if ttl > 0 {
+		s.cache.PutWithTTL(key, value, ttl)
+	} else {
+		s.cache.Put(key, value)
+	}
+	w.writeSimple("OK")
+	return false
+}
+
+func del(s *server, w *respWriter, args [][]byte) bool {
+	var deleted int64
+	for _, key := range args[1:] {
+		if s.cache.Has(string(key)) {
+			s.cache.Delete(string(key))
+			deleted++
+		}
+	}
+	w.writeInt(deleted)
+	return false
+}
+
+func exists(s *server, w *respWriter, args [][]byte) bool {
+	var count int64
+	for _, key := range args[1:] {
+		if s.cache.Has(string(key)) {
+			count++
+		}
+	}
+	w.writeInt(count)
+	return false
+}
+
+func dbsize(s *server, w *respWriter, _ [][]byte) bool {
+	w.writeInt(int64(s.cache.Count()))
+	return false
+}
+
+// FLUSHALL [ASYNC | SYNC], both modes clear the cache synchronously.
+func flushall(s *server, w *respWriter, args [][]byte) bool {
+	if len(args) > 2 {
+		w.writeError("ERR syntax error")
+		return false
+	}
+	if len(args) == 2 {
+		if mode := strings.ToUpper(string(args[1])); mode != "ASYNC" && mode != "SYNC" {
+			w.writeError("ERR syntax error")
+			return false
+		}
+	}
+	s.cache.Clear()
+	w.writeSimple("OK")
+	return false
+}
+
+func ttl(s *server, w *respWriter, args [][]byte) bool {
+	w.writeInt(remainingTTL(s, args[1], time.Second))
+	return false
+}
+
+func pttl(s *server, w *respWriter, args [][]byte) bool {
+	w.writeInt(remainingTTL(s, args[1], time.Millisecond))
+	return false
+}
+
+// Returns -2 when the key does not exist, -1 when the key does not expire, the rounded TTL in unit otherwise.
+func remainingTTL(s *server, key []byte, unit time.Duration) int64 {
+	ttl, found := s.cache.TTL(string(key))
+	if !found {
+		return -2
+	}
+	if ttl == 0 {
+		return -1
+	}
+	return int64((ttl + unit/2) / unit)
+}
@@ -0,0 +1,296 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/larscom/go-cache"
+	"github.com/larscom/go-cache/cachetest"
+	"github.com/stretchr/testify/assert"
+)
+
+// A minimal RESP2 client, replies are a string, an int64, nil, an error or a []any.
+type testClient struct {
+	conn net.Conn
+	r    *bufio.Reader
+}
+
+func (c *testClient) do(args ...any) (any, error) {
+	var b strings.Builder
+	fmt.Fprintf(&b, "*%d\r\n", len(args))
+	for _, arg := range args {
+		s := fmt.Sprint(arg)
+		fmt.Fprintf(&b, "$%d\r\n%s\r\n", len(s), s)
+	}
+	if _, err := io.WriteString(c.conn, b.String()); err != nil {
+		return nil, err
+	}
+	return c.readReply()
+}
+
+func (c *testClient) readReply() (any, error) {
+	line, err := c.r.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	line = strings.TrimSuffix(line, "\r\n")
+
+	switch line[0] {
+	case '+':
+		return line[1:], nil
+	case '-':
+		return nil, errors.New(line[1:])
+	case ':':
+		return strconv.ParseInt(line[1:], 10, 64)
+	case '$':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil || n < 0 {
+			return nil, err
+		}
+		data := make([]byte, n+2)
+		if _, err := io.ReadFull(c.r, data); err != nil {
+			return nil, err
+		}
+		return string(data[:n]), nil
+	case '*':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil {
+			return nil, err
+		}
+		items := make([]any, n)
+		for i := range items {
+			if items[i], err = c.readReply(); err != nil {
+				return nil, err
+			}
+		}
+		return items, nil
+	}
+	return nil, fmt.Errorf("unexpected reply: %q", line)
+}
+
+func (c *testClient) val(args ...any) any {
+	reply, _ := c.do(args...)
+	return reply
+}
+
+func newTestServer(t *testing.T) (*testClient, *cachetest.FakeClock, string) {
+	clock := cachetest.NewFakeClock(time.Now())
+	c := cache.NewCache(cache.WithClock[string, []byte](clock)).(cache.TTLCache[string, []byte])
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+
+	s := newServer(c, defaultMaxBulkLen)
+	donechn := make(chan error, 1)
+	go func() { donechn <- s.Serve(l) }()
+
+	conn, err := net.Dial("tcp", l.Addr().String())
+	assert.NoError(t, err)
+	client := &testClient{conn: conn, r: bufio.NewReader(conn)}
+
+	t.Cleanup(func() {
+		conn.Close()
+		assert.NoError(t, s.Close())
+		assert.NoError(t, <-donechn)
+		c.Close()
+	})
+
+	return client, clock, l.Addr().String()
+}
+
+func TestServer(t *testing.T) {
+	TestGetAndSet := func(t *testing.T) {
+		client, _, _ := newTestServer(t)
+
+		assert.Equal(t, "PONG", client.val("PING"))
+		assert.Equal(t, "OK", client.val("SET", "greeting", "Hello World"))
+
+		assert.Equal(t, "Hello World", client.val("GET", "greeting"))
+
+		value, err := client.do("GET", "missing")
+		assert.NoError(t, err)
+		assert.Nil(t, value)
+	}
+	t.Run("TestGetAndSet", TestGetAndSet)
+
+	TestSetWithExpiration := func(t *testing.T) {
+		client, clock, _ := newTestServer(t)
+
+		assert.Equal(t, "OK", client.val("SET", "ex", "1", "EX", 60))
+		assert.Equal(t, "OK", client.val("SET", "px", "2", "PX", 1500))
+		assert.Equal(t, "OK", client.val("SET", "none", "3"))
+
+		assert.Equal(t, int64(60), client.val("TTL", "ex"))
+		assert.Equal(t, int64(1500), client.val("PTTL", "px"))
+		assert.Equal(t, int64(-1), client.val("TTL", "none"))
+		assert.Equal(t, int64(-2), client.val("TTL", "missing"))
+
+		clock.Advance(time.Second * 2)
+
+		assert.Equal(t, int64(2), client.val("EXISTS", "ex", "px", "none"))
+		assert.Equal(t, int64(58), client.val("TTL", "ex"))
+
+		for _, args := range [][]any{
+			{"SET", "k", "v", "EX", "0"},
+			{"SET", "k", "v", "EX", "a"},
+			{"SET", "k", "v", "NX"},
+			{"SET", "k", "v", "EX", "1", "PX", "1"},
+		} {
+			_, err := client.do(args...)
+			assert.Error(t, err, "%v", args)
+		}
+	}
+	t.Run("TestSetWithExpiration", TestSetWithExpiration)
+
+	TestDelExistsDBSizeAndFlushAll := func(t *testing.T) {
+		client, _, _ := newTestServer(t)
+
+		for i := 0; i < 5; i++ {
+			assert.Equal(t, "OK", client.val("SET", i, i))
+		}
+		assert.Equal(t, int64(5), client.val("DBSIZE"))
+		assert.Equal(t, int64(2), client.val("EXISTS", "0", "1", "missing"))
+
+		assert.Equal(t, int64(2), client.val("DEL", "0", "1", "missing"))
+		assert.Equal(t, int64(3), client.val("DBSIZE"))
+
+		assert.Equal(t, "OK", client.val("FLUSHALL"))
+		assert.Equal(t, int64(0), client.val("DBSIZE"))
+	}
+	t.Run("TestDelExistsDBSizeAndFlushAll", TestDelExistsDBSizeAndFlushAll)
+
+	TestScan := func(t *testing.T) {
+		client, _, _ := newTestServer(t)
+
+		expected := make([]string, 0)
+		for i := 0; i < 100; i++ {
+			key := fmt.Sprintf("user:%d", i)
+			expected = append(expected, key)
+			assert.Equal(t, "OK", client.val("SET", key, i))
+			assert.Equal(t, "OK", client.val("SET", fmt.Sprintf("order:%d", i), i))
+		}
+
+		keys := make([]string, 0)
+		cursor := "0"
+		for {
+			reply, err := client.do("SCAN", cursor, "MATCH", "user:*", "COUNT", 7)
+			assert.NoError(t, err)
+			page := reply.([]any)
+			for _, key := range page[1].([]any) {
+				keys = append(keys, key.(string))
+			}
+			if cursor = page[0].(string); cursor == "0" {
+				break
+			}
+		}
+
+		sort.Strings(expected)
+		sort.Strings(keys)
+		assert.Equal(t, expected, keys)
+
+		reply, err := client.do("SCAN", 0, "COUNT", 1000)
+		assert.NoError(t, err)
+		assert.Equal(t, "0", reply.([]any)[0])
+	}
+	t.Run("TestScan", TestScan)
+
+	TestErrors := func(t *testing.T) {
+		client, _, _ := newTestServer(t)
+
+		_, err := client.do("NOPE")
+		assert.EqualError(t, err, "ERR unknown command 'NOPE'")
+
+		_, err = client.do("GET")
+		assert.EqualError(t, err, "ERR wrong number of arguments for 'get' command")
+	}
+	t.Run("TestErrors", TestErrors)
+
+	TestInlineAndPipelined := func(t *testing.T) {
+		_, _, addr := newTestServer(t)
+
+		conn, err := net.Dial("tcp", addr)
+		assert.NoError(t, err)
+		defer conn.Close()
+
+		_, err = conn.Write([]byte("SET a 1\r\n*2\r\n$3\r\nGET\r\n$1\r\na\r\nQUIT\r\n"))
+		assert.NoError(t, err)
+
+		r := bufio.NewReader(conn)
+		for _, expected := range []string{"+OK\r\n", "$1\r\n", "1\r\n", "+OK\r\n"} {
+			line, err := r.ReadString('\n')
+			assert.NoError(t, err)
+			assert.Equal(t, expected, line)
+		}
+	}
+	t.Run("TestInlineAndPipelined", TestInlineAndPipelined)
+}
+
+func TestRESPReader(t *testing.T) {
+	TestBulk := func(t *testing.T) {
+		r := newRESPReader(strings.NewReader("*2\r\n$3\r\nGET\r\n$5\r\nhello\r\n"), 16)
+
+		args, err := r.readCommand()
+		assert.NoError(t, err)
+		assert.Equal(t, [][]byte{[]byte("GET"), []byte("hello")}, args)
+	}
+	t.Run("TestBulk", TestBulk)
+
+	TestBulkTooLong := func(t *testing.T) {
+		r := newRESPReader(strings.NewReader("*1\r\n$17\r\n"), 16)
+
+		_, err := r.readCommand()
+		assert.ErrorIs(t, err, errProtocol)
+		assert.EqualError(t, err, "protocol error: invalid bulk length")
+	}
+	t.Run("TestBulkTooLong", TestBulkTooLong)
+
+	TestBulkAllocatesWhatArrives := func(t *testing.T) {
+		// announces the maximum length, but only sends a few bytes
+		request := fmt.Sprintf("*%d\r\n$%d\r\nabc", maxArgs, defaultMaxBulkLen)
+
+		var before, after runtime.MemStats
+		runtime.ReadMemStats(&before)
+		_, err := newRESPReader(strings.NewReader(request), defaultMaxBulkLen).readCommand()
+		runtime.ReadMemStats(&after)
+
+		assert.ErrorIs(t, err, io.ErrUnexpectedEOF)
+		assert.Less(t, after.TotalAlloc-before.TotalAlloc, uint64(readerSize*4))
+	}
+	t.Run("TestBulkAllocatesWhatArrives", TestBulkAllocatesWhatArrives)
+}
+
+func TestMatchGlob(t *testing.T) {
+	tests := []struct {
+		pattern string
+		s       string
+		match   bool
+	}{
+		{"*", "", true},
+		{"user:*", "user:1", true},
+		{"user:*", "order:1", false},
+		{"h?llo", "hello", true},
+		{"h?llo", "hllo", false},
+		{"h*llo", "heeeello", true},
+		{"h[ae]llo", "hallo", true},
+		{"h[ae]llo", "hillo", false},
+		{"h[^e]llo", "hallo", true},
+		{"h[^e]llo", "hello", false},
+		{"h[a-b]llo", "hbllo", true},
+		{"h[a-b]llo", "hcllo", false},
+		{`h\*llo`, "h*llo", true},
+		{`h\*llo`, "hello", false},
+	}
+
+	for _, test := range tests {
+		assert.Equal(t, test.match, matchGlob(test.pattern, test.s), "%s %s", test.pattern, test.s)
+	}
+}
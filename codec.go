@@ -0,0 +1,83 @@
+package cache
+
+import (
+	"bytes"
+	"encoding/gob"
+	"encoding/json"
+)
+
+// Encodes and decodes keys to and from bytes.
+type KeyCodec[K any] interface {
+	EncodeKey(key K) ([]byte, error)
+	DecodeKey(data []byte) (K, error)
+}
+
+// Encodes and decodes values to and from bytes.
+type ValueCodec[V any] interface {
+	EncodeValue(value V) ([]byte, error)
+	DecodeValue(data []byte) (V, error)
+}
+
+// Encodes and decodes keys and values, used to persist the cache.
+type Codec[K any, V any] interface {
+	KeyCodec[K]
+	ValueCodec[V]
+}
+
+// Codec using 'encoding/gob', interface types inside keys or values need to be registered with 'gob.Register'.
+type GobCodec[K any, V any] struct{}
+
+func (GobCodec[K, V]) EncodeKey(key K) ([]byte, error) {
+	return gobEncode(key)
+}
+
+func (GobCodec[K, V]) DecodeKey(data []byte) (K, error) {
+	return gobDecode[K](data)
+}
+
+func (GobCodec[K, V]) EncodeValue(value V) ([]byte, error) {
+	return gobEncode(value)
+}
+
+func (GobCodec[K, V]) DecodeValue(data []byte) (V, error) {
+	return gobDecode[V](data)
+}
+
+// Codec using 'encoding/json'.
+type JSONCodec[K any, V any] struct{}
+
+func (JSONCodec[K, V]) EncodeKey(key K) ([]byte, error) {
+	return json.Marshal(key)
+}
+
+func (JSONCodec[K, V]) DecodeKey(data []byte) (K, error) {
+	return jsonDecode[K](data)
+}
+
+func (JSONCodec[K, V]) EncodeValue(value V) ([]byte, error) {
+	return json.Marshal(value)
+}
+
+func (JSONCodec[K, V]) DecodeValue(data []byte) (V, error) {
+	return jsonDecode[V](data)
+}
+
+func gobEncode[T any](value T) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(value); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func gobDecode[T any](data []byte) (T, error) {
+	var value T
+	err := gob.NewDecoder(bytes.NewReader(data)).Decode(&value)
+	return value, err
+}
+
+func jsonDecode[T any](data []byte) (T, error) {
+	var value T
+	err := json.Unmarshal(data, &value)
+	return value, err
+}
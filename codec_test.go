@@ -0,0 +1,40 @@
+package cache
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type codecTestValue struct {
+	Name  string
+	Count int
+}
+
+func TestCodecs(t *testing.T) {
+	codecs := map[string]Codec[string, codecTestValue]{
+		"gob":  GobCodec[string, codecTestValue]{},
+		"json": JSONCodec[string, codecTestValue]{},
+	}
+
+	for name, codec := range codecs {
+		t.Run(name, func(t *testing.T) {
+			data, err := codec.EncodeKey("key")
+			assert.NoError(t, err)
+
+			key, err := codec.DecodeKey(data)
+			assert.NoError(t, err)
+			assert.Equal(t, "key", key)
+
+			data, err = codec.EncodeValue(codecTestValue{Name: "name", Count: 2})
+			assert.NoError(t, err)
+
+			value, err := codec.DecodeValue(data)
+			assert.NoError(t, err)
+			assert.Equal(t, codecTestValue{Name: "name", Count: 2}, value)
+
+			_, err = codec.DecodeValue([]byte("invalid"))
+			assert.Error(t, err)
+		})
+	}
+}
@@ -0,0 +1,270 @@
+package cache
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Returned by 'Validate' (wrapped) when the config is invalid.
+var ErrInvalidConfig = errors.New("invalid config")
+
+// A time.Duration that is encoded as text (e.g. "10s") in JSON, YAML and environment variables.
+type Duration time.Duration
+
+func (d Duration) MarshalText() ([]byte, error) {
+	return []byte(time.Duration(d).String()), nil
+}
+
+func (d *Duration) UnmarshalText(text []byte) error {
+	duration, err := time.ParseDuration(string(text))
+	if err != nil {
+		return err
+	}
+	*d = Duration(duration)
+	return nil
+}
+
+// Declarative configuration of a cache, use 'NewCacheFromConfig' or 'NewLoadingCacheFromConfig' to create a cache.
+//
+// Snapshots and the journal are encoded with GobCodec, pass 'WithSnapshot' or 'WithJournal' as option to use another codec.
+// Unknown fields in JSON and unknown environment variables (see 'FromEnv') are rejected, so a setting is never dropped silently.
+type Config struct {
+	// See 'WithExpireAfterWrite', 0 means items do not expire.
+	ExpireAfterWrite Duration `json:"expireAfterWrite,omitempty" yaml:"expireAfterWrite,omitempty"`
+
+	// See 'WithCleanupInterval', defaults to 5 seconds when nil, 0 disables the background cleanup.
+	CleanupInterval *Duration `json:"cleanupInterval,omitempty" yaml:"cleanupInterval,omitempty"`
+
+	// See 'WithSnapshot', an empty path disables snapshots.
+	SnapshotPath     string   `json:"snapshotPath,omitempty" yaml:"snapshotPath,omitempty"`
+	SnapshotInterval Duration `json:"snapshotInterval,omitempty" yaml:"snapshotInterval,omitempty"`
+
+	// See 'WithJournal' and 'WithJournalCompactSize', an empty path disables the journal.
+	JournalPath        string `json:"journalPath,omitempty" yaml:"journalPath,omitempty"`
+	JournalCompactSize int64  `json:"journalCompactSize,omitempty" yaml:"journalCompactSize,omitempty"`
+
+	// See 'WithStore', one of "csmap", "syncmap" or "sharded". Empty uses the default store.
+	Store string `json:"store,omitempty" yaml:"store,omitempty"`
+
+	// See 'WithShardCount' and 'WithInitialCapacity', 0 uses the default. Not supported by the "syncmap" store.
+	ShardCount      int `json:"shardCount,omitempty" yaml:"shardCount,omitempty"`
+	InitialCapacity int `json:"initialCapacity,omitempty" yaml:"initialCapacity,omitempty"`
+
+	// Not supported, the cache does not evict items when it grows. Anything but 0 is invalid,
+	// use 'NewByteCache' for a cache with a memory limit.
+	MaximumSize int `json:"maximumSize,omitempty" yaml:"maximumSize,omitempty"`
+}
+
+// Decodes the config from JSON, unknown fields are invalid.
+func (c *Config) UnmarshalJSON(data []byte) error {
+	// without the methods of Config, so decoding does not recurse
+	type config Config
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode((*config)(c)); err != nil {
+		if strings.HasPrefix(err.Error(), "json: unknown field") {
+			return fmt.Errorf("%w: %s", ErrInvalidConfig, strings.TrimPrefix(err.Error(), "json: "))
+		}
+		return err
+	}
+	return nil
+}
+
+// Returns an error for every invalid field or combination of fields, the error wraps ErrInvalidConfig.
+func (c Config) Validate() error {
+	var errs []error
+	invalid := func(format string, args ...any) {
+		errs = append(errs, fmt.Errorf("%w: "+format, append([]any{ErrInvalidConfig}, args...)...))
+	}
+
+	if c.ExpireAfterWrite < 0 {
+		invalid("expireAfterWrite must not be negative, got %s", time.Duration(c.ExpireAfterWrite))
+	}
+	if c.CleanupInterval != nil && *c.CleanupInterval < 0 {
+		invalid("cleanupInterval must not be negative, got %s", time.Duration(*c.CleanupInterval))
+	}
+	if c.SnapshotInterval < 0 {
+		invalid("snapshotInterval must not be negative, got %s", time.Duration(c.SnapshotInterval))
+	}
+	if c.SnapshotInterval > 0 && c.SnapshotPath == "" {
+		invalid("snapshotInterval requires a snapshotPath")
+	}
+	if c.JournalCompactSize < 0 {
+		invalid("journalCompactSize must not be negative, got %d", c.JournalCompactSize)
+	}
+	if c.JournalCompactSize > 0 && c.JournalPath == "" {
+		invalid("journalCompactSize requires a journalPath")
+	}
+	if c.SnapshotPath != "" && c.JournalPath != "" {
+		// both restore the cache at startup, the journal already writes snapshots when it compacts
+		invalid("snapshotPath and journalPath can't be used together, the journal compacts into %s.snapshot", c.JournalPath)
+	}
+	if c.Store != "" {
+		if _, err := parseStoreType(c.Store); err != nil {
+			invalid("store must be csmap, syncmap or sharded, got %q", c.Store)
+		}
+	}
+	if c.ShardCount < 0 {
+		invalid("shardCount must not be negative, got %d", c.ShardCount)
+	}
+	if c.InitialCapacity < 0 {
+		invalid("initialCapacity must not be negative, got %d", c.InitialCapacity)
+	}
+	if c.Store == StoreSyncMap.String() && c.ShardCount > 0 {
+		invalid("shardCount is not supported by the syncmap store")
+	}
+	if c.Store == StoreSyncMap.String() && c.InitialCapacity > 0 {
+		invalid("initialCapacity is not supported by the syncmap store")
+	}
+	if c.MaximumSize != 0 {
+		invalid("maximumSize is not supported, the cache does not evict items when it grows (see NewByteCache for a memory limit)")
+	}
+
+	return errors.Join(errs...)
+}
+
+// Overrides fields with environment variables that start with the prefix, e.g. with prefix "USERS":
+//
+//	USERS_EXPIRE_AFTER_WRITE=10m
+//	USERS_CLEANUP_INTERVAL=30s
+//	USERS_SNAPSHOT_PATH=/var/lib/users.snapshot
+//	USERS_SNAPSHOT_INTERVAL=1m
+//	USERS_JOURNAL_PATH=/var/lib/users.journal
+//	USERS_JOURNAL_COMPACT_SIZE=67108864
+//	USERS_STORE=sharded
+//	USERS_SHARD_COUNT=64
+//	USERS_INITIAL_CAPACITY=100000
+//	USERS_MAXIMUM_SIZE=0
+//
+// Variables that are not set keep the current value, other variables that start with the prefix are invalid.
+func (c *Config) FromEnv(prefix string) error {
+	if prefix != "" && !strings.HasSuffix(prefix, "_") {
+		prefix += "_"
+	}
+
+	var errs []error
+	known := make(map[string]bool)
+	lookup := func(name string, parse func(value string) error) {
+		known[prefix+name] = true
+		if value, found := os.LookupEnv(prefix + name); found {
+			if err := parse(value); err != nil {
+				errs = append(errs, fmt.Errorf("%w: %s%s: %v", ErrInvalidConfig, prefix, name, err))
+			}
+		}
+	}
+
+	lookup("EXPIRE_AFTER_WRITE", func(value string) error {
+		return c.ExpireAfterWrite.UnmarshalText([]byte(value))
+	})
+	lookup("CLEANUP_INTERVAL", func(value string) error {
+		c.CleanupInterval = new(Duration)
+		return c.CleanupInterval.UnmarshalText([]byte(value))
+	})
+	lookup("SNAPSHOT_PATH", func(value string) error {
+		c.SnapshotPath = value
+		return nil
+	})
+	lookup("SNAPSHOT_INTERVAL", func(value string) error {
+		return c.SnapshotInterval.UnmarshalText([]byte(value))
+	})
+	lookup("JOURNAL_PATH", func(value string) error {
+		c.JournalPath = value
+		return nil
+	})
+	lookup("JOURNAL_COMPACT_SIZE", func(value string) (err error) {
+		c.JournalCompactSize, err = strconv.ParseInt(value, 10, 64)
+		return err
+	})
+	lookup("STORE", func(value string) error {
+		c.Store = value
+		return nil
+	})
+	lookup("SHARD_COUNT", func(value string) (err error) {
+		c.ShardCount, err = strconv.Atoi(value)
+		return err
+	})
+	lookup("INITIAL_CAPACITY", func(value string) (err error) {
+		c.InitialCapacity, err = strconv.Atoi(value)
+		return err
+	})
+	lookup("MAXIMUM_SIZE", func(value string) (err error) {
+		c.MaximumSize, err = strconv.Atoi(value)
+		return err
+	})
+
+	// without a prefix every variable of the environment would be unknown
+	if prefix != "" {
+		for _, env := range os.Environ() {
+			name, _, _ := strings.Cut(env, "=")
+			if strings.HasPrefix(name, prefix) && !known[name] {
+				errs = append(errs, fmt.Errorf("%w: unknown variable %s", ErrInvalidConfig, name))
+			}
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// Create a new cache from the config, options are applied after the config so they take precedence.
+func NewCacheFromConfig[K comparable, V any](config Config, options ...Option[K, V]) (Cache[K, V], error) {
+	opts, err := configOptions(config, options)
+	if err != nil {
+		return nil, err
+	}
+	return NewCache(opts...), nil
+}
+
+// Create a new loading cache from the config, see 'NewCacheFromConfig'.
+func NewLoadingCacheFromConfig[K comparable, V any](
+	config Config,
+	loaderFunc LoaderFunc[K, V],
+	options ...Option[K, V],
+) (LoadingCache[K, V], error) {
+	opts, err := configOptions(config, options)
+	if err != nil {
+		return nil, err
+	}
+	return NewLoadingCache(loaderFunc, opts...), nil
+}
+
+// Translates the config into options, followed by the given options.
+func configOptions[K comparable, V any](config Config, options []Option[K, V]) ([]Option[K, V], error) {
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+
+	opts := []Option[K, V]{
+		WithExpireAfterWrite[K, V](time.Duration(config.ExpireAfterWrite)),
+	}
+	if config.CleanupInterval != nil {
+		opts = append(opts, WithCleanupInterval[K, V](time.Duration(*config.CleanupInterval)))
+	}
+	if config.SnapshotPath != "" {
+		opts = append(opts, WithSnapshot(config.SnapshotPath, GobCodec[K, V]{}, time.Duration(config.SnapshotInterval)))
+	}
+	if config.JournalPath != "" {
+		opts = append(opts, WithJournal(config.JournalPath, GobCodec[K, V]{}))
+	}
+	if config.JournalCompactSize > 0 {
+		opts = append(opts, WithJournalCompactSize[K, V](config.JournalCompactSize))
+	}
+	if config.Store != "" {
+		storeType, _ := parseStoreType(config.Store)
+		opts = append(opts, WithStore[K, V](storeType))
+	}
+	if config.ShardCount > 0 {
+		opts = append(opts, WithShardCount[K, V](config.ShardCount))
+	}
+	if config.InitialCapacity > 0 {
+		opts = append(opts, WithInitialCapacity[K, V](config.InitialCapacity))
+	}
+
+	return append(opts, options...), nil
+}
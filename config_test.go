@@ -0,0 +1,155 @@
+package cache
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConfig(t *testing.T) {
+	TestJSON := func(t *testing.T) {
+		var config Config
+		err := json.Unmarshal([]byte(`{
+			"expireAfterWrite": "10m",
+			"cleanupInterval": "0s",
+			"snapshotPath": "users.snapshot",
+			"snapshotInterval": "1m30s"
+		}`), &config)
+		assert.NoError(t, err)
+
+		assert.Equal(t, Duration(time.Minute*10), config.ExpireAfterWrite)
+		assert.Equal(t, Duration(0), *config.CleanupInterval)
+		assert.Equal(t, "users.snapshot", config.SnapshotPath)
+		assert.Equal(t, Duration(time.Second*90), config.SnapshotInterval)
+
+		data, err := json.Marshal(Config{ExpireAfterWrite: Duration(time.Second)})
+		assert.NoError(t, err)
+		assert.JSONEq(t, `{"expireAfterWrite":"1s"}`, string(data))
+
+		assert.Error(t, json.Unmarshal([]byte(`{"expireAfterWrite": "soon"}`), &config))
+
+		err = json.Unmarshal([]byte(`{"store": "sharded", "shardCount": 64, "initialCapacity": 1000}`), &config)
+		assert.NoError(t, err)
+		assert.Equal(t, "sharded", config.Store)
+		assert.Equal(t, 64, config.ShardCount)
+		assert.Equal(t, 1000, config.InitialCapacity)
+
+		err = json.Unmarshal([]byte(`{"maxSize": 1000}`), &config)
+		assert.ErrorIs(t, err, ErrInvalidConfig)
+		assert.EqualError(t, err, `invalid config: unknown field "maxSize"`)
+	}
+	t.Run("TestJSON", TestJSON)
+
+	TestValidate := func(t *testing.T) {
+		negative := Duration(-time.Second)
+
+		assert.NoError(t, Config{}.Validate())
+		assert.NoError(t, Config{ExpireAfterWrite: Duration(time.Second), SnapshotPath: "a"}.Validate())
+
+		invalid := []Config{
+			{ExpireAfterWrite: negative},
+			{CleanupInterval: &negative},
+			{SnapshotPath: "a", SnapshotInterval: negative},
+			{SnapshotInterval: Duration(time.Second)},
+			{JournalPath: "a", JournalCompactSize: -1},
+			{JournalCompactSize: 1024},
+			{SnapshotPath: "a", JournalPath: "b"},
+			{Store: "btree"},
+			{ShardCount: -1},
+			{InitialCapacity: -1},
+			{Store: "syncmap", ShardCount: 64},
+			{Store: "syncmap", InitialCapacity: 1000},
+			{MaximumSize: 1000},
+		}
+		for _, config := range invalid {
+			assert.ErrorIs(t, config.Validate(), ErrInvalidConfig, "%+v", config)
+		}
+
+		err := Config{ExpireAfterWrite: negative, JournalCompactSize: 1024}.Validate()
+		assert.ErrorContains(t, err, "expireAfterWrite must not be negative, got -1s")
+		assert.ErrorContains(t, err, "journalCompactSize requires a journalPath")
+
+		assert.NoError(t, Config{Store: "sharded", ShardCount: 64, InitialCapacity: 1000}.Validate())
+		assert.EqualError(t, Config{MaximumSize: 1000}.Validate(),
+			"invalid config: maximumSize is not supported, the cache does not evict items when it grows (see NewByteCache for a memory limit)")
+	}
+	t.Run("TestValidate", TestValidate)
+
+	TestFromEnv := func(t *testing.T) {
+		t.Setenv("USERS_EXPIRE_AFTER_WRITE", "10m")
+		t.Setenv("USERS_CLEANUP_INTERVAL", "30s")
+		t.Setenv("USERS_JOURNAL_PATH", "users.journal")
+		t.Setenv("USERS_JOURNAL_COMPACT_SIZE", "1024")
+
+		config := Config{SnapshotInterval: Duration(time.Minute)}
+		assert.NoError(t, config.FromEnv("USERS"))
+
+		cleanupInterval := Duration(time.Second * 30)
+		assert.Equal(t, Config{
+			ExpireAfterWrite:   Duration(time.Minute * 10),
+			CleanupInterval:    &cleanupInterval,
+			SnapshotInterval:   Duration(time.Minute),
+			JournalPath:        "users.journal",
+			JournalCompactSize: 1024,
+		}, config)
+
+		t.Setenv("USERS_JOURNAL_COMPACT_SIZE", "large")
+		err := config.FromEnv("USERS_")
+		assert.ErrorIs(t, err, ErrInvalidConfig)
+		assert.ErrorContains(t, err, "USERS_JOURNAL_COMPACT_SIZE")
+
+		t.Setenv("USERS_JOURNAL_COMPACT_SIZE", "1024")
+		t.Setenv("USERS_MAX_SIZE", "1000")
+		err = config.FromEnv("USERS")
+		assert.ErrorIs(t, err, ErrInvalidConfig)
+		assert.EqualError(t, err, "invalid config: unknown variable USERS_MAX_SIZE")
+	}
+	t.Run("TestFromEnv", TestFromEnv)
+
+	TestNewCacheFromConfig := func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "cache.snapshot")
+		config := Config{ExpireAfterWrite: Duration(time.Minute), SnapshotPath: path}
+
+		c, err := NewCacheFromConfig[int, string](config)
+		assert.NoError(t, err)
+		c.Put(1, "Hello World")
+		ttl, _ := c.(TTLCache[int, string]).TTL(1)
+		assert.Equal(t, time.Minute, ttl.Round(time.Minute))
+		c.Close()
+
+		restored, err := NewLoadingCacheFromConfig(config, NoopLoaderFunc[int, string])
+		assert.NoError(t, err)
+		defer restored.Close()
+		value, _ := restored.Get(1)
+		assert.Equal(t, "Hello World", value)
+
+		_, err = NewCacheFromConfig[int, string](Config{ExpireAfterWrite: -1})
+		assert.ErrorIs(t, err, ErrInvalidConfig)
+	}
+	t.Run("TestNewCacheFromConfig", TestNewCacheFromConfig)
+
+	TestStoreFromConfig := func(t *testing.T) {
+		c, err := NewCacheFromConfig[int, int](Config{Store: "sharded", ShardCount: 4, InitialCapacity: 100})
+		assert.NoError(t, err)
+		defer c.Close()
+
+		store, ok := c.(*cacheHandle[int, int]).data.(*shardedStore[int, entry[int, int]])
+		assert.True(t, ok)
+		assert.Len(t, store.shards, 4)
+	}
+	t.Run("TestStoreFromConfig", TestStoreFromConfig)
+
+	TestOptionsTakePrecedence := func(t *testing.T) {
+		c, err := NewCacheFromConfig(Config{ExpireAfterWrite: Duration(time.Minute)}, WithExpireAfterWrite[int, int](0))
+		assert.NoError(t, err)
+		defer c.Close()
+
+		c.Put(1, 1)
+		ttl, _ := c.(TTLCache[int, int]).TTL(1)
+		assert.Zero(t, ttl)
+	}
+	t.Run("TestOptionsTakePrecedence", TestOptionsTakePrecedence)
+}
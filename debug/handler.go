@@ -0,0 +1,295 @@
+// Package debug provides an http.Handler to inspect and purge caches while debugging.
+//
+// Mount the handler under a prefix with http.StripPrefix:
+//
+//	h := debug.NewHandler()
+//	debug.Register(h, "users", users)
+//	mux.Handle("/debug/cache/", http.StripPrefix("/debug/cache", h))
+//
+// Routes (all responses are JSON):
+//
+//	GET  /                          lists the registered caches with their count
+//	GET  /caches/{name}?sample=20   shows the count and sample keys with their remaining TTL
+//	GET  /caches/{name}/key?key=k   shows a single item
+//	POST /caches/{name}/delete?key=k  deletes a single item
+//	POST /caches/{name}/clear       clears the cache
+//
+// Caches of a 'cache.Manager' can be listed and cleared with 'RegisterManager'.
+package debug
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/larscom/go-cache"
+)
+
+const defaultSampleSize = 20
+
+// Returned when the key can't be parsed into the key type of the cache.
+var ErrInvalidKey = errors.New("invalid key")
+
+var errKeysNotSupported = errors.New("keys are not supported for caches of a manager, use Register")
+
+type Option func(h *Handler)
+
+// Disables 'Delete' and 'Clear', POST requests are rejected with 403 (Forbidden).
+func WithReadOnly() Option {
+	return func(h *Handler) {
+		h.readOnly = true
+	}
+}
+
+// The default amount of sample keys, defaults to 20.
+func WithSampleSize(sampleSize int) Option {
+	return func(h *Handler) {
+		h.sampleSize = sampleSize
+	}
+}
+
+// Serves the debug routes of the registered caches.
+type Handler struct {
+	mu       sync.RWMutex
+	caches   map[string]inspector
+	managers []*cache.Manager
+
+	readOnly   bool
+	sampleSize int
+	mux        *http.ServeMux
+}
+
+// Create a new handler, register caches with 'Register'.
+func NewHandler(opts ...Option) *Handler {
+	h := &Handler{
+		caches:     make(map[string]inspector),
+		sampleSize: defaultSampleSize,
+		mux:        http.NewServeMux(),
+	}
+
+	for _, opt := range opts {
+		opt(h)
+	}
+
+	h.mux.HandleFunc("GET /{$}", h.list)
+	h.mux.HandleFunc("GET /caches/{name}", h.show)
+	h.mux.HandleFunc("GET /caches/{name}/key", h.lookup)
+	h.mux.HandleFunc("POST /caches/{name}/delete", h.delete)
+	h.mux.HandleFunc("POST /caches/{name}/clear", h.clear)
+
+	return h
+}
+
+// Removes the cache with the name.
+func (h *Handler) Unregister(name string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	delete(h.caches, name)
+}
+
+// Lists the caches of the manager, including caches that are created later.
+//
+// The key type of these caches is unknown, so they only show their count and can be cleared.
+// Caches that are registered with 'Register' under the same name take precedence.
+func (h *Handler) RegisterManager(m *cache.Manager) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.managers = append(h.managers, m)
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.mux.ServeHTTP(w, r)
+}
+
+func (h *Handler) register(name string, c inspector) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.caches[name] = c
+}
+
+func (h *Handler) get(w http.ResponseWriter, r *http.Request) (inspector, bool) {
+	c, found := h.lookupCache(r.PathValue("name"))
+	if !found {
+		writeError(w, http.StatusNotFound, "cache not found")
+	}
+	return c, found
+}
+
+func (h *Handler) lookupCache(name string) (inspector, bool) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	if c, found := h.caches[name]; found {
+		return c, true
+	}
+	for _, m := range h.managers {
+		if c, found := m.Get(name); found {
+			return &anyInspector{c}, true
+		}
+	}
+	return nil, false
+}
+
+// Returns the typed caches and the caches of the managers by name.
+func (h *Handler) allCaches() map[string]inspector {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	caches := make(map[string]inspector, len(h.caches))
+	for _, m := range h.managers {
+		m.ForEach(func(name string, c cache.AnyCache) {
+			if _, found := caches[name]; !found {
+				caches[name] = &anyInspector{c}
+			}
+		})
+	}
+	for name, c := range h.caches {
+		caches[name] = c
+	}
+	return caches
+}
+
+type cacheSummary struct {
+	Name  string `json:"name"`
+	Count int    `json:"count"`
+}
+
+func (h *Handler) list(w http.ResponseWriter, _ *http.Request) {
+	all := h.allCaches()
+	caches := make([]cacheSummary, 0, len(all))
+	for name, c := range all {
+		caches = append(caches, cacheSummary{Name: name, Count: c.count()})
+	}
+
+	sort.Slice(caches, func(i, j int) bool { return caches[i].Name < caches[j].Name })
+
+	writeJSON(w, http.StatusOK, map[string]any{"caches": caches, "readOnly": h.readOnly})
+}
+
+func (h *Handler) show(w http.ResponseWriter, r *http.Request) {
+	c, found := h.get(w, r)
+	if !found {
+		return
+	}
+
+	sampleSize := h.sampleSize
+	if sample := r.URL.Query().Get("sample"); sample != "" {
+		n, err := strconv.Atoi(sample)
+		if err != nil || n < 0 {
+			writeError(w, http.StatusBadRequest, "invalid sample size")
+			return
+		}
+		sampleSize = n
+	}
+
+	body := map[string]any{
+		"name":  r.PathValue("name"),
+		"count": c.count(),
+	}
+	if sample, err := c.sample(sampleSize); err == nil {
+		body["sample"] = sample
+	}
+
+	writeJSON(w, http.StatusOK, body)
+}
+
+func (h *Handler) lookup(w http.ResponseWriter, r *http.Request) {
+	c, found := h.get(w, r)
+	if !found {
+		return
+	}
+
+	item, found, err := c.lookup(r.URL.Query().Get("key"))
+	if err != nil {
+		writeKeyError(w, err)
+		return
+	}
+	if !found {
+		writeError(w, http.StatusNotFound, "key not found")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, item)
+}
+
+func (h *Handler) delete(w http.ResponseWriter, r *http.Request) {
+	if !h.writable(w) {
+		return
+	}
+	c, found := h.get(w, r)
+	if !found {
+		return
+	}
+
+	deleted, err := c.delete(r.FormValue("key"))
+	if err != nil {
+		writeKeyError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]any{"deleted": deleted})
+}
+
+func (h *Handler) clear(w http.ResponseWriter, r *http.Request) {
+	if !h.writable(w) {
+		return
+	}
+	c, found := h.get(w, r)
+	if !found {
+		return
+	}
+
+	cleared := c.count()
+	c.clear()
+
+	writeJSON(w, http.StatusOK, map[string]any{"cleared": cleared})
+}
+
+func (h *Handler) writable(w http.ResponseWriter) bool {
+	if h.readOnly {
+		writeError(w, http.StatusForbidden, "read-only")
+	}
+	return !h.readOnly
+}
+
+// A rendered item, TTL is omitted when the item does not expire.
+type item struct {
+	Key   string `json:"key"`
+	Value any    `json:"value,omitempty"`
+	TTL   string `json:"ttl,omitempty"`
+}
+
+func renderTTL(ttl time.Duration) string {
+	if ttl == 0 {
+		return ""
+	}
+	return ttl.Round(time.Millisecond).String()
+}
+
+func writeJSON(w http.ResponseWriter, status int, body any) {
+	data, err := json.Marshal(body)
+	if err != nil {
+		status = http.StatusInternalServerError
+		data, _ = json.Marshal(map[string]string{"error": err.Error()})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(append(data, '\n'))
+}
+
+func writeKeyError(w http.ResponseWriter, err error) {
+	if errors.Is(err, errKeysNotSupported) {
+		writeError(w, http.StatusNotImplemented, err.Error())
+		return
+	}
+	writeError(w, http.StatusBadRequest, err.Error())
+}
+
+func writeError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, map[string]string{"error": message})
+}
@@ -0,0 +1,223 @@
+package debug
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/larscom/go-cache"
+	"github.com/larscom/go-cache/cachetest"
+	"github.com/stretchr/testify/assert"
+)
+
+type user struct {
+	Name     string `json:"name"`
+	Password string `json:"password"`
+}
+
+func request(t *testing.T, h http.Handler, method, target string) (int, map[string]any) {
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(method, target, nil))
+
+	var body map[string]any
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+	return rec.Code, body
+}
+
+func newTestHandler(t *testing.T, opts ...Option) (*Handler, cache.Cache[int, user], cache.Cache[string, string]) {
+	clock := cachetest.NewFakeClock(time.Now())
+
+	users := cache.NewCache(cache.WithClock[int, user](clock)).(cache.TTLCache[int, user])
+	t.Cleanup(users.Close)
+	users.PutWithTTL(1, user{Name: "alice", Password: "secret"}, time.Minute)
+	users.Put(2, user{Name: "bob", Password: "secret"})
+
+	sessions := cache.NewCache[string, string]()
+	t.Cleanup(sessions.Close)
+	sessions.Put("a/b", "session")
+
+	h := NewHandler(opts...)
+	Register(h, "users", users, WithValueRenderer[int](func(u user) any {
+		return map[string]string{"name": u.Name}
+	}))
+	Register(h, "sessions", sessions)
+
+	return h, users, sessions
+}
+
+func TestHandler(t *testing.T) {
+	TestList := func(t *testing.T) {
+		h, _, _ := newTestHandler(t)
+
+		status, body := request(t, h, http.MethodGet, "/")
+		assert.Equal(t, http.StatusOK, status)
+		assert.Equal(t, []any{
+			map[string]any{"name": "sessions", "count": float64(1)},
+			map[string]any{"name": "users", "count": float64(2)},
+		}, body["caches"])
+	}
+	t.Run("TestList", TestList)
+
+	TestShow := func(t *testing.T) {
+		h, _, _ := newTestHandler(t)
+
+		status, body := request(t, h, http.MethodGet, "/caches/users")
+		assert.Equal(t, http.StatusOK, status)
+		assert.Equal(t, float64(2), body["count"])
+		assert.ElementsMatch(t, []any{
+			map[string]any{"key": "1", "ttl": "1m0s"},
+			map[string]any{"key": "2"},
+		}, body["sample"])
+
+		_, body = request(t, h, http.MethodGet, "/caches/users?sample=1")
+		assert.Len(t, body["sample"], 1)
+
+		status, _ = request(t, h, http.MethodGet, "/caches/users?sample=a")
+		assert.Equal(t, http.StatusBadRequest, status)
+
+		status, _ = request(t, h, http.MethodGet, "/caches/unknown")
+		assert.Equal(t, http.StatusNotFound, status)
+	}
+	t.Run("TestShow", TestShow)
+
+	TestLookup := func(t *testing.T) {
+		h, _, _ := newTestHandler(t)
+
+		status, body := request(t, h, http.MethodGet, "/caches/users/key?key=1")
+		assert.Equal(t, http.StatusOK, status)
+		assert.Equal(t, map[string]any{
+			"key":   "1",
+			"value": map[string]any{"name": "alice"},
+			"ttl":   "1m0s",
+		}, body)
+
+		status, body = request(t, h, http.MethodGet, "/caches/sessions/key?key=a%2Fb")
+		assert.Equal(t, http.StatusOK, status)
+		assert.Equal(t, "session", body["value"])
+
+		status, _ = request(t, h, http.MethodGet, "/caches/users/key?key=3")
+		assert.Equal(t, http.StatusNotFound, status)
+
+		status, body = request(t, h, http.MethodGet, "/caches/users/key?key=a")
+		assert.Equal(t, http.StatusBadRequest, status)
+		assert.Contains(t, body["error"], ErrInvalidKey.Error())
+	}
+	t.Run("TestLookup", TestLookup)
+
+	TestDeleteAndClear := func(t *testing.T) {
+		h, users, sessions := newTestHandler(t)
+
+		status, body := request(t, h, http.MethodPost, "/caches/users/delete?key=1")
+		assert.Equal(t, http.StatusOK, status)
+		assert.Equal(t, true, body["deleted"])
+		assert.False(t, users.Has(1))
+
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/caches/users/delete", strings.NewReader("key=2"))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		h.ServeHTTP(rec, req)
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.False(t, users.Has(2))
+
+		status, body = request(t, h, http.MethodPost, "/caches/sessions/clear")
+		assert.Equal(t, http.StatusOK, status)
+		assert.Equal(t, float64(1), body["cleared"])
+		assert.True(t, sessions.IsEmpty())
+
+		rec = httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/caches/sessions/clear", nil))
+		assert.Equal(t, http.StatusMethodNotAllowed, rec.Code)
+	}
+	t.Run("TestDeleteAndClear", TestDeleteAndClear)
+
+	TestReadOnly := func(t *testing.T) {
+		h, users, _ := newTestHandler(t, WithReadOnly())
+
+		status, _ := request(t, h, http.MethodPost, "/caches/users/delete?key=1")
+		assert.Equal(t, http.StatusForbidden, status)
+		status, _ = request(t, h, http.MethodPost, "/caches/users/clear")
+		assert.Equal(t, http.StatusForbidden, status)
+		assert.Equal(t, 2, users.Count())
+	}
+	t.Run("TestReadOnly", TestReadOnly)
+
+	TestUnregister := func(t *testing.T) {
+		h, _, _ := newTestHandler(t)
+		h.Unregister("users")
+
+		_, body := request(t, h, http.MethodGet, "/")
+		assert.Len(t, body["caches"], 1)
+	}
+	t.Run("TestUnregister", TestUnregister)
+
+	TestStripPrefix := func(t *testing.T) {
+		h, _, _ := newTestHandler(t)
+		mux := http.NewServeMux()
+		mux.Handle("/debug/cache/", http.StripPrefix("/debug/cache", h))
+
+		status, body := request(t, mux, http.MethodGet, "/debug/cache/")
+		assert.Equal(t, http.StatusOK, status)
+		assert.Len(t, body["caches"], 2)
+	}
+	t.Run("TestStripPrefix", TestStripPrefix)
+}
+
+func TestRegisterManager(t *testing.T) {
+	m := cache.NewManager()
+	defer m.Close()
+
+	users, _ := cache.NewManagedCache[int, user](m, "users")
+	users.Put(1, user{Name: "alice"})
+	products, _ := cache.NewManagedCache[string, int](m, "products")
+	products.Put("a", 1)
+	products.Put("b", 2)
+
+	h := NewHandler()
+	h.RegisterManager(m)
+	Register(h, "users", users)
+
+	// created after registering the manager
+	orders, _ := cache.NewManagedCache[int, int](m, "orders")
+	orders.Put(1, 1)
+
+	_, body := request(t, h, http.MethodGet, "/")
+	assert.Equal(t, []any{
+		map[string]any{"name": "orders", "count": float64(1)},
+		map[string]any{"name": "products", "count": float64(2)},
+		map[string]any{"name": "users", "count": float64(1)},
+	}, body["caches"])
+
+	status, body := request(t, h, http.MethodGet, "/caches/products")
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, float64(2), body["count"])
+	assert.NotContains(t, body, "sample")
+
+	status, _ = request(t, h, http.MethodGet, "/caches/products/key?key=a")
+	assert.Equal(t, http.StatusNotImplemented, status)
+
+	status, _ = request(t, h, http.MethodGet, "/caches/users/key?key=1")
+	assert.Equal(t, http.StatusOK, status)
+
+	status, _ = request(t, h, http.MethodPost, "/caches/products/clear")
+	assert.Equal(t, http.StatusOK, status)
+	assert.True(t, products.IsEmpty())
+}
+
+func TestParseKey(t *testing.T) {
+	s, err := parseKey[string]("a b")
+	assert.NoError(t, err)
+	assert.Equal(t, "a b", s)
+
+	n, err := parseKey[int64]("-5")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(-5), n)
+
+	_, err = parseKey[uint32]("-5")
+	assert.ErrorIs(t, err, ErrInvalidKey)
+
+	_, err = parseKey[struct{ ID int }]("1")
+	assert.ErrorIs(t, err, ErrInvalidKey)
+}
@@ -0,0 +1,183 @@
+package debug
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/larscom/go-cache"
+)
+
+type RegisterOption[K comparable, V any] func(i *cacheInspector[K, V])
+
+// Parses the key of the 'key' query parameter, defaults to parsing strings, booleans and numbers.
+func WithKeyParser[K comparable, V any](keyParser func(key string) (K, error)) RegisterOption[K, V] {
+	return func(i *cacheInspector[K, V]) {
+		i.parseKey = keyParser
+	}
+}
+
+// Renders a value into something that can be encoded to JSON, defaults to the value itself.
+//
+// Use this to hide sensitive fields or to render values that can't be encoded to JSON.
+func WithValueRenderer[K comparable, V any](valueRenderer func(value V) any) RegisterOption[K, V] {
+	return func(i *cacheInspector[K, V]) {
+		i.renderValue = valueRenderer
+	}
+}
+
+// Registers the cache under the name, a cache with the same name gets replaced.
+//
+// Showing sample keys loops over the whole cache, so it's relatively expensive for large caches.
+func Register[K comparable, V any](h *Handler, name string, c cache.Cache[K, V], opts ...RegisterOption[K, V]) {
+	i := &cacheInspector[K, V]{
+		cache:       c,
+		parseKey:    parseKey[K],
+		renderValue: func(value V) any { return value },
+	}
+
+	for _, opt := range opts {
+		opt(i)
+	}
+
+	h.register(name, i)
+}
+
+// The type erased view on a cache.
+type inspector interface {
+	count() int
+	sample(n int) ([]item, error)
+	lookup(key string) (item, bool, error)
+	delete(key string) (bool, error)
+	clear()
+}
+
+type cacheInspector[K comparable, V any] struct {
+	cache       cache.Cache[K, V]
+	parseKey    func(key string) (K, error)
+	renderValue func(value V) any
+}
+
+func (i *cacheInspector[K, V]) count() int {
+	return i.cache.Count()
+}
+
+func (i *cacheInspector[K, V]) sample(n int) ([]item, error) {
+	keys := make([]K, 0, n)
+	i.cache.ForEach(func(key K, _ V) {
+		if len(keys) < n {
+			keys = append(keys, key)
+		}
+	})
+
+	items := make([]item, 0, len(keys))
+	for _, key := range keys {
+		if ttl, found := i.ttl(key); found {
+			items = append(items, item{Key: fmt.Sprint(key), TTL: renderTTL(ttl)})
+		}
+	}
+	return items, nil
+}
+
+// Returns the 'TTL' of the item, the 'TTL' is 0 for caches that don't implement cache.TTLCache.
+func (i *cacheInspector[K, V]) ttl(key K) (time.Duration, bool) {
+	if c, ok := i.cache.(cache.TTLCache[K, V]); ok {
+		return c.TTL(key)
+	}
+	return 0, i.cache.Has(key)
+}
+
+func (i *cacheInspector[K, V]) lookup(rawKey string) (item, bool, error) {
+	key, err := i.parseKey(rawKey)
+	if err != nil {
+		return item{}, false, err
+	}
+
+	value, found := i.cache.Get(key)
+	if !found {
+		return item{}, false, nil
+	}
+	ttl, _ := i.ttl(key)
+
+	return item{Key: fmt.Sprint(key), Value: i.renderValue(value), TTL: renderTTL(ttl)}, true, nil
+}
+
+func (i *cacheInspector[K, V]) delete(rawKey string) (bool, error) {
+	key, err := i.parseKey(rawKey)
+	if err != nil {
+		return false, err
+	}
+
+	found := i.cache.Has(key)
+	i.cache.Delete(key)
+	return found, nil
+}
+
+func (i *cacheInspector[K, V]) clear() {
+	i.cache.Clear()
+}
+
+// Parses strings, booleans and numbers, other key types need 'WithKeyParser'.
+func parseKey[K comparable](raw string) (K, error) {
+	var key K
+
+	var err error
+	switch k := any(&key).(type) {
+	case *string:
+		*k = raw
+	case *bool:
+		*k, err = strconv.ParseBool(raw)
+	case *int:
+		*k, err = strconv.Atoi(raw)
+	case *int64:
+		*k, err = strconv.ParseInt(raw, 10, 64)
+	case *int32:
+		var n int64
+		n, err = strconv.ParseInt(raw, 10, 32)
+		*k = int32(n)
+	case *uint:
+		var n uint64
+		n, err = strconv.ParseUint(raw, 10, 0)
+		*k = uint(n)
+	case *uint64:
+		*k, err = strconv.ParseUint(raw, 10, 64)
+	case *uint32:
+		var n uint64
+		n, err = strconv.ParseUint(raw, 10, 32)
+		*k = uint32(n)
+	case *float64:
+		*k, err = strconv.ParseFloat(raw, 64)
+	default:
+		return key, fmt.Errorf("%w: no key parser for %T, use WithKeyParser", ErrInvalidKey, key)
+	}
+
+	if err != nil {
+		return key, fmt.Errorf("%w: %v", ErrInvalidKey, err)
+	}
+	return key, nil
+}
+
+// A cache of a manager, only the functions that don't depend on the key type are available.
+type anyInspector struct {
+	cache cache.AnyCache
+}
+
+func (i *anyInspector) count() int {
+	return i.cache.Count()
+}
+
+func (i *anyInspector) sample(int) ([]item, error) {
+	return nil, errKeysNotSupported
+}
+
+func (i *anyInspector) lookup(string) (item, bool, error) {
+	return item{}, false, errKeysNotSupported
+}
+
+func (i *anyInspector) delete(string) (bool, error) {
+	return false, errKeysNotSupported
+}
+
+func (i *anyInspector) clear() {
+	i.cache.Clear()
+}
@@ -2,33 +2,67 @@ package cache
 
 import "time"
 
-var zeroTime = time.Time{}
-
+// Stored by value, so putting an item does not allocate.
 type entry[K comparable, V any] struct {
-	key      K
-	value    V
-	expireAt time.Time
+	key   K
+	value V
+	// unix nano, 0 means the entry does not expire
+	expireAt int64
+	// replaces value when 'WithWeakValues' is used
+	weak weakValue[V]
 }
 
 func newEntry[K comparable, V any](
 	key K,
 	value V,
-	expireAt time.Time,
-) *entry[K, V] {
-	return &entry[K, V]{
+	expireAt int64,
+) entry[K, V] {
+	return entry[K, V]{
 		key:      key,
 		value:    value,
 		expireAt: expireAt,
 	}
 }
 
-func (e *entry[K, V]) isExpired() bool {
-	if e.expireAt.IsZero() {
+// Returns the value, false when it is a weak value that has been reclaimed by the GC.
+func (e entry[K, V]) load() (V, bool) {
+	if e.weak == nil {
+		return e.value, true
+	}
+	return e.weak.value()
+}
+
+// Returns the value, false when the entry is expired or its weak value has been reclaimed.
+func (e entry[K, V]) loadAt(now int64) (V, bool) {
+	if e.isExpired(now) {
+		var empty V
+		return empty, false
+	}
+	return e.load()
+}
+
+func (e entry[K, V]) isExpired(now int64) bool {
+	if e.expireAt == 0 {
 		return false
 	}
-	return time.Now().After(e.expireAt)
+	return now > e.expireAt
 }
 
-func (e *entry[K, V]) isValid() bool {
-	return !e.isExpired()
+func (e entry[K, V]) isValid(now int64) bool {
+	return !e.isExpired(now)
+}
+
+func (e entry[K, V]) expireAtTime() time.Time {
+	if e.expireAt == 0 {
+		return time.Time{}
+	}
+	return time.Unix(0, e.expireAt)
+}
+
+// Returns t as unix nano, 0 for the zero time.
+func unixNano(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+	return t.UnixNano()
 }
@@ -8,26 +8,29 @@ import (
 )
 
 func TestEntryExpired(t *testing.T) {
-	entry := newEntry(0, 0, time.Now().Add(time.Millisecond*5))
+	now := time.Now().UnixNano()
+	entry := newEntry(0, 0, now+int64(time.Millisecond*5))
 
-	<-time.After(time.Millisecond * 10)
+	now += int64(time.Millisecond * 10)
 
-	assert.True(t, entry.isExpired())
-	assert.False(t, entry.isValid())
+	assert.True(t, entry.isExpired(now))
+	assert.False(t, entry.isValid(now))
 }
 
 func TestEntryNotExpired(t *testing.T) {
-	entry := newEntry(0, 0, time.Now().Add(time.Millisecond*10))
+	now := time.Now().UnixNano()
+	entry := newEntry(0, 0, now+int64(time.Millisecond*10))
 
-	<-time.After(time.Millisecond * 5)
+	now += int64(time.Millisecond * 5)
 
-	assert.False(t, entry.isExpired())
-	assert.True(t, entry.isValid())
+	assert.False(t, entry.isExpired(now))
+	assert.True(t, entry.isValid(now))
 }
 
 func TestEntryNotExpiredZeroTime(t *testing.T) {
-	entry := newEntry(0, 0, zeroTime)
+	entry := newEntry(0, 0, 0)
 
-	assert.False(t, entry.isExpired())
-	assert.True(t, entry.isValid())
+	assert.False(t, entry.isExpired(time.Now().UnixNano()))
+	assert.True(t, entry.isValid(time.Now().UnixNano()))
+	assert.True(t, entry.expireAtTime().IsZero())
 }
@@ -0,0 +1,172 @@
+package cache
+
+import (
+	"hash/maphash"
+	"sync"
+	"sync/atomic"
+)
+
+type EventType int
+
+const (
+	// A new item has been put into the cache.
+	EventPut EventType = iota + 1
+
+	// An existing item has been replaced.
+	EventUpdate
+
+	// An item has been deleted.
+	EventDelete
+
+	// An expired item has been removed by the cleanup.
+	EventExpire
+
+	// An item has been removed by the cache itself to reclaim resources.
+	EventEvict
+
+	// All items have been removed, the event has no key or values.
+	EventClear
+)
+
+func (t EventType) String() string {
+	switch t {
+	case EventPut:
+		return "put"
+	case EventUpdate:
+		return "update"
+	case EventDelete:
+		return "delete"
+	case EventExpire:
+		return "expire"
+	case EventEvict:
+		return "evict"
+	case EventClear:
+		return "clear"
+	default:
+		return "unknown"
+	}
+}
+
+// A change of the cache.
+type Event[K any, V any] struct {
+	Type EventType
+	Key  K
+
+	// The value before the change, zero for EventPut and EventClear.
+	OldValue V
+
+	// The value after the change, only set for EventPut and EventUpdate.
+	NewValue V
+}
+
+// The count of locks that order the events of keys, keys that share a lock are ordered together.
+const eventKeyLocks = 64
+
+type eventHub[K comparable, V any] struct {
+	mu          sync.RWMutex
+	subscribers map[*subscriber[K, V]]struct{}
+	count       atomic.Int32
+	closed      bool
+
+	// orders the changes with their events, see 'lockKey' and 'lockAll'
+	order    sync.RWMutex
+	keyLocks [eventKeyLocks]sync.Mutex
+	seed     maphash.Seed
+}
+
+type subscriber[K comparable, V any] struct {
+	eventchn chan Event[K, V]
+}
+
+func newEventHub[K comparable, V any]() *eventHub[K, V] {
+	return &eventHub[K, V]{
+		subscribers: make(map[*subscriber[K, V]]struct{}),
+		seed:        maphash.MakeSeed(),
+	}
+}
+
+// Locks the key while a change is applied and its event is published, so subscribers receive the events
+// of a key in the order the changes were applied. Without subscribers nothing is locked.
+func (h *eventHub[K, V]) lockKey(key K) (unlock func()) {
+	if !h.active() {
+		return func() {}
+	}
+	h.order.RLock()
+	mu := &h.keyLocks[maphash.Comparable(h.seed, key)%eventKeyLocks]
+	mu.Lock()
+	return func() {
+		mu.Unlock()
+		h.order.RUnlock()
+	}
+}
+
+// Locks every key, used for changes of all items like a clear.
+func (h *eventHub[K, V]) lockAll() (unlock func()) {
+	if !h.active() {
+		return func() {}
+	}
+	h.order.Lock()
+	return h.order.Unlock
+}
+
+// Returns true if there is at least one subscriber, so callers can skip building events.
+func (h *eventHub[K, V]) active() bool {
+	return h.count.Load() > 0
+}
+
+func (h *eventHub[K, V]) subscribe(buffer int) (<-chan Event[K, V], func()) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	sub := &subscriber[K, V]{eventchn: make(chan Event[K, V], buffer)}
+	if h.closed {
+		close(sub.eventchn)
+		return sub.eventchn, func() {}
+	}
+
+	h.subscribers[sub] = struct{}{}
+	h.count.Add(1)
+
+	return sub.eventchn, func() {
+		h.mu.Lock()
+		defer h.mu.Unlock()
+		h.remove(sub)
+	}
+}
+
+// Sends the event to every subscriber without blocking, the event is dropped for subscribers that are full.
+func (h *eventHub[K, V]) publish(event Event[K, V]) {
+	if !h.active() {
+		return
+	}
+
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	for sub := range h.subscribers {
+		select {
+		case sub.eventchn <- event:
+		default:
+		}
+	}
+}
+
+// Closes the channel of every subscriber, subscribing afterwards returns a closed channel.
+func (h *eventHub[K, V]) close() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.closed = true
+	for sub := range h.subscribers {
+		h.remove(sub)
+	}
+}
+
+func (h *eventHub[K, V]) remove(sub *subscriber[K, V]) {
+	if _, found := h.subscribers[sub]; !found {
+		return
+	}
+	delete(h.subscribers, sub)
+	h.count.Add(-1)
+	close(sub.eventchn)
+}
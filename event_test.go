@@ -0,0 +1,211 @@
+package cache
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/larscom/go-cache/cachetest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEvents(t *testing.T) {
+	TestPutUpdateDelete := func(t *testing.T) {
+		cache := NewCache[int, int]()
+		defer cache.Close()
+
+		events, cancel := cache.Subscribe(10)
+		defer cancel()
+
+		cache.Put(1, 100)
+		cache.Put(1, 200)
+		cache.Delete(1)
+		cache.Delete(2)
+		cache.Clear()
+
+		assert.Equal(t, Event[int, int]{Type: EventPut, Key: 1, NewValue: 100}, <-events)
+		assert.Equal(t, Event[int, int]{Type: EventUpdate, Key: 1, OldValue: 100, NewValue: 200}, <-events)
+		assert.Equal(t, Event[int, int]{Type: EventDelete, Key: 1, OldValue: 200}, <-events)
+		assert.Equal(t, Event[int, int]{Type: EventClear}, <-events)
+		assert.Empty(t, events)
+	}
+	t.Run("TestPutUpdateDelete", TestPutUpdateDelete)
+
+	TestLoadAndReload := func(t *testing.T) {
+		cache := NewLoadingCache(func(key int) (int, error) {
+			return key * 2, nil
+		})
+		defer cache.Close()
+
+		events, cancel := cache.Subscribe(10)
+		defer cancel()
+
+		cache.Load(1)
+		cache.Load(1)
+		cache.Reload(1)
+
+		assert.Equal(t, Event[int, int]{Type: EventPut, Key: 1, NewValue: 2}, <-events)
+		assert.Equal(t, Event[int, int]{Type: EventUpdate, Key: 1, OldValue: 2, NewValue: 2}, <-events)
+		assert.Empty(t, events)
+	}
+	t.Run("TestLoadAndReload", TestLoadAndReload)
+
+	TestExpire := func(t *testing.T) {
+		clock := cachetest.NewFakeClock(time.Now())
+		cache := NewCache(
+			WithExpireAfterWrite[int, int](time.Second),
+			WithCleanupInterval[int, int](time.Second*2),
+			WithClock[int, int](clock),
+		)
+		defer cache.Close()
+
+		events, cancel := cache.Subscribe(10)
+		defer cancel()
+
+		cache.Put(1, 100)
+		<-events
+
+		clock.Advance(time.Second * 2)
+
+		assert.Equal(t, Event[int, int]{Type: EventExpire, Key: 1, OldValue: 100}, <-events)
+	}
+	t.Run("TestExpire", TestExpire)
+
+	TestDeleteExpired := func(t *testing.T) {
+		clock := cachetest.NewFakeClock(time.Now())
+		cache := NewCache(
+			WithExpireAfterWrite[int, int](time.Second),
+			WithCleanupInterval[int, int](time.Hour),
+			WithClock[int, int](clock),
+		)
+		defer cache.Close()
+
+		events, cancel := cache.Subscribe(10)
+		defer cancel()
+
+		cache.Put(1, 100)
+		<-events
+
+		clock.Advance(time.Second * 2)
+		cache.Delete(1)
+
+		assert.Equal(t, Event[int, int]{Type: EventExpire, Key: 1, OldValue: 100}, <-events)
+		assert.Empty(t, events)
+	}
+	t.Run("TestDeleteExpired", TestDeleteExpired)
+
+	TestConcurrentOrder := func(t *testing.T) {
+		const (
+			keys       = 2
+			goroutines = 16
+			iterations = 1000
+		)
+
+		cache := NewCache[int, int]()
+		defer cache.Close()
+
+		events, cancel := cache.Subscribe(goroutines * iterations)
+		defer cancel()
+
+		var wg sync.WaitGroup
+		for g := range goroutines {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for i := range iterations {
+					key := i % keys
+					switch {
+					case g == 0 && i%100 == 0:
+						cache.Clear()
+					case (g+i)%2 == 0:
+						cache.Put(key, g*iterations+i)
+					default:
+						cache.Delete(key)
+					}
+				}
+			}()
+		}
+		wg.Wait()
+
+		// replaying the events must end in the final state of the cache
+		values := make(map[int]int)
+		for len(events) > 0 {
+			event := <-events
+			switch event.Type {
+			case EventPut:
+				_, found := values[event.Key]
+				assert.False(t, found, "put of a present key %d", event.Key)
+				values[event.Key] = event.NewValue
+			case EventUpdate:
+				assert.Equal(t, values[event.Key], event.OldValue)
+				values[event.Key] = event.NewValue
+			case EventDelete:
+				assert.Equal(t, values[event.Key], event.OldValue)
+				delete(values, event.Key)
+			case EventClear:
+				clear(values)
+			}
+		}
+
+		for key := range keys {
+			value, found := cache.Get(key)
+			expected, expectedFound := values[key]
+			assert.Equal(t, expectedFound, found)
+			assert.Equal(t, expected, value)
+		}
+	}
+	t.Run("TestConcurrentOrder", TestConcurrentOrder)
+
+	TestDropWhenFull := func(t *testing.T) {
+		cache := NewCache[int, int]()
+		defer cache.Close()
+
+		events, cancel := cache.Subscribe(1)
+		defer cancel()
+
+		cache.Put(1, 100)
+		cache.Put(2, 200)
+
+		assert.Equal(t, 1, (<-events).Key)
+		assert.Empty(t, events)
+	}
+	t.Run("TestDropWhenFull", TestDropWhenFull)
+
+	TestCancel := func(t *testing.T) {
+		cache := NewCache[int, int]()
+		defer cache.Close()
+
+		events, cancel := cache.Subscribe(1)
+		cancel()
+		cancel()
+
+		cache.Put(1, 100)
+
+		_, ok := <-events
+		assert.False(t, ok)
+	}
+	t.Run("TestCancel", TestCancel)
+
+	TestClose := func(t *testing.T) {
+		cache := NewCache[int, int]()
+
+		events, cancel := cache.Subscribe(1)
+		defer cancel()
+
+		cache.Close()
+
+		_, ok := <-events
+		assert.False(t, ok)
+
+		events, _ = cache.Subscribe(1)
+		_, ok = <-events
+		assert.False(t, ok)
+	}
+	t.Run("TestClose", TestClose)
+}
+
+func TestEventTypeString(t *testing.T) {
+	assert.Equal(t, "put", EventPut.String())
+	assert.Equal(t, "clear", EventClear.String())
+	assert.Equal(t, "unknown", EventType(0).String())
+}
@@ -1,6 +1,6 @@
 module github.com/larscom/go-cache
 
-go 1.22.0
+go 1.24.0
 
 require (
 	github.com/mhmtszr/concurrent-swiss-map v1.0.9
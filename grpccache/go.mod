@@ -0,0 +1,21 @@
+module github.com/larscom/go-cache/grpccache
+
+go 1.24.0
+
+require (
+	github.com/larscom/go-cache v0.0.0-20261018152619-aa72783b62fc
+	github.com/stretchr/testify v1.11.1
+	google.golang.org/grpc v1.71.0
+	google.golang.org/protobuf v1.36.4
+)
+
+require (
+	github.com/davecgh/go-spew v1.1.1 // indirect
+	github.com/mhmtszr/concurrent-swiss-map v1.0.9 // indirect
+	github.com/pmezard/go-difflib v1.0.0 // indirect
+	golang.org/x/net v0.34.0 // indirect
+	golang.org/x/sys v0.29.0 // indirect
+	golang.org/x/text v0.21.0 // indirect
+	google.golang.org/genproto/googleapis/rpc v0.0.0-20250115164207-1a7da9e5054f // indirect
+	gopkg.in/yaml.v3 v3.0.1 // indirect
+)
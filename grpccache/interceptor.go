@@ -0,0 +1,192 @@
+// Package grpccache provides gRPC unary interceptors that cache responses of idempotent calls.
+package grpccache
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/larscom/go-cache"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/protobuf/proto"
+)
+
+type Option func(o *options)
+
+// Cache responses of the method (e.g. "/grpc.health.v1.Health/Check") for ttl, a ttl of 0 uses the 'TTL' of the cache.
+//
+// Only methods that are configured are cached, so only configure idempotent methods.
+func WithMethodTTL(fullMethod string, ttl time.Duration) Option {
+	return func(o *options) {
+		o.methods[fullMethod] = ttl
+	}
+}
+
+// Metadata keys (e.g. "authorization") that are part of the cache key, so callers with different values don't share responses.
+func WithMetadataKeys(keys ...string) Option {
+	return func(o *options) {
+		for _, key := range keys {
+			o.metadataKeys = append(o.metadataKeys, strings.ToLower(key))
+		}
+	}
+}
+
+type options struct {
+	methods      map[string]time.Duration
+	metadataKeys []string
+}
+
+func newOptions(opts []Option) *options {
+	o := &options{methods: make(map[string]time.Duration)}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// Caches responses of the server handler, concurrent calls with the same request wait for a single handler call.
+// That call keeps running when the caller that started it cancels, the deadline of that caller still applies.
+//
+// The cache key is the method and the deterministic protobuf encoding of the request, errors are not cached.
+// Cached responses are shared between calls and must not be modified.
+func UnaryServerInterceptor(c cache.Cache[string, proto.Message], opts ...Option) grpc.UnaryServerInterceptor {
+	o := newOptions(opts)
+
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		ttl, found := o.methods[info.FullMethod]
+		if !found {
+			return handler(ctx, req)
+		}
+
+		md, _ := metadata.FromIncomingContext(ctx)
+		key, ok := o.key(info.FullMethod, req, md)
+		if !ok {
+			return handler(ctx, req)
+		}
+
+		return load(c, key, ttl, func(string) (proto.Message, error) {
+			ctx, cancel := withoutCancel(ctx)
+			defer cancel()
+
+			res, err := handler(ctx, req)
+			if err != nil {
+				return nil, err
+			}
+			msg, _ := res.(proto.Message)
+			return msg, nil
+		})
+	}
+}
+
+// Caches responses on the client, concurrent calls with the same request wait for a single call.
+// That call keeps running when the caller that started it cancels, the deadline of that caller still applies.
+//
+// The cache key is the method and the deterministic protobuf encoding of the request, errors are not cached.
+// The reply receives a copy of the cached response.
+func UnaryClientInterceptor(c cache.Cache[string, proto.Message], opts ...Option) grpc.UnaryClientInterceptor {
+	o := newOptions(opts)
+
+	return func(
+		ctx context.Context,
+		method string,
+		req, reply any,
+		cc *grpc.ClientConn,
+		invoker grpc.UnaryInvoker,
+		callOpts ...grpc.CallOption,
+	) error {
+		ttl, found := o.methods[method]
+		replyMsg, isMsg := reply.(proto.Message)
+		if !found || !isMsg {
+			return invoker(ctx, method, req, reply, cc, callOpts...)
+		}
+
+		md, _ := metadata.FromOutgoingContext(ctx)
+		key, ok := o.key(method, req, md)
+		if !ok {
+			return invoker(ctx, method, req, reply, cc, callOpts...)
+		}
+
+		res, err := load(c, key, ttl, func(string) (proto.Message, error) {
+			ctx, cancel := withoutCancel(ctx)
+			defer cancel()
+
+			fresh := replyMsg.ProtoReflect().New().Interface()
+			if err := invoker(ctx, method, req, fresh, cc, callOpts...); err != nil {
+				return nil, err
+			}
+			return fresh, nil
+		})
+		if err != nil {
+			return err
+		}
+
+		proto.Reset(replyMsg)
+		proto.Merge(replyMsg, res)
+		return nil
+	}
+}
+
+// Whenever the cache has been closed, the call is not cached.
+func load(
+	c cache.Cache[string, proto.Message],
+	key string,
+	ttl time.Duration,
+	loaderFunc cache.LoaderFunc[string, proto.Message],
+) (proto.Message, error) {
+	loaded := false
+	load := func(key string) (proto.Message, error) {
+		loaded = true
+		return loaderFunc(key)
+	}
+
+	var (
+		res proto.Message
+		err error
+	)
+	if ttl > 0 {
+		res, err = cache.GetOrLoadWithTTL(c, key, ttl, load)
+	} else {
+		res, err = cache.GetOrLoad(c, key, load)
+	}
+
+	if !loaded && errors.Is(err, cache.ErrClosed) {
+		return loaderFunc(key)
+	}
+	return res, err
+}
+
+// The context of a call that other calls wait for, so canceling the caller that started it doesn't fail the
+// waiting calls. The values and the deadline of ctx are kept.
+func withoutCancel(ctx context.Context) (context.Context, context.CancelFunc) {
+	detached := context.WithoutCancel(ctx)
+	if deadline, found := ctx.Deadline(); found {
+		return context.WithDeadline(detached, deadline)
+	}
+	return detached, func() {}
+}
+
+// Returns the method, the selected metadata and the deterministic encoding of the request, false when req is not a proto.Message.
+func (o *options) key(method string, req any, md metadata.MD) (string, bool) {
+	msg, ok := req.(proto.Message)
+	if !ok {
+		return "", false
+	}
+	data, err := proto.MarshalOptions{Deterministic: true}.Marshal(msg)
+	if err != nil {
+		return "", false
+	}
+
+	var b strings.Builder
+	b.WriteString(method)
+	for _, key := range o.metadataKeys {
+		b.WriteByte('\n')
+		b.WriteString(key)
+		b.WriteByte(':')
+		b.WriteString(strings.Join(md.Get(key), ","))
+	}
+	b.WriteByte('\n')
+	b.Write(data)
+	return b.String(), true
+}
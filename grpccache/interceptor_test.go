@@ -0,0 +1,287 @@
+package grpccache
+
+import (
+	"context"
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/larscom/go-cache"
+	"github.com/larscom/go-cache/cachetest"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/proto"
+)
+
+const checkMethod = grpc_health_v1.Health_Check_FullMethodName
+
+type healthServer struct {
+	grpc_health_v1.UnimplementedHealthServer
+	calls atomic.Int64
+}
+
+func (s *healthServer) Check(_ context.Context, req *grpc_health_v1.HealthCheckRequest) (*grpc_health_v1.HealthCheckResponse, error) {
+	s.calls.Add(1)
+	if req.Service == "unknown" {
+		return nil, status.Error(codes.NotFound, "unknown service")
+	}
+	return &grpc_health_v1.HealthCheckResponse{Status: grpc_health_v1.HealthCheckResponse_SERVING}, nil
+}
+
+func newTestClient(t *testing.T, serverOpts []grpc.ServerOption, dialOpts ...grpc.DialOption) (grpc_health_v1.HealthClient, *healthServer) {
+	listener := bufconn.Listen(1024 * 1024)
+
+	health := new(healthServer)
+	server := grpc.NewServer(serverOpts...)
+	grpc_health_v1.RegisterHealthServer(server, health)
+	go server.Serve(listener)
+
+	dialOpts = append(dialOpts,
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	conn, err := grpc.NewClient("passthrough:///bufnet", dialOpts...)
+	assert.NoError(t, err)
+
+	t.Cleanup(func() {
+		conn.Close()
+		server.Stop()
+	})
+
+	return grpc_health_v1.NewHealthClient(conn), health
+}
+
+func newTestCache(t *testing.T) (cache.Cache[string, proto.Message], *cachetest.FakeClock) {
+	clock := cachetest.NewFakeClock(time.Now())
+	c := cache.NewCache(cache.WithClock[string, proto.Message](clock))
+	t.Cleanup(c.Close)
+	return c, clock
+}
+
+func check(client grpc_health_v1.HealthClient, ctx context.Context, service string) (*grpc_health_v1.HealthCheckResponse, error) {
+	return client.Check(ctx, &grpc_health_v1.HealthCheckRequest{Service: service})
+}
+
+func TestUnaryServerInterceptor(t *testing.T) {
+	ctx := context.Background()
+
+	TestCacheMethod := func(t *testing.T) {
+		c, _ := newTestCache(t)
+		client, health := newTestClient(t, []grpc.ServerOption{
+			grpc.UnaryInterceptor(UnaryServerInterceptor(c, WithMethodTTL(checkMethod, 0))),
+		})
+
+		for i := 0; i < 3; i++ {
+			res, err := check(client, ctx, "a")
+			assert.NoError(t, err)
+			assert.Equal(t, grpc_health_v1.HealthCheckResponse_SERVING, res.Status)
+		}
+		assert.Equal(t, int64(1), health.calls.Load())
+
+		check(client, ctx, "b")
+		assert.Equal(t, int64(2), health.calls.Load())
+	}
+	t.Run("TestCacheMethod", TestCacheMethod)
+
+	TestMethodNotConfigured := func(t *testing.T) {
+		c, _ := newTestCache(t)
+		client, health := newTestClient(t, []grpc.ServerOption{
+			grpc.UnaryInterceptor(UnaryServerInterceptor(c)),
+		})
+
+		check(client, ctx, "a")
+		check(client, ctx, "a")
+		assert.Equal(t, int64(2), health.calls.Load())
+		assert.True(t, c.IsEmpty())
+	}
+	t.Run("TestMethodNotConfigured", TestMethodNotConfigured)
+
+	TestErrorNotCached := func(t *testing.T) {
+		c, _ := newTestCache(t)
+		client, health := newTestClient(t, []grpc.ServerOption{
+			grpc.UnaryInterceptor(UnaryServerInterceptor(c, WithMethodTTL(checkMethod, 0))),
+		})
+
+		for i := 0; i < 2; i++ {
+			_, err := check(client, ctx, "unknown")
+			assert.Equal(t, codes.NotFound, status.Code(err))
+		}
+		assert.Equal(t, int64(2), health.calls.Load())
+	}
+	t.Run("TestErrorNotCached", TestErrorNotCached)
+
+	TestTTL := func(t *testing.T) {
+		c, clock := newTestCache(t)
+		client, health := newTestClient(t, []grpc.ServerOption{
+			grpc.UnaryInterceptor(UnaryServerInterceptor(c, WithMethodTTL(checkMethod, time.Minute))),
+		})
+
+		check(client, ctx, "a")
+		clock.Advance(time.Second * 30)
+		check(client, ctx, "a")
+		assert.Equal(t, int64(1), health.calls.Load())
+
+		clock.Advance(time.Minute)
+		check(client, ctx, "a")
+		assert.Equal(t, int64(2), health.calls.Load())
+	}
+	t.Run("TestTTL", TestTTL)
+
+	TestMetadataKeys := func(t *testing.T) {
+		c, _ := newTestCache(t)
+		client, health := newTestClient(t, []grpc.ServerOption{
+			grpc.UnaryInterceptor(UnaryServerInterceptor(c, WithMethodTTL(checkMethod, 0), WithMetadataKeys("Authorization"))),
+		})
+
+		alice := metadata.AppendToOutgoingContext(ctx, "authorization", "alice")
+		bob := metadata.AppendToOutgoingContext(ctx, "authorization", "bob")
+
+		check(client, alice, "a")
+		check(client, alice, "a")
+		check(client, bob, "a")
+		assert.Equal(t, int64(2), health.calls.Load())
+	}
+	t.Run("TestMetadataKeys", TestMetadataKeys)
+
+	TestClosedCache := func(t *testing.T) {
+		c, _ := newTestCache(t)
+		client, health := newTestClient(t, []grpc.ServerOption{
+			grpc.UnaryInterceptor(UnaryServerInterceptor(c, WithMethodTTL(checkMethod, 0))),
+		})
+		c.Close()
+
+		for i := 0; i < 2; i++ {
+			res, err := check(client, ctx, "a")
+			assert.NoError(t, err)
+			assert.Equal(t, grpc_health_v1.HealthCheckResponse_SERVING, res.Status)
+		}
+		assert.Equal(t, int64(2), health.calls.Load())
+	}
+	t.Run("TestClosedCache", TestClosedCache)
+
+	TestFirstCallerCanceled := func(t *testing.T) {
+		c, _ := newTestCache(t)
+		interceptor := UnaryServerInterceptor(c, WithMethodTTL(checkMethod, 0))
+		info := &grpc.UnaryServerInfo{FullMethod: checkMethod}
+		req := &grpc_health_v1.HealthCheckRequest{Service: "a"}
+
+		started := make(chan struct{})
+		release := make(chan struct{})
+		var calls atomic.Int64
+		var hasDeadline atomic.Bool
+		handler := func(ctx context.Context, req any) (any, error) {
+			calls.Add(1)
+			_, found := ctx.Deadline()
+			hasDeadline.Store(found)
+			close(started)
+			<-release
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+			return &grpc_health_v1.HealthCheckResponse{Status: grpc_health_v1.HealthCheckResponse_SERVING}, nil
+		}
+
+		firstCtx, cancel := context.WithTimeout(ctx, time.Minute)
+		go interceptor(firstCtx, req, info, handler)
+		<-started
+
+		type result struct {
+			res any
+			err error
+		}
+		waiting := make(chan result)
+		go func() {
+			res, err := interceptor(ctx, req, info, handler)
+			waiting <- result{res, err}
+		}()
+		// the second call waits for the handler call of the first one
+		time.Sleep(time.Millisecond * 20)
+
+		cancel()
+		close(release)
+
+		second := <-waiting
+		assert.NoError(t, second.err)
+		assert.Equal(t, grpc_health_v1.HealthCheckResponse_SERVING, second.res.(*grpc_health_v1.HealthCheckResponse).Status)
+		assert.Equal(t, int64(1), calls.Load())
+		assert.True(t, hasDeadline.Load())
+	}
+	t.Run("TestFirstCallerCanceled", TestFirstCallerCanceled)
+}
+
+func TestUnaryClientInterceptor(t *testing.T) {
+	ctx := context.Background()
+
+	TestCacheMethod := func(t *testing.T) {
+		c, _ := newTestCache(t)
+		client, health := newTestClient(t, nil,
+			grpc.WithUnaryInterceptor(UnaryClientInterceptor(c, WithMethodTTL(checkMethod, 0))),
+		)
+
+		res, err := check(client, ctx, "a")
+		assert.NoError(t, err)
+		assert.Equal(t, grpc_health_v1.HealthCheckResponse_SERVING, res.Status)
+
+		// the reply is a copy, so modifying it doesn't change the cached response
+		res.Status = grpc_health_v1.HealthCheckResponse_NOT_SERVING
+
+		res, err = check(client, ctx, "a")
+		assert.NoError(t, err)
+		assert.Equal(t, grpc_health_v1.HealthCheckResponse_SERVING, res.Status)
+		assert.Equal(t, int64(1), health.calls.Load())
+	}
+	t.Run("TestCacheMethod", TestCacheMethod)
+
+	TestErrorNotCached := func(t *testing.T) {
+		c, _ := newTestCache(t)
+		client, health := newTestClient(t, nil,
+			grpc.WithUnaryInterceptor(UnaryClientInterceptor(c, WithMethodTTL(checkMethod, 0))),
+		)
+
+		for i := 0; i < 2; i++ {
+			_, err := check(client, ctx, "unknown")
+			assert.Equal(t, codes.NotFound, status.Code(err))
+		}
+		assert.Equal(t, int64(2), health.calls.Load())
+	}
+	t.Run("TestErrorNotCached", TestErrorNotCached)
+
+	TestLoadingCache := func(t *testing.T) {
+		c := cache.NewLoadingCache(cache.NoopLoaderFunc[string, proto.Message])
+		defer c.Close()
+		client, health := newTestClient(t, nil,
+			grpc.WithUnaryInterceptor(UnaryClientInterceptor(c, WithMethodTTL(checkMethod, time.Minute))),
+		)
+
+		check(client, ctx, "a")
+		check(client, ctx, "a")
+		assert.Equal(t, int64(1), health.calls.Load())
+		assert.Equal(t, 1, c.Count())
+	}
+	t.Run("TestLoadingCache", TestLoadingCache)
+
+	TestClosedCache := func(t *testing.T) {
+		c, _ := newTestCache(t)
+		client, health := newTestClient(t, nil,
+			grpc.WithUnaryInterceptor(UnaryClientInterceptor(c, WithMethodTTL(checkMethod, time.Minute))),
+		)
+		c.Close()
+
+		for i := 0; i < 2; i++ {
+			res, err := check(client, ctx, "a")
+			assert.NoError(t, err)
+			assert.Equal(t, grpc_health_v1.HealthCheckResponse_SERVING, res.Status)
+		}
+		assert.Equal(t, int64(2), health.calls.Load())
+	}
+	t.Run("TestClosedCache", TestClosedCache)
+}
@@ -0,0 +1,106 @@
+package httpcache
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// The directives of a Cache-Control header, directives without a value map to "".
+type cacheControl map[string]string
+
+func parseCacheControl(header http.Header) cacheControl {
+	cc := make(cacheControl)
+	for _, value := range header.Values("Cache-Control") {
+		for _, directive := range strings.Split(value, ",") {
+			directive = strings.TrimSpace(directive)
+			if directive == "" {
+				continue
+			}
+			name, arg, _ := strings.Cut(directive, "=")
+			cc[strings.ToLower(strings.TrimSpace(name))] = strings.Trim(strings.TrimSpace(arg), `"`)
+		}
+	}
+	return cc
+}
+
+func (cc cacheControl) has(directive string) bool {
+	_, found := cc[directive]
+	return found
+}
+
+// Reports whether a shared cache may store the response to a request with 'Authorization' (RFC 9111 section 3.5).
+func (cc cacheControl) allowsAuthorization() bool {
+	return cc.has("public") || cc.has("s-maxage") || cc.has("must-revalidate")
+}
+
+// Returns the value of a delta-seconds directive (e.g. max-age), false when missing or invalid.
+func (cc cacheControl) seconds(directive string) (time.Duration, bool) {
+	value, found := cc[directive]
+	if !found {
+		return 0, false
+	}
+	seconds, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || seconds < 0 {
+		return 0, false
+	}
+	return time.Duration(seconds) * time.Second, true
+}
+
+// The freshness lifetime of a response (RFC 9111 section 4.2.1), false when the response has no explicit lifetime.
+func freshnessLifetime(header http.Header, shared bool) (time.Duration, bool) {
+	cc := parseCacheControl(header)
+	if shared {
+		if lifetime, found := cc.seconds("s-maxage"); found {
+			return lifetime, true
+		}
+	}
+	if lifetime, found := cc.seconds("max-age"); found {
+		return lifetime, true
+	}
+
+	expires := header.Get("Expires")
+	if expires == "" {
+		return 0, false
+	}
+	// an invalid Expires (e.g. "0") means already expired
+	expiresAt, err := http.ParseTime(expires)
+	if err != nil {
+		return 0, true
+	}
+	date, err := http.ParseTime(header.Get("Date"))
+	if err != nil {
+		return 0, true
+	}
+	return max(expiresAt.Sub(date), 0), true
+}
+
+// The age of a response at the time it was received (RFC 9111 section 4.2.3).
+func initialAge(header http.Header, requestTime, responseTime time.Time) time.Duration {
+	var apparentAge time.Duration
+	if date, err := http.ParseTime(header.Get("Date")); err == nil {
+		apparentAge = max(responseTime.Sub(date), 0)
+	}
+
+	var ageValue time.Duration
+	if seconds, err := strconv.ParseInt(header.Get("Age"), 10, 64); err == nil && seconds > 0 {
+		ageValue = time.Duration(seconds) * time.Second
+	}
+	correctedAge := ageValue + responseTime.Sub(requestTime)
+
+	return max(apparentAge, correctedAge)
+}
+
+// Returns the field names of the Vary header in canonical form.
+func varyFields(header http.Header) []string {
+	var fields []string
+	for _, value := range header.Values("Vary") {
+		for _, field := range strings.Split(value, ",") {
+			if field = strings.TrimSpace(field); field != "" {
+				fields = append(fields, http.CanonicalHeaderKey(field))
+			}
+		}
+	}
+	return fields
+}
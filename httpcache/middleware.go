@@ -0,0 +1,273 @@
+package httpcache
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/larscom/go-cache"
+)
+
+// A response that is stored by 'Middleware'.
+type Response struct {
+	StatusCode int
+	Header     http.Header
+	Body       []byte
+}
+
+type MiddlewareOption func(m *middleware)
+
+// The function that creates the cache key of a request, defaults to the method, host, URL and key headers.
+func WithKeyFunc(keyFunc func(r *http.Request) string) MiddlewareOption {
+	return func(m *middleware) {
+		m.keyFunc = keyFunc
+	}
+}
+
+// Request headers that are part of the default cache key, e.g. "Accept-Language".
+func WithKeyHeaders(headers ...string) MiddlewareOption {
+	return func(m *middleware) {
+		for _, header := range headers {
+			m.keyHeaders = append(m.keyHeaders, http.CanonicalHeaderKey(header))
+		}
+	}
+}
+
+// The 'TTL' of responses for paths that start with pathPrefix, the longest matching prefix wins.
+//
+// Responses of other paths use the 'TTL' of the cache (see 'cache.WithExpireAfterWrite').
+func WithRouteTTL(pathPrefix string, ttl time.Duration) MiddlewareOption {
+	return func(m *middleware) {
+		m.routes = append(m.routes, route{pathPrefix, ttl})
+		sort.SliceStable(m.routes, func(i, j int) bool {
+			return len(m.routes[i].pathPrefix) > len(m.routes[j].pathPrefix)
+		})
+	}
+}
+
+// Requests that have this header bypass the cache, e.g. "X-Cache-Bypass". The response is not stored either.
+func WithBypassHeader(header string) MiddlewareOption {
+	return func(m *middleware) {
+		m.bypassHeaders = append(m.bypassHeaders, header)
+	}
+}
+
+type route struct {
+	pathPrefix string
+	ttl        time.Duration
+}
+
+type middleware struct {
+	cache         cache.Cache[string, Response]
+	next          http.Handler
+	keyFunc       func(r *http.Request) string
+	keyHeaders    []string
+	routes        []route
+	bypassHeaders []string
+}
+
+// Returned by the loader when the response should not be stored.
+var errNotStorable = errors.New("response is not storable")
+
+// Caches GET and HEAD responses of the next handler.
+//
+// Concurrent requests for the same key wait for a single call of the next handler (see 'cache.GetOrLoad'),
+// whenever that response is not stored the waiting requests call the next handler themselves.
+//
+// Responses are stored when the status code is cacheable by default (e.g. 200 or 404), unless they
+// contain 'Cache-Control: no-store' or 'private' or set a cookie. Responses to requests with an
+// 'Authorization' header are only stored and served from the cache when they contain
+// 'Cache-Control: public', 's-maxage' or 'must-revalidate'. Responses are buffered, so
+// streaming responses are not supported.
+func Middleware(c cache.Cache[string, Response], opts ...MiddlewareOption) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		m := &middleware{
+			cache: c,
+			next:  next,
+		}
+		for _, opt := range opts {
+			opt(m)
+		}
+		if m.keyFunc == nil {
+			m.keyFunc = m.defaultKey
+		}
+		return m
+	}
+}
+
+func (m *middleware) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if (r.Method != http.MethodGet && r.Method != http.MethodHead) || m.bypass(r) {
+		m.next.ServeHTTP(w, r)
+		return
+	}
+
+	key := m.keyFunc(r)
+	authorized := r.Header.Get("Authorization") != ""
+
+	if res, found := m.cache.Get(key); found && (!authorized || parseCacheControl(res.Header).allowsAuthorization()) {
+		writeResponse(w, r, res, true)
+		return
+	}
+
+	// responses to other users are not shared with authorized requests
+	if authorized {
+		res, _ := m.load(r, key)
+		writeResponse(w, r, res, false)
+		return
+	}
+
+	loaded := false
+	loaderFunc := func(string) (Response, error) {
+		loaded = true
+
+		rec := newResponseRecorder()
+		m.next.ServeHTTP(rec, r)
+
+		res := rec.response()
+		if !isStorableResponse(r, res) {
+			return res, errNotStorable
+		}
+		return res, nil
+	}
+
+	var (
+		res Response
+		err error
+	)
+	if ttl, found := m.routeTTL(r.URL.Path); found {
+		res, err = cache.GetOrLoadWithTTL(m.cache, key, ttl, loaderFunc)
+	} else {
+		res, err = cache.GetOrLoad(m.cache, key, loaderFunc)
+	}
+
+	switch {
+	case loaded:
+		writeResponse(w, r, res, false)
+	case err != nil:
+		// the response of another request is not storable (so not shared either) or the cache has been closed
+		m.next.ServeHTTP(w, r)
+	default:
+		writeResponse(w, r, res, true)
+	}
+}
+
+// Calls the next handler and stores the response, returns false when the response is not storable.
+func (m *middleware) load(r *http.Request, key string) (Response, bool) {
+	rec := newResponseRecorder()
+	m.next.ServeHTTP(rec, r)
+
+	res := rec.response()
+	if !isStorableResponse(r, res) {
+		return res, false
+	}
+	m.put(r, key, res)
+	return res, true
+}
+
+// Stores the response with the 'TTL' of the route (whenever the cache implements cache.TTLCache).
+func (m *middleware) put(r *http.Request, key string, res Response) {
+	ttl, found := m.routeTTL(r.URL.Path)
+	if c, ok := m.cache.(cache.TTLCache[string, Response]); ok && found {
+		c.PutWithTTL(key, res, ttl)
+	} else {
+		m.cache.Put(key, res)
+	}
+}
+
+func writeResponse(w http.ResponseWriter, r *http.Request, res Response, fromCache bool) {
+	for field, values := range res.Header {
+		w.Header()[field] = append([]string(nil), values...)
+	}
+	if fromCache {
+		w.Header().Set(XFromCache, "1")
+	}
+	w.WriteHeader(res.StatusCode)
+	if r.Method != http.MethodHead {
+		w.Write(res.Body)
+	}
+}
+
+func (m *middleware) defaultKey(r *http.Request) string {
+	var b strings.Builder
+	b.WriteString(r.Method)
+	b.WriteByte(' ')
+	b.WriteString(r.Host)
+	b.WriteString(r.URL.RequestURI())
+	for _, header := range m.keyHeaders {
+		b.WriteByte('\n')
+		b.WriteString(header)
+		b.WriteByte(':')
+		b.WriteString(strings.Join(r.Header.Values(header), ","))
+	}
+	return b.String()
+}
+
+func (m *middleware) bypass(r *http.Request) bool {
+	for _, header := range m.bypassHeaders {
+		if r.Header.Get(header) != "" {
+			return true
+		}
+	}
+	return false
+}
+
+func (m *middleware) routeTTL(path string) (time.Duration, bool) {
+	for _, route := range m.routes {
+		if strings.HasPrefix(path, route.pathPrefix) {
+			return route.ttl, true
+		}
+	}
+	return 0, false
+}
+
+func isStorableResponse(r *http.Request, res Response) bool {
+	if !cacheableStatusCodes[res.StatusCode] || res.Header.Get("Set-Cookie") != "" {
+		return false
+	}
+	cc := parseCacheControl(res.Header)
+	if r.Header.Get("Authorization") != "" && !cc.allowsAuthorization() {
+		return false
+	}
+	return !cc.has("no-store") && !cc.has("private")
+}
+
+// Buffers the response of the next handler.
+type responseRecorder struct {
+	header     http.Header
+	statusCode int
+	body       bytes.Buffer
+}
+
+func newResponseRecorder() *responseRecorder {
+	return &responseRecorder{header: make(http.Header)}
+}
+
+func (r *responseRecorder) Header() http.Header {
+	return r.header
+}
+
+func (r *responseRecorder) WriteHeader(statusCode int) {
+	if r.statusCode == 0 {
+		r.statusCode = statusCode
+	}
+}
+
+func (r *responseRecorder) Write(data []byte) (int, error) {
+	r.WriteHeader(http.StatusOK)
+	return r.body.Write(data)
+}
+
+func (r *responseRecorder) response() Response {
+	statusCode := r.statusCode
+	if statusCode == 0 {
+		statusCode = http.StatusOK
+	}
+	return Response{
+		StatusCode: statusCode,
+		Header:     r.header.Clone(),
+		Body:       r.body.Bytes(),
+	}
+}
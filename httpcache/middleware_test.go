@@ -0,0 +1,239 @@
+package httpcache
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/larscom/go-cache"
+	"github.com/larscom/go-cache/cachetest"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestHandler(t *testing.T, handler http.HandlerFunc, opts ...MiddlewareOption) (http.Handler, *cachetest.FakeClock, *atomic.Int64) {
+	clock := cachetest.NewFakeClock(time.Now())
+	c := cache.NewCache(cache.WithClock[string, Response](clock))
+	t.Cleanup(c.Close)
+
+	calls := new(atomic.Int64)
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		handler(w, r)
+	})
+
+	return Middleware(c, opts...)(next), clock, calls
+}
+
+func serve(handler http.Handler, method, target string, header ...string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, target, nil)
+	for i := 0; i+1 < len(header); i += 2 {
+		req.Header.Set(header[i], header[i+1])
+	}
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestMiddleware(t *testing.T) {
+	hello := func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain")
+		io.WriteString(w, "Hello World")
+	}
+
+	TestCacheGet := func(t *testing.T) {
+		handler, _, calls := newTestHandler(t, hello)
+
+		rec := serve(handler, http.MethodGet, "/hello")
+		assert.Equal(t, "Hello World", rec.Body.String())
+		assert.Empty(t, rec.Header().Get(XFromCache))
+
+		rec = serve(handler, http.MethodGet, "/hello")
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "Hello World", rec.Body.String())
+		assert.Equal(t, "text/plain", rec.Header().Get("Content-Type"))
+		assert.Equal(t, "1", rec.Header().Get(XFromCache))
+
+		serve(handler, http.MethodGet, "/hello?page=2")
+		assert.Equal(t, int64(2), calls.Load())
+	}
+	t.Run("TestCacheGet", TestCacheGet)
+
+	TestHead := func(t *testing.T) {
+		handler, _, calls := newTestHandler(t, hello)
+
+		rec := serve(handler, http.MethodHead, "/hello")
+		assert.Empty(t, rec.Body.String())
+
+		rec = serve(handler, http.MethodGet, "/hello")
+		assert.Equal(t, "Hello World", rec.Body.String())
+		assert.Equal(t, int64(2), calls.Load())
+	}
+	t.Run("TestHead", TestHead)
+
+	TestCoalesce := func(t *testing.T) {
+		handler, _, calls := newTestHandler(t, func(w http.ResponseWriter, r *http.Request) {
+			time.Sleep(time.Millisecond * 5)
+			hello(w, r)
+		})
+
+		var wg sync.WaitGroup
+		for i := 0; i < 10; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				rec := serve(handler, http.MethodGet, "/hello")
+				assert.Equal(t, "Hello World", rec.Body.String())
+			}()
+		}
+		wg.Wait()
+
+		assert.Equal(t, int64(1), calls.Load())
+	}
+	t.Run("TestCoalesce", TestCoalesce)
+
+	TestNotStored := func(t *testing.T) {
+		responses := map[string]http.HandlerFunc{
+			"no-store": func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Cache-Control", "no-store")
+			},
+			"cookie": func(w http.ResponseWriter, r *http.Request) {
+				http.SetCookie(w, &http.Cookie{Name: "session", Value: "1"})
+			},
+			"error": func(w http.ResponseWriter, r *http.Request) {
+				http.Error(w, "error", http.StatusInternalServerError)
+			},
+		}
+
+		for name, response := range responses {
+			handler, _, calls := newTestHandler(t, response)
+
+			first := serve(handler, http.MethodGet, "/")
+			second := serve(handler, http.MethodGet, "/")
+
+			assert.Equal(t, first.Code, second.Code)
+			assert.Empty(t, second.Header().Get(XFromCache))
+			assert.Equal(t, int64(2), calls.Load(), name)
+		}
+	}
+	t.Run("TestNotStored", TestNotStored)
+
+	TestNotStoredConcurrent := func(t *testing.T) {
+		var running, maxRunning atomic.Int64
+		handler, _, calls := newTestHandler(t, func(w http.ResponseWriter, r *http.Request) {
+			n := running.Add(1)
+			defer running.Add(-1)
+			for {
+				if m := maxRunning.Load(); n <= m || maxRunning.CompareAndSwap(m, n) {
+					break
+				}
+			}
+			time.Sleep(time.Millisecond * 20)
+			w.Header().Set("Cache-Control", "private")
+		})
+
+		var wg sync.WaitGroup
+		for i := 0; i < 10; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				serve(handler, http.MethodGet, "/")
+			}()
+		}
+		wg.Wait()
+
+		assert.Equal(t, int64(10), calls.Load())
+		assert.Greater(t, maxRunning.Load(), int64(1))
+	}
+	t.Run("TestNotStoredConcurrent", TestNotStoredConcurrent)
+
+	TestAuthorization := func(t *testing.T) {
+		handler, _, calls := newTestHandler(t, func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path == "/public" {
+				w.Header().Set("Cache-Control", "public")
+			}
+			io.WriteString(w, r.Header.Get("Authorization"))
+		})
+
+		serve(handler, http.MethodGet, "/private", "Authorization", "alice")
+		rec := serve(handler, http.MethodGet, "/private", "Authorization", "bob")
+		assert.Equal(t, "bob", rec.Body.String())
+		rec = serve(handler, http.MethodGet, "/private")
+		assert.Empty(t, rec.Body.String())
+		assert.Equal(t, int64(3), calls.Load())
+
+		// responses to anonymous requests are not served to authorized requests either
+		rec = serve(handler, http.MethodGet, "/private", "Authorization", "alice")
+		assert.Equal(t, "alice", rec.Body.String())
+		assert.Equal(t, int64(4), calls.Load())
+
+		serve(handler, http.MethodGet, "/public", "Authorization", "alice")
+		rec = serve(handler, http.MethodGet, "/public", "Authorization", "bob")
+		assert.Equal(t, "alice", rec.Body.String())
+		assert.Equal(t, "1", rec.Header().Get(XFromCache))
+		assert.Equal(t, int64(5), calls.Load())
+	}
+	t.Run("TestAuthorization", TestAuthorization)
+
+	TestUnsafeMethod := func(t *testing.T) {
+		handler, _, calls := newTestHandler(t, hello)
+
+		serve(handler, http.MethodPost, "/hello")
+		serve(handler, http.MethodPost, "/hello")
+		assert.Equal(t, int64(2), calls.Load())
+	}
+	t.Run("TestUnsafeMethod", TestUnsafeMethod)
+
+	TestBypassHeader := func(t *testing.T) {
+		handler, _, calls := newTestHandler(t, hello, WithBypassHeader("X-Cache-Bypass"))
+
+		serve(handler, http.MethodGet, "/hello")
+		rec := serve(handler, http.MethodGet, "/hello", "X-Cache-Bypass", "1")
+		assert.Empty(t, rec.Header().Get(XFromCache))
+		assert.Equal(t, int64(2), calls.Load())
+	}
+	t.Run("TestBypassHeader", TestBypassHeader)
+
+	TestRouteTTL := func(t *testing.T) {
+		handler, clock, calls := newTestHandler(t, hello,
+			WithRouteTTL("/", time.Hour),
+			WithRouteTTL("/api", time.Minute),
+		)
+
+		serve(handler, http.MethodGet, "/api/users")
+		serve(handler, http.MethodGet, "/index.html")
+
+		clock.Advance(time.Minute * 2)
+
+		serve(handler, http.MethodGet, "/api/users")
+		serve(handler, http.MethodGet, "/index.html")
+		assert.Equal(t, int64(3), calls.Load())
+	}
+	t.Run("TestRouteTTL", TestRouteTTL)
+
+	TestKeyHeaders := func(t *testing.T) {
+		handler, _, calls := newTestHandler(t, func(w http.ResponseWriter, r *http.Request) {
+			io.WriteString(w, r.Header.Get("Accept-Language"))
+		}, WithKeyHeaders("accept-language"))
+
+		assert.Equal(t, "en", serve(handler, http.MethodGet, "/", "Accept-Language", "en").Body.String())
+		assert.Equal(t, "nl", serve(handler, http.MethodGet, "/", "Accept-Language", "nl").Body.String())
+		assert.Equal(t, "en", serve(handler, http.MethodGet, "/", "Accept-Language", "en").Body.String())
+		assert.Equal(t, int64(2), calls.Load())
+	}
+	t.Run("TestKeyHeaders", TestKeyHeaders)
+
+	TestKeyFunc := func(t *testing.T) {
+		handler, _, calls := newTestHandler(t, hello, WithKeyFunc(func(r *http.Request) string {
+			return r.URL.Path
+		}))
+
+		serve(handler, http.MethodGet, "/hello?a=1")
+		serve(handler, http.MethodGet, "/hello?a=2")
+		assert.Equal(t, int64(1), calls.Load())
+	}
+	t.Run("TestKeyFunc", TestKeyFunc)
+}
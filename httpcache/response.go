@@ -0,0 +1,100 @@
+package httpcache
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// A stored response, fields are exported so the response can be encoded by a codec (e.g. for a Redis store).
+//
+// A CachedResponse is shared between requests and must not be modified.
+type CachedResponse struct {
+	StatusCode int
+	Header     http.Header
+	Body       []byte
+
+	// The request header values of the fields in the Vary header of the response.
+	VaryHeader http.Header
+
+	RequestTime  time.Time
+	ResponseTime time.Time
+}
+
+func newCachedResponse(req *http.Request, res *http.Response, body []byte, requestTime, responseTime time.Time) *CachedResponse {
+	varyHeader := make(http.Header)
+	for _, field := range varyFields(res.Header) {
+		varyHeader[field] = req.Header.Values(field)
+	}
+
+	return &CachedResponse{
+		StatusCode:   res.StatusCode,
+		Header:       res.Header.Clone(),
+		Body:         body,
+		VaryHeader:   varyHeader,
+		RequestTime:  requestTime,
+		ResponseTime: responseTime,
+	}
+}
+
+// Reports whether the request has the same values for the fields in the Vary header.
+func (r *CachedResponse) matches(req *http.Request) bool {
+	for field, values := range r.VaryHeader {
+		if strings.Join(req.Header.Values(field), ", ") != strings.Join(values, ", ") {
+			return false
+		}
+	}
+	return true
+}
+
+func (r *CachedResponse) age(now time.Time) time.Duration {
+	return initialAge(r.Header, r.RequestTime, r.ResponseTime) + now.Sub(r.ResponseTime)
+}
+
+func (r *CachedResponse) isFresh(now time.Time, shared bool) bool {
+	if parseCacheControl(r.Header).has("no-cache") {
+		return false
+	}
+	lifetime, _ := freshnessLifetime(r.Header, shared)
+	return lifetime > r.age(now)
+}
+
+func (r *CachedResponse) hasValidators() bool {
+	return r.Header.Get("ETag") != "" || r.Header.Get("Last-Modified") != ""
+}
+
+// Returns a copy with the headers of a 304 (Not Modified) response applied (RFC 9111 section 4.3.4).
+func (r *CachedResponse) revalidated(header http.Header, requestTime, responseTime time.Time) *CachedResponse {
+	updated := *r
+	updated.Header = r.Header.Clone()
+	for field, values := range header {
+		if field == "Content-Length" {
+			continue
+		}
+		updated.Header[field] = values
+	}
+	updated.RequestTime = requestTime
+	updated.ResponseTime = responseTime
+	return &updated
+}
+
+func (r *CachedResponse) response(req *http.Request, now time.Time) *http.Response {
+	header := r.Header.Clone()
+	header.Set("Age", strconv.FormatInt(int64(r.age(now)/time.Second), 10))
+	header.Set(XFromCache, "1")
+
+	return &http.Response{
+		Status:        strconv.Itoa(r.StatusCode) + " " + http.StatusText(r.StatusCode),
+		StatusCode:    r.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader(r.Body)),
+		ContentLength: int64(len(r.Body)),
+		Request:       req,
+	}
+}
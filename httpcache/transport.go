@@ -0,0 +1,238 @@
+// Package httpcache provides an http.RoundTripper that caches responses following the semantics of RFC 9111.
+package httpcache
+
+import (
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/larscom/go-cache"
+)
+
+// The header that is set to "1" on responses that are served from the cache.
+const XFromCache = "X-From-Cache"
+
+// Status codes that are cacheable by default (RFC 9110 section 15.1).
+var cacheableStatusCodes = map[int]bool{
+	http.StatusOK:                   true,
+	http.StatusNonAuthoritativeInfo: true,
+	http.StatusNoContent:            true,
+	http.StatusMultipleChoices:      true,
+	http.StatusMovedPermanently:     true,
+	http.StatusPermanentRedirect:    true,
+	http.StatusNotFound:             true,
+	http.StatusMethodNotAllowed:     true,
+	http.StatusGone:                 true,
+	http.StatusRequestURITooLong:    true,
+	http.StatusNotImplemented:       true,
+}
+
+type Option func(t *Transport)
+
+// The transport that sends the requests, defaults to http.DefaultTransport.
+func WithTransport(transport http.RoundTripper) Option {
+	return func(t *Transport) {
+		t.transport = transport
+	}
+}
+
+// Behave like a private cache (e.g. a cache per user), which also stores responses with 'Cache-Control: private'.
+//
+// By default the transport behaves like a shared cache, because a client is usually shared by all users of a service.
+func WithPrivateCache() Option {
+	return func(t *Transport) {
+		t.shared = false
+	}
+}
+
+// The clock used to calculate the age of responses, defaults to the system clock.
+func WithClock(clock cache.Clock) Option {
+	return func(t *Transport) {
+		t.now = clock.Now
+	}
+}
+
+// An http.RoundTripper that caches GET responses.
+//
+// Fresh responses (max-age, s-maxage, Expires) are served from the cache, stale responses with an ETag
+// or Last-Modified are revalidated with a conditional request. Responses with 'Cache-Control: no-store'
+// are never stored, the Vary header selects which request headers must match the stored response.
+// Successful unsafe requests (e.g. POST) invalidate the stored response of their URL.
+//
+// Bodies of stored responses are read into memory completely.
+type Transport struct {
+	cache     cache.Cache[string, *CachedResponse]
+	transport http.RoundTripper
+	now       func() time.Time
+	shared    bool
+}
+
+// Create a new caching transport on top of the cache.
+func NewTransport(c cache.Cache[string, *CachedResponse], opts ...Option) *Transport {
+	t := &Transport{
+		cache:     c,
+		transport: http.DefaultTransport,
+		now:       time.Now,
+		shared:    true,
+	}
+
+	for _, opt := range opts {
+		opt(t)
+	}
+
+	return t
+}
+
+// Returns a new http.Client that uses this transport.
+func (t *Transport) Client() *http.Client {
+	return &http.Client{Transport: t}
+}
+
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	key := cacheKey(req)
+
+	if req.Method != http.MethodGet {
+		res, err := t.transport.RoundTrip(req)
+		if err == nil && isUnsafe(req.Method) && res.StatusCode < 400 {
+			t.cache.Delete(key)
+		}
+		return res, err
+	}
+
+	reqCC := parseCacheControl(req.Header)
+	if reqCC.has("no-store") || hasConditionalHeaders(req) {
+		return t.transport.RoundTrip(req)
+	}
+
+	cached, found := t.cache.Get(key)
+	if found && !cached.matches(req) {
+		found = false
+	}
+
+	if found && !reqCC.has("no-cache") && cached.isFresh(t.now(), t.shared) {
+		return cached.response(req, t.now()), nil
+	}
+
+	if found && cached.hasValidators() {
+		return t.revalidate(req, key, cached)
+	}
+
+	requestTime := t.now()
+	res, err := t.transport.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	return t.store(req, key, res, requestTime, t.now())
+}
+
+// Sends a conditional request, a 304 (Not Modified) refreshes the stored response.
+func (t *Transport) revalidate(req *http.Request, key string, cached *CachedResponse) (*http.Response, error) {
+	conditional := req.Clone(req.Context())
+	if etag := cached.Header.Get("ETag"); etag != "" {
+		conditional.Header.Set("If-None-Match", etag)
+	}
+	if lastModified := cached.Header.Get("Last-Modified"); lastModified != "" {
+		conditional.Header.Set("If-Modified-Since", lastModified)
+	}
+
+	requestTime := t.now()
+	res, err := t.transport.RoundTrip(conditional)
+	if err != nil {
+		return nil, err
+	}
+	responseTime := t.now()
+
+	if res.StatusCode != http.StatusNotModified {
+		return t.store(req, key, res, requestTime, responseTime)
+	}
+
+	io.Copy(io.Discard, res.Body)
+	res.Body.Close()
+
+	updated := cached.revalidated(res.Header, requestTime, responseTime)
+	t.put(key, updated)
+
+	return updated.response(req, t.now()), nil
+}
+
+// Stores the response when it is storable and returns a response that can be read by the caller.
+func (t *Transport) store(req *http.Request, key string, res *http.Response, requestTime, responseTime time.Time) (*http.Response, error) {
+	if !t.isStorable(req, res) {
+		return res, nil
+	}
+
+	body, err := io.ReadAll(res.Body)
+	res.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	cached := newCachedResponse(req, res, body, requestTime, responseTime)
+	t.put(key, cached)
+
+	return cached.response(req, t.now()), nil
+}
+
+// Responses without validators are useless once stale, so they expire from the cache at that moment
+// (whenever the cache implements cache.TTLCache).
+func (t *Transport) put(key string, cached *CachedResponse) {
+	if cached.hasValidators() {
+		t.cache.Put(key, cached)
+		return
+	}
+	lifetime, _ := freshnessLifetime(cached.Header, t.shared)
+	ttl := lifetime - cached.age(t.now())
+	if ttl <= 0 {
+		return
+	}
+	if c, ok := t.cache.(cache.TTLCache[string, *CachedResponse]); ok {
+		c.PutWithTTL(key, cached, ttl)
+	} else {
+		t.cache.Put(key, cached)
+	}
+}
+
+// Reports whether the response may be stored (RFC 9111 section 3) and is worth storing.
+func (t *Transport) isStorable(req *http.Request, res *http.Response) bool {
+	if !cacheableStatusCodes[res.StatusCode] {
+		return false
+	}
+
+	resCC := parseCacheControl(res.Header)
+	if resCC.has("no-store") {
+		return false
+	}
+	if t.shared && resCC.has("private") {
+		return false
+	}
+	if t.shared && req.Header.Get("Authorization") != "" && !resCC.allowsAuthorization() {
+		return false
+	}
+	for _, field := range varyFields(res.Header) {
+		if field == "*" {
+			return false
+		}
+	}
+
+	lifetime, explicit := freshnessLifetime(res.Header, t.shared)
+	hasValidators := res.Header.Get("ETag") != "" || res.Header.Get("Last-Modified") != ""
+
+	return hasValidators || (explicit && lifetime > 0)
+}
+
+func cacheKey(req *http.Request) string {
+	return req.URL.String()
+}
+
+func isUnsafe(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace:
+		return false
+	}
+	return true
+}
+
+// Requests with their own validators are passed through, the caller handles the 304 itself.
+func hasConditionalHeaders(req *http.Request) bool {
+	return req.Header.Get("If-None-Match") != "" || req.Header.Get("If-Modified-Since") != ""
+}
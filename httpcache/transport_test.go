@@ -0,0 +1,266 @@
+package httpcache
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/larscom/go-cache"
+	"github.com/larscom/go-cache/cachetest"
+	"github.com/stretchr/testify/assert"
+)
+
+type testServer struct {
+	*httptest.Server
+	hits atomic.Int64
+}
+
+func newTestServer(t *testing.T, handler http.HandlerFunc) *testServer {
+	s := new(testServer)
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.hits.Add(1)
+		handler(w, r)
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func newTestClient(t *testing.T, opts ...Option) (*http.Client, *cachetest.FakeClock) {
+	clock := cachetest.NewFakeClock(time.Now())
+	c := cache.NewCache(cache.WithClock[string, *CachedResponse](clock))
+	t.Cleanup(c.Close)
+
+	return NewTransport(c, append(opts, WithClock(clock))...).Client(), clock
+}
+
+func get(t *testing.T, client *http.Client, url string, header ...string) (*http.Response, string) {
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	assert.NoError(t, err)
+	for i := 0; i+1 < len(header); i += 2 {
+		req.Header.Set(header[i], header[i+1])
+	}
+
+	res, err := client.Do(req)
+	assert.NoError(t, err)
+	defer res.Body.Close()
+
+	body, err := io.ReadAll(res.Body)
+	assert.NoError(t, err)
+	return res, string(body)
+}
+
+func TestTransport(t *testing.T) {
+	TestMaxAge := func(t *testing.T) {
+		server := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Cache-Control", "max-age=60")
+			io.WriteString(w, "Hello World")
+		})
+		client, clock := newTestClient(t)
+
+		res, body := get(t, client, server.URL)
+		assert.Equal(t, "Hello World", body)
+		assert.Equal(t, "1", res.Header.Get(XFromCache))
+
+		clock.Advance(time.Second * 30)
+
+		res, body = get(t, client, server.URL)
+		assert.Equal(t, "Hello World", body)
+		assert.Equal(t, "30", res.Header.Get("Age"))
+		assert.Equal(t, int64(1), server.hits.Load())
+
+		clock.Advance(time.Second * 31)
+
+		get(t, client, server.URL)
+		assert.Equal(t, int64(2), server.hits.Load())
+	}
+	t.Run("TestMaxAge", TestMaxAge)
+
+	TestExpires := func(t *testing.T) {
+		server := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+			now := time.Now().UTC()
+			w.Header().Set("Date", now.Format(http.TimeFormat))
+			w.Header().Set("Expires", now.Add(time.Minute).Format(http.TimeFormat))
+		})
+		client, _ := newTestClient(t)
+
+		get(t, client, server.URL)
+		get(t, client, server.URL)
+		assert.Equal(t, int64(1), server.hits.Load())
+	}
+	t.Run("TestExpires", TestExpires)
+
+	TestNotStored := func(t *testing.T) {
+		for _, cacheControl := range []string{"no-store", "private, max-age=60", ""} {
+			server := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Cache-Control", cacheControl)
+			})
+			client, _ := newTestClient(t)
+
+			get(t, client, server.URL)
+			res, _ := get(t, client, server.URL)
+			assert.Empty(t, res.Header.Get(XFromCache))
+			assert.Equal(t, int64(2), server.hits.Load(), cacheControl)
+		}
+	}
+	t.Run("TestNotStored", TestNotStored)
+
+	TestPrivateCache := func(t *testing.T) {
+		server := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Cache-Control", "private, max-age=60")
+		})
+		client, _ := newTestClient(t, WithPrivateCache())
+
+		get(t, client, server.URL)
+		get(t, client, server.URL)
+		assert.Equal(t, int64(1), server.hits.Load())
+	}
+	t.Run("TestPrivateCache", TestPrivateCache)
+
+	TestSharedMaxAge := func(t *testing.T) {
+		server := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Cache-Control", "max-age=60, s-maxage=0")
+		})
+		client, _ := newTestClient(t)
+
+		get(t, client, server.URL)
+		get(t, client, server.URL)
+		assert.Equal(t, int64(2), server.hits.Load())
+	}
+	t.Run("TestSharedMaxAge", TestSharedMaxAge)
+
+	TestRequestNoCache := func(t *testing.T) {
+		server := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Cache-Control", "max-age=60")
+		})
+		client, _ := newTestClient(t)
+
+		get(t, client, server.URL)
+		get(t, client, server.URL, "Cache-Control", "no-cache")
+		assert.Equal(t, int64(2), server.hits.Load())
+	}
+	t.Run("TestRequestNoCache", TestRequestNoCache)
+
+	TestETag := func(t *testing.T) {
+		var modified atomic.Int64
+		server := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Cache-Control", "no-cache")
+			w.Header().Set("ETag", `"v1"`)
+			if r.Header.Get("If-None-Match") == `"v1"` {
+				w.WriteHeader(http.StatusNotModified)
+				return
+			}
+			modified.Add(1)
+			io.WriteString(w, "Hello World")
+		})
+		client, _ := newTestClient(t)
+
+		get(t, client, server.URL)
+		res, body := get(t, client, server.URL)
+
+		assert.Equal(t, http.StatusOK, res.StatusCode)
+		assert.Equal(t, "Hello World", body)
+		assert.Equal(t, "1", res.Header.Get(XFromCache))
+		assert.Equal(t, int64(2), server.hits.Load())
+		assert.Equal(t, int64(1), modified.Load())
+	}
+	t.Run("TestETag", TestETag)
+
+	TestLastModified := func(t *testing.T) {
+		lastModified := time.Now().UTC().Add(-time.Hour).Format(http.TimeFormat)
+		client, clock := newTestClient(t)
+		server := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Date", clock.Now().UTC().Format(http.TimeFormat))
+			w.Header().Set("Cache-Control", "max-age=10")
+			w.Header().Set("Last-Modified", lastModified)
+			if r.Header.Get("If-Modified-Since") == lastModified {
+				w.Header().Set("X-Revalidated", "true")
+				w.WriteHeader(http.StatusNotModified)
+				return
+			}
+			io.WriteString(w, "Hello World")
+		})
+
+		get(t, client, server.URL)
+		clock.Advance(time.Second * 11)
+
+		res, body := get(t, client, server.URL)
+		assert.Equal(t, "Hello World", body)
+		assert.Equal(t, "true", res.Header.Get("X-Revalidated"))
+
+		// fresh again after revalidation
+		get(t, client, server.URL)
+		assert.Equal(t, int64(2), server.hits.Load())
+	}
+	t.Run("TestLastModified", TestLastModified)
+
+	TestChangedAfterRevalidation := func(t *testing.T) {
+		var version atomic.Int64
+		server := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+			etag := `"` + strings.Repeat("v", int(version.Add(1))) + `"`
+			w.Header().Set("Cache-Control", "no-cache")
+			w.Header().Set("ETag", etag)
+			io.WriteString(w, etag)
+		})
+		client, _ := newTestClient(t)
+
+		_, first := get(t, client, server.URL)
+		_, second := get(t, client, server.URL)
+		assert.NotEqual(t, first, second)
+	}
+	t.Run("TestChangedAfterRevalidation", TestChangedAfterRevalidation)
+
+	TestVary := func(t *testing.T) {
+		server := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Cache-Control", "max-age=60")
+			w.Header().Set("Vary", "Accept-Language")
+			io.WriteString(w, r.Header.Get("Accept-Language"))
+		})
+		client, _ := newTestClient(t)
+
+		_, body := get(t, client, server.URL, "Accept-Language", "en")
+		assert.Equal(t, "en", body)
+		_, body = get(t, client, server.URL, "Accept-Language", "en")
+		assert.Equal(t, "en", body)
+		assert.Equal(t, int64(1), server.hits.Load())
+
+		_, body = get(t, client, server.URL, "Accept-Language", "nl")
+		assert.Equal(t, "nl", body)
+		assert.Equal(t, int64(2), server.hits.Load())
+	}
+	t.Run("TestVary", TestVary)
+
+	TestUnsafeMethodInvalidates := func(t *testing.T) {
+		server := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Cache-Control", "max-age=60")
+		})
+		client, _ := newTestClient(t)
+
+		get(t, client, server.URL)
+
+		res, err := client.Post(server.URL, "text/plain", strings.NewReader("update"))
+		assert.NoError(t, err)
+		res.Body.Close()
+
+		get(t, client, server.URL)
+		assert.Equal(t, int64(3), server.hits.Load())
+	}
+	t.Run("TestUnsafeMethodInvalidates", TestUnsafeMethodInvalidates)
+}
+
+func TestParseCacheControl(t *testing.T) {
+	header := http.Header{}
+	header.Add("Cache-Control", `max-age=60, No-Cache`)
+	header.Add("Cache-Control", `private="Set-Cookie"`)
+
+	cc := parseCacheControl(header)
+	maxAge, found := cc.seconds("max-age")
+	assert.True(t, found)
+	assert.Equal(t, time.Minute, maxAge)
+	assert.True(t, cc.has("no-cache"))
+	assert.Equal(t, "Set-Cookie", cc["private"])
+	assert.False(t, cc.has("no-store"))
+}
@@ -0,0 +1,170 @@
+package cache
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"sync"
+)
+
+// Tells other caches to evict a key, or all items when 'All' is true.
+type Invalidation struct {
+	// Identifies the cache that published the invalidation.
+	Origin string `json:"origin"`
+
+	// The key encoded with the KeyCodec, empty when 'All' is true.
+	Key []byte `json:"key,omitempty"`
+
+	// Whether all items should be evicted.
+	All bool `json:"all,omitempty"`
+}
+
+// Broadcasts invalidations between caches (e.g. between replicas of a service).
+type InvalidationBus interface {
+	// Publish an invalidation to all subscribers, including the publisher itself.
+	Publish(ctx context.Context, invalidation Invalidation) error
+
+	// Calls fn for every published invalidation until the returned cancel function is called.
+	Subscribe(fn func(invalidation Invalidation)) (cancel func(), err error)
+}
+
+// Broadcast local changes ('Put', 'Delete' and 'Clear') to other caches and evict items that are changed by other caches.
+//
+// Invalidations received from other caches evict the item locally without broadcasting it again.
+// Values stored by 'Load' and 'Reload' are not broadcast. Publishing happens synchronously,
+// errors are passed to the error handler.
+func WithInvalidationBus[K comparable, V any](
+	bus InvalidationBus,
+	keyCodec KeyCodec[K],
+) Option[K, V] {
+	return func(c *cache[K, V]) {
+		c.invalidation = &invalidation[K]{
+			bus:      bus,
+			keyCodec: keyCodec,
+			origin:   newOrigin(),
+		}
+	}
+}
+
+type invalidation[K comparable] struct {
+	bus      InvalidationBus
+	keyCodec KeyCodec[K]
+	origin   string
+	cancel   func()
+}
+
+func (c *cache[K, V]) subscribeInvalidations() {
+	inv := c.invalidation
+	if inv == nil {
+		return
+	}
+
+	cancel, err := inv.bus.Subscribe(c.handleInvalidation)
+	if err != nil {
+		c.handleError(fmt.Errorf("subscribe invalidations: %w", err))
+		return
+	}
+	inv.cancel = cancel
+}
+
+func (c *cache[K, V]) unsubscribeInvalidations() {
+	if c.invalidation != nil && c.invalidation.cancel != nil {
+		c.invalidation.cancel()
+	}
+}
+
+// Evicts the item (or all items) that changed in another cache.
+func (c *cache[K, V]) handleInvalidation(invalidation Invalidation) {
+	if invalidation.Origin == c.invalidation.origin || c.closed.Load() {
+		return
+	}
+
+	if invalidation.All {
+		c.clear()
+		return
+	}
+
+	key, err := c.invalidation.keyCodec.DecodeKey(invalidation.Key)
+	if err != nil {
+		c.handleError(fmt.Errorf("decode invalidation key: %w", err))
+		return
+	}
+	c.remove(key)
+}
+
+func (c *cache[K, V]) broadcastKey(key K) {
+	if c.invalidation == nil {
+		return
+	}
+
+	data, err := c.invalidation.keyCodec.EncodeKey(key)
+	if err != nil {
+		c.handleError(fmt.Errorf("encode invalidation key %v: %w", key, err))
+		return
+	}
+	c.broadcast(Invalidation{Origin: c.invalidation.origin, Key: data})
+}
+
+func (c *cache[K, V]) broadcastAll() {
+	if c.invalidation == nil {
+		return
+	}
+	c.broadcast(Invalidation{Origin: c.invalidation.origin, All: true})
+}
+
+func (c *cache[K, V]) broadcast(invalidation Invalidation) {
+	if err := c.invalidation.bus.Publish(context.Background(), invalidation); err != nil {
+		c.handleError(fmt.Errorf("publish invalidation: %w", err))
+	}
+}
+
+func newOrigin() string {
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(id)
+}
+
+// An in-process InvalidationBus, e.g. to connect multiple caches in tests.
+//
+// Subscribers are called synchronously while publishing.
+type LocalBus struct {
+	mu          sync.RWMutex
+	subscribers map[*func(Invalidation)]struct{}
+}
+
+func NewLocalBus() *LocalBus {
+	return &LocalBus{
+		subscribers: make(map[*func(Invalidation)]struct{}),
+	}
+}
+
+func (b *LocalBus) Publish(_ context.Context, invalidation Invalidation) error {
+	b.mu.RLock()
+	subscribers := make([]func(Invalidation), 0, len(b.subscribers))
+	for fn := range b.subscribers {
+		subscribers = append(subscribers, *fn)
+	}
+	b.mu.RUnlock()
+
+	for _, fn := range subscribers {
+		fn(invalidation)
+	}
+	return nil
+}
+
+func (b *LocalBus) Subscribe(fn func(invalidation Invalidation)) (func(), error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	key := &fn
+	b.subscribers[key] = struct{}{}
+
+	return func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		delete(b.subscribers, key)
+	}, nil
+}
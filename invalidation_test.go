@@ -0,0 +1,92 @@
+package cache
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type countingBus struct {
+	*LocalBus
+	published atomic.Int32
+}
+
+func (b *countingBus) Publish(ctx context.Context, invalidation Invalidation) error {
+	b.published.Add(1)
+	return b.LocalBus.Publish(ctx, invalidation)
+}
+
+func TestInvalidationBus(t *testing.T) {
+	codec := JSONCodec[int, int]{}
+
+	TestPutAndDelete := func(t *testing.T) {
+		bus := &countingBus{LocalBus: NewLocalBus()}
+
+		a := NewCache(WithInvalidationBus[int, int](bus, codec))
+		defer a.Close()
+		b := NewCache(WithInvalidationBus[int, int](bus, codec))
+		defer b.Close()
+
+		b.Put(1, 100)
+		b.Put(2, 200)
+		assert.Equal(t, int32(2), bus.published.Load())
+
+		a.Put(1, 101)
+		assert.True(t, a.Has(1))
+		assert.False(t, b.Has(1))
+
+		a.Delete(2)
+		assert.False(t, b.Has(2))
+
+		// remote invalidations are not broadcast again
+		assert.Equal(t, int32(4), bus.published.Load())
+	}
+	t.Run("TestPutAndDelete", TestPutAndDelete)
+
+	TestClear := func(t *testing.T) {
+		bus := NewLocalBus()
+
+		a := NewCache(WithInvalidationBus[int, int](bus, codec))
+		defer a.Close()
+		b := NewCache(WithInvalidationBus[int, int](bus, codec))
+		defer b.Close()
+
+		a.Put(1, 100)
+		b.Put(2, 200)
+
+		a.Clear()
+
+		assert.True(t, a.IsEmpty())
+		assert.True(t, b.IsEmpty())
+	}
+	t.Run("TestClear", TestClear)
+
+	TestLoadIsNotBroadcast := func(t *testing.T) {
+		bus := NewLocalBus()
+
+		a := NewLoadingCache(func(key int) (int, error) {
+			return key, nil
+		}, WithInvalidationBus[int, int](bus, codec))
+		defer a.Close()
+		b := NewCache(WithInvalidationBus[int, int](bus, codec))
+		defer b.Close()
+
+		b.Put(1, 100)
+		a.Load(1)
+
+		assert.True(t, b.Has(1))
+	}
+	t.Run("TestLoadIsNotBroadcast", TestLoadIsNotBroadcast)
+
+	TestCloseUnsubscribes := func(t *testing.T) {
+		bus := NewLocalBus()
+
+		a := NewCache(WithInvalidationBus[int, int](bus, codec))
+		a.Close()
+
+		assert.Empty(t, bus.subscribers)
+	}
+	t.Run("TestCloseUnsubscribes", TestCloseUnsubscribes)
+}
@@ -0,0 +1,351 @@
+package cache
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const defaultJournalCompactSize = 64 << 20
+
+type journalOp byte
+
+const (
+	journalPut journalOp = iota + 1
+	journalDelete
+	journalClear
+)
+
+// Appends every change ('Put', 'Delete', 'Clear' and loaded values) to a journal file, so the cache
+// can be recovered after a crash.
+//
+// When the cache is created, the latest compacted snapshot ('path' + ".snapshot") and the journal are replayed.
+// Once the journal grows past the compact size (64 MiB by default), it is compacted into the snapshot in the background,
+// changes keep being appended to a new journal meanwhile.
+//
+// Every record has a checksum, a corrupted or partially written tail (e.g. after a crash) is detected and truncated
+// on replay. Records are written without fsync, so they survive a crash of the process but not of the machine.
+func WithJournal[K comparable, V any](
+	path string,
+	codec Codec[K, V],
+) Option[K, V] {
+	return func(c *cache[K, V]) {
+		c.journal = &journal[K, V]{
+			path:        path,
+			codec:       codec,
+			compactSize: defaultJournalCompactSize,
+		}
+	}
+}
+
+// The size in bytes after which the journal gets compacted, has no effect without 'WithJournal'.
+func WithJournalCompactSize[K comparable, V any](
+	compactSize int64,
+) Option[K, V] {
+	return func(c *cache[K, V]) {
+		c.journalCompactSize = compactSize
+	}
+}
+
+type journal[K comparable, V any] struct {
+	// guards the file and keeps the order of the records the same as the order of the changes
+	mu          sync.Mutex
+	file        *os.File
+	size        int64
+	path        string
+	codec       Codec[K, V]
+	compactSize int64
+	compacting  atomic.Bool
+	closing     bool
+	wg          sync.WaitGroup
+}
+
+func (j *journal[K, V]) snapshotPath() string {
+	return j.path + ".snapshot"
+}
+
+func (j *journal[K, V]) rotatedPath() string {
+	return j.path + ".rotated"
+}
+
+// Restores the snapshot, replays the journal and opens it for appending.
+func (c *cache[K, V]) openJournal() error {
+	j := c.journal
+	if c.journalCompactSize > 0 {
+		j.compactSize = c.journalCompactSize
+	}
+
+	snapshot, err := os.Open(j.snapshotPath())
+	if err == nil {
+		err = c.loadFrom(snapshot, j.codec)
+		snapshot.Close()
+		if err != nil {
+			return fmt.Errorf("restore snapshot %s: %w", j.snapshotPath(), err)
+		}
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+
+	// the journal that was rotated by a compaction that did not finish
+	rotated, err := os.Open(j.rotatedPath())
+	if err == nil {
+		_, err = c.replayJournal(rotated)
+		rotated.Close()
+		if err != nil {
+			c.handleError(fmt.Errorf("replay journal %s: %w", j.rotatedPath(), err))
+		}
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+
+	file, err := os.OpenFile(j.path, os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		return err
+	}
+
+	size, err := c.replayJournal(file)
+	if err != nil {
+		c.handleError(fmt.Errorf("replay journal %s: %w (truncated at %d bytes)", j.path, err, size))
+	}
+	if err := file.Truncate(size); err != nil {
+		file.Close()
+		return err
+	}
+	if _, err := file.Seek(size, io.SeekStart); err != nil {
+		file.Close()
+		return err
+	}
+
+	j.file = file
+	j.size = size
+
+	if rotated != nil {
+		if err := c.compactJournal(); err != nil {
+			c.handleError(fmt.Errorf("compact journal %s: %w", j.path, err))
+		}
+	}
+
+	return nil
+}
+
+// Applies all valid records and returns the size of the valid part of the journal.
+func (c *cache[K, V]) replayJournal(r io.Reader) (int64, error) {
+	br := bufio.NewReader(r)
+	header := make([]byte, 8)
+
+	var size int64
+	for {
+		if _, err := io.ReadFull(br, header); err != nil {
+			if err == io.EOF {
+				return size, nil
+			}
+			return size, err
+		}
+
+		length := binary.BigEndian.Uint32(header[:4])
+		checksum := binary.BigEndian.Uint32(header[4:])
+		if length > maxRecordFieldSize {
+			return size, errInvalidSnapshot
+		}
+
+		payload, err := readSized(br, int64(length))
+		if err != nil {
+			return size, err
+		}
+		if crc32.ChecksumIEEE(payload) != checksum {
+			return size, errors.New("checksum mismatch")
+		}
+		if err := c.applyJournalRecord(payload); err != nil {
+			return size, err
+		}
+
+		size += int64(len(header) + len(payload))
+	}
+}
+
+func (c *cache[K, V]) applyJournalRecord(payload []byte) error {
+	if len(payload) == 0 {
+		return errInvalidSnapshot
+	}
+
+	r := bufio.NewReader(bytes.NewReader(payload[1:]))
+	switch journalOp(payload[0]) {
+	case journalPut:
+		key, value, expireAt, err := readRecord(r, c.journal.codec)
+		if err != nil {
+			return noEOF(err)
+		}
+		created := newEntry(key, value, unixNano(expireAt))
+		if created.expireAt != 0 {
+			if c.now() >= created.expireAt {
+				c.data.Delete(key)
+				return nil
+			}
+			c.startCleaner()
+		}
+		c.data.Store(key, c.weaken(created))
+	case journalDelete:
+		keyData, err := readField(r)
+		if err != nil {
+			return noEOF(err)
+		}
+		key, err := c.journal.codec.DecodeKey(keyData)
+		if err != nil {
+			return fmt.Errorf("decode key: %w", err)
+		}
+		c.data.Delete(key)
+	case journalClear:
+		c.data.Clear()
+	default:
+		return errInvalidSnapshot
+	}
+
+	return nil
+}
+
+// Appends a put record, the journal lock must be held.
+func (c *cache[K, V]) journalPut(key K, value V, expireAt time.Time) {
+	var buf bytes.Buffer
+	buf.WriteByte(byte(journalPut))
+	if err := writeRecord(&buf, c.journal.codec, key, value, expireAt); err != nil {
+		c.handleError(fmt.Errorf("journal put: %w", err))
+		return
+	}
+	c.appendJournal(buf.Bytes())
+}
+
+// Appends a delete record, the journal lock must be held.
+func (c *cache[K, V]) journalDelete(key K) {
+	keyData, err := c.journal.codec.EncodeKey(key)
+	if err != nil {
+		c.handleError(fmt.Errorf("journal delete: encode key: %w", err))
+		return
+	}
+	payload := binary.AppendUvarint([]byte{byte(journalDelete)}, uint64(len(keyData)))
+	c.appendJournal(append(payload, keyData...))
+}
+
+// Appends a clear record, the journal lock must be held.
+func (c *cache[K, V]) journalClear() {
+	c.appendJournal([]byte{byte(journalClear)})
+}
+
+func (c *cache[K, V]) appendJournal(payload []byte) {
+	j := c.journal
+	if j.file == nil {
+		return
+	}
+
+	record := make([]byte, 8, 8+len(payload))
+	binary.BigEndian.PutUint32(record[:4], uint32(len(payload)))
+	binary.BigEndian.PutUint32(record[4:], crc32.ChecksumIEEE(payload))
+	record = append(record, payload...)
+
+	n, err := j.file.Write(record)
+	j.size += int64(n)
+	if err != nil {
+		c.handleError(fmt.Errorf("journal %s: %w", j.path, err))
+		return
+	}
+
+	if j.size > j.compactSize && !j.closing && j.compacting.CompareAndSwap(false, true) {
+		j.wg.Add(1)
+		go func() {
+			defer j.wg.Done()
+			defer j.compacting.Store(false)
+			if err := c.compactJournal(); err != nil {
+				c.handleError(fmt.Errorf("compact journal %s: %w", j.path, err))
+			}
+		}()
+	}
+}
+
+// Rotates the journal, writes all items to the snapshot and removes the rotated journal.
+//
+// Only the rotation holds the journal lock, so changes are appended to the new journal while the
+// snapshot is written. Replaying them on top of the snapshot is safe, every record overwrites the
+// state of its key.
+func (c *cache[K, V]) compactJournal() error {
+	if err := c.rotateJournal(); err != nil {
+		return err
+	}
+
+	j := c.journal
+	err := writeFileAtomic(j.snapshotPath(), func(w io.Writer) error {
+		return c.saveTo(w, j.codec)
+	})
+	if err != nil {
+		return err
+	}
+
+	return os.Remove(j.rotatedPath())
+}
+
+// Moves the journal aside and continues with an empty journal.
+//
+// The journal is kept whenever the previous compaction failed, the rotated journal still holds
+// changes that are not in the snapshot.
+func (c *cache[K, V]) rotateJournal() error {
+	j := c.journal
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if j.file == nil {
+		return nil
+	}
+	if _, err := os.Stat(j.rotatedPath()); err == nil {
+		return nil
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+
+	if err := os.Rename(j.path, j.rotatedPath()); err != nil {
+		return err
+	}
+	file, err := os.OpenFile(j.path, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0o644)
+	if err != nil {
+		return errors.Join(err, os.Rename(j.rotatedPath(), j.path))
+	}
+
+	if err := j.file.Close(); err != nil {
+		c.handleError(fmt.Errorf("close journal %s: %w", j.rotatedPath(), err))
+	}
+	j.file = file
+	j.size = 0
+
+	return nil
+}
+
+// Waits for a running compaction and closes the journal file.
+func (c *cache[K, V]) closeJournal() {
+	j := c.journal
+	if j == nil {
+		return
+	}
+
+	// no compaction can be started once closing
+	j.mu.Lock()
+	j.closing = true
+	j.mu.Unlock()
+
+	j.wg.Wait()
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if j.file != nil {
+		if err := j.file.Close(); err != nil {
+			c.handleError(fmt.Errorf("close journal %s: %w", j.path, err))
+		}
+		j.file = nil
+	}
+}
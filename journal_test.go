@@ -0,0 +1,222 @@
+package cache
+
+import (
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/larscom/go-cache/cachetest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestJournal(t *testing.T) {
+	codec := GobCodec[int, string]{}
+
+	TestReplay := func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "cache.journal")
+
+		cache := NewLoadingCache(func(key int) (string, error) {
+			return "loaded", nil
+		}, WithJournal(path, codec))
+
+		cache.Put(1, "one")
+		cache.Put(2, "two")
+		cache.Clear()
+		cache.Put(3, "three")
+		cache.Put(4, "four")
+		cache.Put(4, "FOUR")
+		cache.Delete(3)
+		cache.Load(5)
+		cache.Close()
+
+		restored := NewCache(WithJournal(path, codec))
+		defer restored.Close()
+
+		assert.Equal(t, 2, restored.Count())
+
+		value, _ := restored.Get(4)
+		assert.Equal(t, "FOUR", value)
+
+		value, _ = restored.Get(5)
+		assert.Equal(t, "loaded", value)
+	}
+	t.Run("TestReplay", TestReplay)
+
+	TestReplaySkipsExpired := func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "cache.journal")
+		clock := cachetest.NewFakeClock(time.Now())
+
+		cache := NewCache(
+			WithJournal(path, codec),
+			WithExpireAfterWrite[int, string](time.Minute),
+			WithClock[int, string](clock),
+		)
+		cache.Put(1, "one")
+		clock.Advance(time.Second * 30)
+		cache.Put(2, "two")
+		cache.Close()
+
+		clock.Advance(time.Second * 45)
+
+		restored := NewCache(WithJournal(path, codec), WithClock[int, string](clock))
+		defer restored.Close()
+
+		assert.Equal(t, 1, restored.Count())
+		assert.True(t, restored.Has(2))
+	}
+	t.Run("TestReplaySkipsExpired", TestReplaySkipsExpired)
+
+	TestTruncateCorruptedTail := func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "cache.journal")
+
+		cache := NewCache(WithJournal(path, codec))
+		cache.Put(1, "one")
+		cache.Close()
+
+		info, err := os.Stat(path)
+		assert.NoError(t, err)
+		validSize := info.Size()
+
+		cache = NewCache(WithJournal(path, codec))
+		cache.Put(2, "two")
+		cache.Close()
+
+		// simulate a crash halfway through writing the last record
+		assert.NoError(t, os.Truncate(path, validSize+5))
+
+		var handled error
+		restored := NewCache(
+			WithJournal(path, codec),
+			WithErrorHandler[int, string](func(err error) { handled = err }),
+		)
+		defer restored.Close()
+
+		assert.Error(t, handled)
+		assert.Equal(t, 1, restored.Count())
+		assert.True(t, restored.Has(1))
+
+		info, err = os.Stat(path)
+		assert.NoError(t, err)
+		assert.Equal(t, validSize, info.Size())
+	}
+	t.Run("TestTruncateCorruptedTail", TestTruncateCorruptedTail)
+
+	TestChecksumMismatch := func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "cache.journal")
+
+		cache := NewCache(WithJournal(path, codec))
+		cache.Put(1, "one")
+		cache.Put(2, "two")
+		cache.Close()
+
+		data, err := os.ReadFile(path)
+		assert.NoError(t, err)
+		data[len(data)-2] ^= 0xff
+		assert.NoError(t, os.WriteFile(path, data, 0o644))
+
+		var handled error
+		restored := NewCache(
+			WithJournal(path, codec),
+			WithErrorHandler[int, string](func(err error) { handled = err }),
+		)
+		defer restored.Close()
+
+		assert.ErrorContains(t, handled, "checksum mismatch")
+		assert.Equal(t, 1, restored.Count())
+		assert.True(t, restored.Has(1))
+	}
+	t.Run("TestChecksumMismatch", TestChecksumMismatch)
+
+	TestCompact := func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "cache.journal")
+
+		cache := NewCache(WithJournal(path, codec), WithJournalCompactSize[int, string](64))
+		for i := 0; i < 10; i++ {
+			cache.Put(i, "value")
+		}
+		cache.Close()
+
+		assert.FileExists(t, path+".snapshot")
+
+		restored := NewCache(WithJournal(path, codec))
+		defer restored.Close()
+
+		assert.Equal(t, 10, restored.Count())
+	}
+	t.Run("TestCompact", TestCompact)
+
+	TestCompactDoesNotBlockChanges := func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "cache.journal")
+		codec := &blockingCodec{blocked: make(chan struct{}), release: make(chan struct{})}
+
+		cache := NewCache(WithJournal[int, string](path, codec), WithJournalCompactSize[int, string](64))
+		defer cache.Close()
+
+		cache.Put(-1, "blocks the snapshot")
+		codec.armed.Store(true)
+		for i := 0; i < 10; i++ {
+			cache.Put(i, "value")
+		}
+
+		<-codec.blocked
+
+		putchn := make(chan struct{})
+		go func() {
+			cache.Put(100, "value")
+			close(putchn)
+		}()
+
+		select {
+		case <-putchn:
+		case <-time.After(time.Second):
+			t.Error("put is blocked by the compaction")
+		}
+		close(codec.release)
+	}
+	t.Run("TestCompactDoesNotBlockChanges", TestCompactDoesNotBlockChanges)
+
+	TestReplayRotatedJournal := func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "cache.journal")
+
+		cache := NewCache(WithJournal(path, codec))
+		cache.Put(1, "one")
+		cache.Put(2, "two")
+		cache.Close()
+
+		// simulate a crash after the journal has been rotated, before the snapshot has been written
+		assert.NoError(t, os.Rename(path, path+".rotated"))
+
+		cache = NewCache(WithJournal(path, codec))
+		cache.Delete(2)
+		cache.Put(3, "three")
+		cache.Close()
+
+		assert.NoFileExists(t, path+".rotated")
+
+		restored := NewCache(WithJournal(path, codec))
+		defer restored.Close()
+
+		assert.Equal(t, 2, restored.Count())
+		assert.True(t, restored.Has(1))
+		assert.True(t, restored.Has(3))
+	}
+	t.Run("TestReplayRotatedJournal", TestReplayRotatedJournal)
+}
+
+// Blocks encoding key -1 once armed, until released.
+type blockingCodec struct {
+	GobCodec[int, string]
+	blocked chan struct{}
+	release chan struct{}
+	armed   atomic.Bool
+}
+
+func (c *blockingCodec) EncodeKey(key int) ([]byte, error) {
+	if key == -1 && c.armed.CompareAndSwap(true, false) {
+		close(c.blocked)
+		<-c.release
+	}
+	return c.GobCodec.EncodeKey(key)
+}
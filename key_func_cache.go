@@ -0,0 +1,261 @@
+package cache
+
+import (
+	"context"
+	"io"
+	"runtime"
+	"time"
+)
+
+// Create a new cache for keys that are not comparable (e.g. []byte or a struct containing a slice).
+//
+// keyFn maps a key to the key under which it is stored, keys with the same result are the same item.
+// 'ForEach', 'SaveTo' and events return the original key.
+//
+// Only 'WithExpireAfterWrite', 'WithCleanupInterval', 'WithClock', 'WithErrorHandler', 'WithStore',
+// 'WithShardCount' and 'WithInitialCapacity' are supported, other options return ErrUnsupportedOption.
+func NewCacheWithKeyFunc[K any, V any](
+	keyFn func(key K) string,
+	options ...Option[string, V],
+) (Cache[K, V], error) {
+	c, err := newKeyFuncCache("NewCacheWithKeyFunc", keyFn, options)
+	if err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// Create a new loading cache for keys that are not comparable, see 'NewCacheWithKeyFunc'.
+func NewLoadingCacheWithKeyFunc[K any, V any](
+	keyFn func(key K) string,
+	loaderFunc LoaderFunc[K, V],
+	options ...Option[string, V],
+) (LoadingCache[K, V], error) {
+	c, err := newKeyFuncCache("NewLoadingCacheWithKeyFunc", keyFn, options)
+	if err != nil {
+		return nil, err
+	}
+	return &keyFuncLoadingCache[K, V]{
+		keyFuncCache: c,
+		loaderFunc:   loaderFunc,
+	}, nil
+}
+
+// The original key is stored next to the value.
+type keyedValue[K any, V any] struct {
+	key   K
+	value V
+}
+
+type keyFuncCache[K any, V any] struct {
+	c     *cache[string, keyedValue[K, V]]
+	keyFn func(key K) string
+}
+
+type keyFuncLoadingCache[K any, V any] struct {
+	*keyFuncCache[K, V]
+	loaderFunc LoaderFunc[K, V]
+}
+
+// The cleaner goroutine only references the underlying cache, see 'cacheHandle'.
+func newKeyFuncCache[K any, V any](
+	constructor string,
+	keyFn func(key K) string,
+	options []Option[string, V],
+) (*keyFuncCache[K, V], error) {
+	settings, err := newSettings(constructor, options, "WithStore", "WithShardCount", "WithInitialCapacity")
+	if err != nil {
+		return nil, err
+	}
+
+	c := &keyFuncCache[K, V]{
+		c: newCache(func(c *cache[string, keyedValue[K, V]]) {
+			c.expireAfterWrite.Store(settings.expireAfterWrite.Load())
+			c.cleanupInterval.Store(settings.cleanupInterval.Load())
+			c.clock = settings.clock
+			c.errorHandler = settings.errorHandler
+			c.storeType = settings.storeType
+			c.shardCount = settings.shardCount
+			c.initialCapacity = settings.initialCapacity
+		}),
+		keyFn: keyFn,
+	}
+	runtime.SetFinalizer(c, func(c *keyFuncCache[K, V]) {
+		c.c.Close()
+	})
+	return c, nil
+}
+
+func (c *keyFuncCache[K, V]) Get(key K) (V, bool) {
+	kv, found := c.c.Get(c.keyFn(key))
+	return kv.value, found
+}
+
+func (c *keyFuncCache[K, V]) Put(key K, value V) {
+	c.c.Put(c.keyFn(key), keyedValue[K, V]{key, value})
+}
+
+func (c *keyFuncCache[K, V]) TryPut(key K, value V) error {
+	return c.c.TryPut(c.keyFn(key), keyedValue[K, V]{key, value})
+}
+
+func (c *keyFuncCache[K, V]) PutWithTTL(key K, value V, ttl time.Duration) {
+	c.c.PutWithTTL(c.keyFn(key), keyedValue[K, V]{key, value}, ttl)
+}
+
+func (c *keyFuncCache[K, V]) TTL(key K) (time.Duration, bool) {
+	return c.c.TTL(c.keyFn(key))
+}
+
+func (c *keyFuncCache[K, V]) Has(key K) bool {
+	return c.c.Has(c.keyFn(key))
+}
+
+func (c *keyFuncCache[K, V]) IsEmpty() bool {
+	return c.c.IsEmpty()
+}
+
+func (c *keyFuncCache[K, V]) Count() int {
+	return c.c.Count()
+}
+
+func (c *keyFuncCache[K, V]) ForEach(fn func(key K, value V)) {
+	c.c.ForEach(func(_ string, kv keyedValue[K, V]) {
+		fn(kv.key, kv.value)
+	})
+}
+
+func (c *keyFuncCache[K, V]) Delete(key K) {
+	c.c.Delete(c.keyFn(key))
+}
+
+func (c *keyFuncCache[K, V]) TryDelete(key K) error {
+	return c.c.TryDelete(c.keyFn(key))
+}
+
+func (c *keyFuncCache[K, V]) Clear() {
+	c.c.Clear()
+}
+
+// The events are forwarded by a goroutine, so they are dropped for a full buffer like the events of 'Cache'.
+func (c *keyFuncCache[K, V]) Subscribe(buffer int) (<-chan Event[K, V], func()) {
+	source, cancel := c.c.Subscribe(buffer)
+
+	eventchn := make(chan Event[K, V], buffer)
+	go func() {
+		defer close(eventchn)
+		for event := range source {
+			select {
+			case eventchn <- keyFuncEvent(event):
+			default:
+			}
+		}
+	}()
+
+	return eventchn, cancel
+}
+
+func keyFuncEvent[K any, V any](event Event[string, keyedValue[K, V]]) Event[K, V] {
+	key := event.OldValue.key
+	if event.Type == EventPut || event.Type == EventUpdate {
+		key = event.NewValue.key
+	}
+	return Event[K, V]{
+		Type:     event.Type,
+		Key:      key,
+		OldValue: event.OldValue.value,
+		NewValue: event.NewValue.value,
+	}
+}
+
+func (c *keyFuncCache[K, V]) SaveTo(w io.Writer, codec Codec[K, V]) error {
+	if c.c.closed.Load() {
+		return ErrClosed
+	}
+
+	sw, err := NewSnapshotWriter(w, codec)
+	if err != nil {
+		return err
+	}
+
+	now := c.c.now()
+	c.c.data.Range(func(_ string, entry entry[string, keyedValue[K, V]]) (stop bool) {
+		if entry.isExpired(now) {
+			return false
+		}
+		err = sw.Write(entry.value.key, entry.value.value, entry.expireAtTime())
+		return err != nil
+	})
+	if err != nil {
+		return err
+	}
+
+	return sw.Flush()
+}
+
+func (c *keyFuncCache[K, V]) LoadFrom(r io.Reader, codec Codec[K, V]) error {
+	if c.c.closed.Load() {
+		return ErrClosed
+	}
+
+	sr, err := NewSnapshotReader(r, codec)
+	if err != nil {
+		return err
+	}
+
+	for {
+		key, value, expireAt, err := sr.Read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		created := newEntry(c.keyFn(key), keyedValue[K, V]{key, value}, unixNano(expireAt))
+		if created.expireAt != 0 {
+			if c.c.now() >= created.expireAt {
+				continue
+			}
+			c.c.startCleaner()
+		}
+		c.c.storeEntry(created)
+	}
+}
+
+func (c *keyFuncCache[K, V]) CleanUp() int {
+	return c.c.CleanUp()
+}
+
+func (c *keyFuncCache[K, V]) Close() {
+	c.c.Close()
+}
+
+func (c *keyFuncCache[K, V]) Shutdown(ctx context.Context) error {
+	return c.c.Shutdown(ctx)
+}
+
+func (c *keyFuncCache[K, V]) Policy() Policy {
+	return c.c.Policy()
+}
+
+// Makes 'GetOrLoad' call the loaderFunc only once in a concurrent environment.
+func (c *keyFuncCache[K, V]) loadWith(key K, loaderFunc LoaderFunc[K, V], ttl time.Duration) (V, error) {
+	kv, err := c.c.loadWith(c.keyFn(key), c.keyedLoader(key, loaderFunc), ttl)
+	return kv.value, err
+}
+
+func (c *keyFuncCache[K, V]) keyedLoader(key K, loaderFunc LoaderFunc[K, V]) LoaderFunc[string, keyedValue[K, V]] {
+	return func(string) (keyedValue[K, V], error) {
+		value, err := loaderFunc(key)
+		return keyedValue[K, V]{key, value}, err
+	}
+}
+
+func (c *keyFuncLoadingCache[K, V]) Load(key K) (V, error) {
+	return c.loadWith(key, c.loaderFunc, expireAfterWriteTTL)
+}
+
+func (c *keyFuncLoadingCache[K, V]) Reload(key K) (V, error) {
+	kv, err := c.c.reloadWith(c.keyFn(key), c.keyedLoader(key, c.loaderFunc))
+	return kv.value, err
+}
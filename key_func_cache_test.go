@@ -0,0 +1,254 @@
+package cache
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/larscom/go-cache/cachetest"
+	"github.com/stretchr/testify/assert"
+)
+
+// Returns the value, panics on err.
+func must[T any](value T, err error) T {
+	if err != nil {
+		panic(err)
+	}
+	return value
+}
+
+type query struct {
+	table string
+	ids   []int
+}
+
+func queryKey(q query) string {
+	return fmt.Sprint(q.table, q.ids)
+}
+
+func TestKeyFuncCache(t *testing.T) {
+	const defaultTTL = time.Minute
+
+	TestPutAndGet := func(t *testing.T) {
+		cache := must(NewCacheWithKeyFunc[[]byte, int](func(key []byte) string {
+			return string(key)
+		}))
+		defer cache.Close()
+
+		cache.Put([]byte("a"), 1)
+		assert.NoError(t, cache.TryPut([]byte("b"), 2))
+
+		value, found := cache.Get([]byte("a"))
+		assert.True(t, found)
+		assert.Equal(t, 1, value)
+
+		assert.True(t, cache.Has([]byte("b")))
+		assert.Equal(t, 2, cache.Count())
+
+		cache.Delete([]byte("a"))
+		assert.False(t, cache.Has([]byte("a")))
+
+		cache.Clear()
+		assert.True(t, cache.IsEmpty())
+	}
+	t.Run("TestPutAndGet", TestPutAndGet)
+
+	TestForEachReturnsOriginalKey := func(t *testing.T) {
+		cache := must(NewCacheWithKeyFunc[query, string](queryKey))
+		defer cache.Close()
+
+		q := query{table: "users", ids: []int{1, 2}}
+		cache.Put(q, "result")
+
+		cache.ForEach(func(key query, value string) {
+			assert.Equal(t, q, key)
+			assert.Equal(t, "result", value)
+		})
+	}
+	t.Run("TestForEachReturnsOriginalKey", TestForEachReturnsOriginalKey)
+
+	TestExpireAfterWrite := func(t *testing.T) {
+		clock := cachetest.NewFakeClock(time.Now())
+		cache := must(NewCacheWithKeyFunc(
+			queryKey,
+			WithExpireAfterWrite[string, string](defaultTTL),
+			WithClock[string, string](clock),
+		))
+		defer cache.Close()
+
+		q := query{table: "users", ids: []int{1}}
+		cache.Put(q, "result")
+		cache.(TTLCache[query, string]).PutWithTTL(query{table: "orders"}, "result", 0)
+
+		ttl, found := cache.(TTLCache[query, string]).TTL(q)
+		assert.True(t, found)
+		assert.Equal(t, defaultTTL, ttl)
+		assert.Equal(t, defaultTTL, cache.Policy().ExpireAfterWrite())
+
+		clock.Advance(defaultTTL * 2)
+
+		assert.False(t, cache.Has(q))
+		assert.Equal(t, 1, cache.Count())
+	}
+	t.Run("TestExpireAfterWrite", TestExpireAfterWrite)
+
+	TestEvents := func(t *testing.T) {
+		cache := must(NewCacheWithKeyFunc[query, string](queryKey))
+
+		eventchn, _ := cache.Subscribe(10)
+
+		q := query{table: "users", ids: []int{1}}
+		cache.Put(q, "a")
+		cache.Put(q, "b")
+		cache.Delete(q)
+		cache.Clear()
+		cache.Close()
+
+		events := make([]Event[query, string], 0)
+		for event := range eventchn {
+			events = append(events, event)
+		}
+
+		assert.Equal(t, []Event[query, string]{
+			{Type: EventPut, Key: q, NewValue: "a"},
+			{Type: EventUpdate, Key: q, OldValue: "a", NewValue: "b"},
+			{Type: EventDelete, Key: q, OldValue: "b"},
+			{Type: EventClear},
+		}, events)
+	}
+	t.Run("TestEvents", TestEvents)
+
+	TestSaveToAndLoadFrom := func(t *testing.T) {
+		codec := GobCodec[[]int, string]{}
+		keyFn := func(key []int) string { return fmt.Sprint(key) }
+
+		cache := must(NewCacheWithKeyFunc[[]int, string](keyFn))
+		defer cache.Close()
+
+		cache.Put([]int{1, 2}, "a")
+		cache.(TTLCache[[]int, string]).PutWithTTL([]int{3}, "b", defaultTTL)
+
+		var buf bytes.Buffer
+		assert.NoError(t, cache.SaveTo(&buf, codec))
+
+		restored := must(NewCacheWithKeyFunc[[]int, string](keyFn))
+		defer restored.Close()
+
+		assert.NoError(t, restored.LoadFrom(&buf, codec))
+
+		value, found := restored.Get([]int{1, 2})
+		assert.True(t, found)
+		assert.Equal(t, "a", value)
+
+		ttl, found := restored.(TTLCache[[]int, string]).TTL([]int{3})
+		assert.True(t, found)
+		assert.InDelta(t, defaultTTL, ttl, float64(time.Second))
+	}
+	t.Run("TestSaveToAndLoadFrom", TestSaveToAndLoadFrom)
+
+	TestGetOrLoad := func(t *testing.T) {
+		cache := must(NewCacheWithKeyFunc[[]byte, string](func(key []byte) string {
+			return string(key)
+		}))
+		defer cache.Close()
+
+		var calls atomic.Int32
+		loaderFunc := func(key []byte) (string, error) {
+			calls.Add(1)
+			time.Sleep(time.Millisecond * 10)
+			return string(key), nil
+		}
+
+		var wg sync.WaitGroup
+		for range 10 {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				value, err := GetOrLoad(cache, []byte("a"), loaderFunc)
+				assert.NoError(t, err)
+				assert.Equal(t, "a", value)
+			}()
+		}
+		wg.Wait()
+
+		assert.Equal(t, int32(1), calls.Load())
+	}
+	t.Run("TestGetOrLoad", TestGetOrLoad)
+
+	TestUnsupportedOption := func(t *testing.T) {
+		_, err := NewCacheWithKeyFunc(queryKey, WithJournal(filepath.Join(t.TempDir(), "journal"), GobCodec[string, string]{}))
+		assert.ErrorIs(t, err, ErrUnsupportedOption)
+		assert.EqualError(t, err, "unsupported option: NewCacheWithKeyFunc does not support WithJournal")
+
+		_, err = NewLoadingCacheWithKeyFunc(queryKey, func(q query) (string, error) {
+			return q.table, nil
+		}, WithWriteBehind[string, string](nil, 0, 0))
+		assert.EqualError(t, err, "unsupported option: NewLoadingCacheWithKeyFunc does not support WithWriteBehind")
+
+		cache, err := NewCacheWithKeyFunc(queryKey, WithShardCount[string, string](4), WithInitialCapacity[string, string](8))
+		assert.NoError(t, err)
+		cache.Close()
+	}
+	t.Run("TestUnsupportedOption", TestUnsupportedOption)
+}
+
+func TestKeyFuncLoadingCache(t *testing.T) {
+	TestLoad := func(t *testing.T) {
+		var calls atomic.Int32
+		cache := must(NewLoadingCacheWithKeyFunc(queryKey, func(q query) (int, error) {
+			calls.Add(1)
+			return len(q.ids), nil
+		}))
+		defer cache.Close()
+
+		q := query{table: "users", ids: []int{1, 2, 3}}
+
+		value, err := cache.Load(q)
+		assert.NoError(t, err)
+		assert.Equal(t, 3, value)
+
+		value, err = cache.Load(q)
+		assert.NoError(t, err)
+		assert.Equal(t, 3, value)
+		assert.Equal(t, int32(1), calls.Load())
+
+		value, err = cache.Reload(q)
+		assert.NoError(t, err)
+		assert.Equal(t, 3, value)
+		assert.Equal(t, int32(2), calls.Load())
+
+		cache.ForEach(func(key query, value int) {
+			assert.Equal(t, q, key)
+		})
+	}
+	t.Run("TestLoad", TestLoad)
+
+	TestLoadError := func(t *testing.T) {
+		errLoad := errors.New("load failed")
+		cache := must(NewLoadingCacheWithKeyFunc(queryKey, func(q query) (int, error) {
+			return 0, errLoad
+		}))
+		defer cache.Close()
+
+		_, err := cache.Load(query{table: "users"})
+		assert.ErrorIs(t, err, errLoad)
+		assert.True(t, cache.IsEmpty())
+	}
+	t.Run("TestLoadError", TestLoadError)
+
+	TestClosed := func(t *testing.T) {
+		cache := must(NewLoadingCacheWithKeyFunc(queryKey, func(q query) (int, error) {
+			return 1, nil
+		}))
+		cache.Close()
+
+		_, err := cache.Load(query{table: "users"})
+		assert.ErrorIs(t, err, ErrClosed)
+	}
+	t.Run("TestClosed", TestClosed)
+}
@@ -1,27 +1,31 @@
 package cache
 
-import (
-	"time"
+import "time"
 
-	csmap "github.com/mhmtszr/concurrent-swiss-map"
-)
+// Stores loaded values using 'WithExpireAfterWrite' instead of a 'TTL' per item.
+const expireAfterWriteTTL = time.Duration(-1)
 
 // Function that gets executed by the 'Load' and 'Reload' function
-type LoaderFunc[K comparable, V any] func(key K) (V, error)
+type LoaderFunc[K any, V any] func(key K) (V, error)
 
-type LoadingCache[K comparable, V any] interface {
+type LoadingCache[K any, V any] interface {
 	// Loads an item into cache using the provided LoaderFunc and returns the value.
 	//
 	// If the item is already cached, it'll return that value instead.
 	//
 	// Whenever the LoaderFunc returns an error, the value does NOT get saved.
 	//
-	// This function is thread-safe and the LoaderFunc is called only once in a concurrent environment.
+	// This function is thread-safe and the LoaderFunc is called only once in a concurrent environment,
+	// concurrent calls for the same key share its value and error.
+	//
+	// Returns ErrClosed when the cache has been closed.
 	Load(key K) (V, error)
 
 	// Reloads an item into cache using the provided LoaderFunc and returns the new value.
 	//
 	// Whenever the LoaderFunc returns an error, the value does NOT get saved (old value remains in cache)
+	//
+	// Returns ErrClosed when the cache has been closed.
 	Reload(key K) (V, error)
 
 	// Embed Cache
@@ -32,13 +36,95 @@ func NewLoadingCache[K comparable, V any](
 	loaderFunc LoaderFunc[K, V],
 	options ...Option[K, V],
 ) LoadingCache[K, V] {
-	data := csmap.Create[K, *entry[K, V]]()
-	cleaner := newCacheCleaner(data, time.Second*5)
 	opts := append(options, withLoaderFunc(loaderFunc))
-	return newCache(data, cleaner, opts...)
+	return newCacheHandle(newCache(opts...))
+}
+
+// Returns the cached value or loads it using loaderFunc and puts it into cache.
+//
+// Whenever c is created by this package, the loaderFunc is called only once in a concurrent
+// environment and concurrent calls share its value and error (like 'Load'). Other Cache
+// implementations don't coalesce concurrent calls.
+//
+// Whenever the loaderFunc returns an error, the value does NOT get saved.
+func GetOrLoad[K any, V any](c Cache[K, V], key K, loaderFunc LoaderFunc[K, V]) (V, error) {
+	return getOrLoad(c, key, loaderFunc, expireAfterWriteTTL)
+}
+
+// Like 'GetOrLoad', the loaded value expires after ttl (see 'TTLCache').
+//
+// Whenever c does not implement TTLCache, the loaded value is put without a 'TTL'.
+func GetOrLoadWithTTL[K any, V any](c Cache[K, V], key K, ttl time.Duration, loaderFunc LoaderFunc[K, V]) (V, error) {
+	return getOrLoad(c, key, loaderFunc, max(ttl, 0))
+}
+
+type loaderWith[K any, V any] interface {
+	loadWith(key K, loaderFunc LoaderFunc[K, V], ttl time.Duration) (V, error)
+}
+
+func getOrLoad[K any, V any](c Cache[K, V], key K, loaderFunc LoaderFunc[K, V], ttl time.Duration) (V, error) {
+	if loader, ok := c.(loaderWith[K, V]); ok {
+		return loader.loadWith(key, loaderFunc, ttl)
+	}
+
+	if value, found := c.Get(key); found {
+		return value, nil
+	}
+	value, err := loaderFunc(key)
+	if err != nil {
+		return value, err
+	}
+	if ttlCache, ok := c.(TTLCache[K, V]); ok && ttl != expireAfterWriteTTL {
+		ttlCache.PutWithTTL(key, value, ttl)
+	} else {
+		c.Put(key, value)
+	}
+	return value, nil
 }
 
 func (c *cache[K, V]) Load(key K) (V, error) {
+	return c.loadWith(key, c.loaderFunc, expireAfterWriteTTL)
+}
+
+// A load that concurrent callers of 'loadWith' wait for.
+type loadCall[V any] struct {
+	done  chan struct{}
+	value V
+	err   error
+}
+
+func (c *cache[K, V]) loadWith(key K, loaderFunc LoaderFunc[K, V], ttl time.Duration) (V, error) {
+	if ttl > 0 {
+		c.startCleaner()
+	}
+	// cached items don't need the key lock
+	if cached, found := c.get(key); found {
+		return cached, nil
+	}
+
+	call := &loadCall[V]{done: make(chan struct{})}
+	if inflight, loading := c.loadCalls.LoadOrStore(key, call); loading {
+		call = inflight.(*loadCall[V])
+		<-call.done
+		return call.value, call.err
+	}
+	defer func() {
+		c.loadCalls.Delete(key)
+		close(call.done)
+	}()
+
+	call.value, call.err = c.load(key, loaderFunc, ttl)
+	return call.value, call.err
+}
+
+func (c *cache[K, V]) load(key K, loaderFunc LoaderFunc[K, V], ttl time.Duration) (V, error) {
+	if !c.beginLoad() {
+		var empty V
+		return empty, ErrClosed
+	}
+	defer c.loads.Done()
+
+	// a reload or write-through change of the key might be in progress
 	unlock := c.mu.lock(key)
 	defer unlock()
 
@@ -47,26 +133,58 @@ func (c *cache[K, V]) Load(key K) (V, error) {
 		return cached, nil
 	}
 
-	value, err := c.loaderFunc(key)
+	value, err := loaderFunc(key)
 	if err == nil {
-		c.data.Store(key, c.newEntry(key, value))
+		c.storeLoaded(c.newEntryWithTTL(key, value, ttl))
 	}
 
 	return value, err
 }
 
 func (c *cache[K, V]) Reload(key K) (V, error) {
+	return c.reloadWith(key, c.loaderFunc)
+}
+
+func (c *cache[K, V]) reloadWith(key K, loaderFunc LoaderFunc[K, V]) (V, error) {
+	if !c.beginLoad() {
+		var empty V
+		return empty, ErrClosed
+	}
+	defer c.loads.Done()
+
 	unlock := c.mu.lock(key)
 	defer unlock()
 
-	value, err := c.loaderFunc(key)
+	value, err := loaderFunc(key)
 	if err == nil {
-		c.data.Store(key, c.newEntry(key, value))
+		c.storeLoaded(c.newEntry(key, value))
 	}
 
 	return value, err
 }
 
+// Registers an in-flight load, returns false if the cache has been closed.
+func (c *cache[K, V]) beginLoad() bool {
+	c.lifecycle.RLock()
+	defer c.lifecycle.RUnlock()
+
+	if c.closed.Load() {
+		return false
+	}
+	c.loads.Add(1)
+	return true
+}
+
+// Stores a loaded value, unless the cache got closed while loading.
+func (c *cache[K, V]) storeLoaded(created entry[K, V]) {
+	c.lifecycle.RLock()
+	defer c.lifecycle.RUnlock()
+
+	if !c.closed.Load() {
+		c.storeEntry(created)
+	}
+}
+
 // Function that can be used inside a testing environment
 func NoopLoaderFunc[K comparable, V any](key K) (V, error) {
 	var empty V
@@ -1,6 +1,7 @@
 package cache
 
 import (
+	"context"
 	"fmt"
 	"log/slog"
 	"sync"
@@ -8,6 +9,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/larscom/go-cache/cachetest"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -49,7 +51,8 @@ func TestLoadingCache(t *testing.T) {
 	t.Run("TestLoadError", TestLoadError)
 
 	TestLoadWithExpireAfterWrite := func(t *testing.T) {
-		cache := NewLoadingCache(defaultLoaderFunc, WithExpireAfterWrite[int, int](defaultTTL))
+		clock := cachetest.NewFakeClock(time.Now())
+		cache := NewLoadingCache(defaultLoaderFunc, WithExpireAfterWrite[int, int](defaultTTL), WithClock[int, int](clock))
 		defer cache.Close()
 
 		const key = 1
@@ -59,7 +62,7 @@ func TestLoadingCache(t *testing.T) {
 		assert.Equal(t, 2, value)
 		assert.True(t, cache.Has(key))
 
-		<-time.After(defaultTTL + 5)
+		clock.Advance(defaultTTL + 5)
 		assert.False(t, cache.Has(key))
 	}
 	t.Run("TestLoadWithExpireAfterWrite", TestLoadWithExpireAfterWrite)
@@ -97,6 +100,45 @@ func TestLoadingCache(t *testing.T) {
 	}
 	t.Run("TestLoadCalledOnceInConcurrentEnvironment", TestLoadCalledOnceInConcurrentEnvironment)
 
+	TestLoadErrorSharedInConcurrentEnvironment := func(t *testing.T) {
+		counter := int64(0)
+		started := make(chan struct{})
+		release := make(chan struct{})
+
+		loaderFunc := func(key int) (int, error) {
+			atomic.AddInt64(&counter, 1)
+			close(started)
+			<-release
+			return 0, fmt.Errorf("got error on key: %d", key)
+		}
+		cache := NewLoadingCache(loaderFunc)
+		defer cache.Close()
+
+		errs := make(chan error, 5)
+		go func() {
+			_, err := cache.Load(100)
+			errs <- err
+		}()
+		<-started
+
+		for i := 0; i < 4; i++ {
+			go func() {
+				_, err := cache.Load(100)
+				errs <- err
+			}()
+		}
+		// the other loads wait for the first one
+		time.Sleep(time.Millisecond * 20)
+		close(release)
+
+		for i := 0; i < 5; i++ {
+			assert.EqualError(t, <-errs, "got error on key: 100")
+		}
+		assert.Equal(t, int64(1), atomic.LoadInt64(&counter))
+		assert.Zero(t, cache.Count())
+	}
+	t.Run("TestLoadErrorSharedInConcurrentEnvironment", TestLoadErrorSharedInConcurrentEnvironment)
+
 	TestLoadCalledTwiceInConcurrentEnvironment := func(t *testing.T) {
 		counter := int64(0)
 
@@ -180,7 +222,8 @@ func TestLoadingCache(t *testing.T) {
 	t.Run("TestReloadError", TestReloadError)
 
 	TestReloadWithExpireAfterWrite := func(t *testing.T) {
-		cache := NewLoadingCache(defaultLoaderFunc, WithExpireAfterWrite[int, int](defaultTTL))
+		clock := cachetest.NewFakeClock(time.Now())
+		cache := NewLoadingCache(defaultLoaderFunc, WithExpireAfterWrite[int, int](defaultTTL), WithClock[int, int](clock))
 		defer cache.Close()
 
 		const key = 1
@@ -190,7 +233,7 @@ func TestLoadingCache(t *testing.T) {
 		assert.Equal(t, 2, value)
 		assert.True(t, cache.Has(key))
 
-		<-time.After(defaultTTL + 5)
+		clock.Advance(defaultTTL + 5)
 		assert.False(t, cache.Has(key))
 	}
 	t.Run("TestReloadWithExpireAfterWrite", TestReloadWithExpireAfterWrite)
@@ -214,7 +257,8 @@ func TestLoadingCache(t *testing.T) {
 	t.Run("TestGet", TestGet)
 
 	TestGetWithExpireAfterWrite := func(t *testing.T) {
-		cache := NewLoadingCache(defaultLoaderFunc, WithExpireAfterWrite[int, int](defaultTTL))
+		clock := cachetest.NewFakeClock(time.Now())
+		cache := NewLoadingCache(defaultLoaderFunc, WithExpireAfterWrite[int, int](defaultTTL), WithClock[int, int](clock))
 		defer cache.Close()
 
 		const key = 1
@@ -225,7 +269,7 @@ func TestLoadingCache(t *testing.T) {
 		assert.True(t, found)
 		assert.Equal(t, 100, value)
 
-		<-time.After(defaultTTL + 5)
+		clock.Advance(defaultTTL + 5)
 
 		value, found = cache.Get(key)
 		assert.False(t, found)
@@ -251,7 +295,8 @@ func TestLoadingCache(t *testing.T) {
 	t.Run("TestPut", TestPut)
 
 	TestPutWithExpireAfterWrite := func(t *testing.T) {
-		cache := NewLoadingCache(defaultLoaderFunc, WithExpireAfterWrite[int, int](defaultTTL))
+		clock := cachetest.NewFakeClock(time.Now())
+		cache := NewLoadingCache(defaultLoaderFunc, WithExpireAfterWrite[int, int](defaultTTL), WithClock[int, int](clock))
 		defer cache.Close()
 
 		const key = 1
@@ -259,7 +304,7 @@ func TestLoadingCache(t *testing.T) {
 		cache.Put(key, 100)
 		assert.True(t, cache.Has(key))
 
-		<-time.After(defaultTTL + 5)
+		clock.Advance(defaultTTL + 5)
 
 		assert.False(t, cache.Has(key))
 	}
@@ -278,7 +323,8 @@ func TestLoadingCache(t *testing.T) {
 	t.Run("TestHas", TestHas)
 
 	TestHasWithExpireAfterWrite := func(t *testing.T) {
-		cache := NewLoadingCache(defaultLoaderFunc, WithExpireAfterWrite[int, int](defaultTTL))
+		clock := cachetest.NewFakeClock(time.Now())
+		cache := NewLoadingCache(defaultLoaderFunc, WithExpireAfterWrite[int, int](defaultTTL), WithClock[int, int](clock))
 		defer cache.Close()
 
 		const key = 1
@@ -286,7 +332,7 @@ func TestLoadingCache(t *testing.T) {
 		cache.Put(key, 100)
 		assert.True(t, cache.Has(key))
 
-		<-time.After(defaultTTL + 5)
+		clock.Advance(defaultTTL + 5)
 
 		assert.False(t, cache.Has(key))
 	}
@@ -307,13 +353,14 @@ func TestLoadingCache(t *testing.T) {
 	t.Run("TestIsEmpty", TestIsEmpty)
 
 	TestIsEmptyWithExpireAfterWrite := func(t *testing.T) {
-		cache := NewLoadingCache(defaultLoaderFunc, WithExpireAfterWrite[int, int](defaultTTL))
+		clock := cachetest.NewFakeClock(time.Now())
+		cache := NewLoadingCache(defaultLoaderFunc, WithExpireAfterWrite[int, int](defaultTTL), WithClock[int, int](clock))
 		defer cache.Close()
 
 		cache.Put(1, 100)
 		assert.False(t, cache.IsEmpty())
 
-		<-time.After(defaultTTL + 5)
+		clock.Advance(defaultTTL + 5)
 		assert.True(t, cache.IsEmpty())
 	}
 	t.Run("TestIsEmptyWithExpireAfterWrite", TestIsEmptyWithExpireAfterWrite)
@@ -331,7 +378,8 @@ func TestLoadingCache(t *testing.T) {
 	t.Run("TestCount", TestCount)
 
 	TestCountWithExpireAfterWrite := func(t *testing.T) {
-		cache := NewLoadingCache(defaultLoaderFunc, WithExpireAfterWrite[int, int](defaultTTL))
+		clock := cachetest.NewFakeClock(time.Now())
+		cache := NewLoadingCache(defaultLoaderFunc, WithExpireAfterWrite[int, int](defaultTTL), WithClock[int, int](clock))
 		defer cache.Close()
 
 		for i := 0; i < 5; i++ {
@@ -339,7 +387,7 @@ func TestLoadingCache(t *testing.T) {
 		}
 		assert.Equal(t, 5, cache.Count())
 
-		<-time.After(defaultTTL + 5)
+		clock.Advance(defaultTTL + 5)
 
 		assert.Zero(t, cache.Count())
 	}
@@ -369,11 +417,12 @@ func TestLoadingCache(t *testing.T) {
 	t.Run("TestForEach", TestForEach)
 
 	TestForEachWithExpireAfterWrite := func(t *testing.T) {
-		cache := NewLoadingCache(defaultLoaderFunc, WithExpireAfterWrite[int, int](defaultTTL))
+		clock := cachetest.NewFakeClock(time.Now())
+		cache := NewLoadingCache(defaultLoaderFunc, WithExpireAfterWrite[int, int](defaultTTL), WithClock[int, int](clock))
 		defer cache.Close()
 
 		cache.Put(1, 100)
-		<-time.After(defaultTTL + 5)
+		clock.Advance(defaultTTL + 5)
 
 		cache.Put(2, 200)
 
@@ -439,4 +488,128 @@ func TestLoadingCache(t *testing.T) {
 		assert.Zero(t, cache.Count())
 	}
 	t.Run("TestCloseShouldClear", TestCloseShouldClear)
+
+	TestLoadAfterClose := func(t *testing.T) {
+		cache := NewLoadingCache(defaultLoaderFunc)
+		cache.Close()
+
+		_, err := cache.Load(1)
+		assert.ErrorIs(t, err, ErrClosed)
+
+		_, err = cache.Reload(1)
+		assert.ErrorIs(t, err, ErrClosed)
+	}
+	t.Run("TestLoadAfterClose", TestLoadAfterClose)
+
+	TestShutdownWaitsForLoad := func(t *testing.T) {
+		started := make(chan struct{})
+		release := make(chan struct{})
+
+		cache := NewLoadingCache(func(key int) (int, error) {
+			close(started)
+			<-release
+			return key, nil
+		})
+
+		loaded := make(chan error)
+		go func() {
+			_, err := cache.Load(1)
+			loaded <- err
+		}()
+		<-started
+
+		ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*10)
+		defer cancel()
+		assert.ErrorIs(t, cache.Shutdown(ctx), context.DeadlineExceeded)
+
+		close(release)
+		assert.NoError(t, <-loaded)
+		assert.NoError(t, cache.Shutdown(context.Background()))
+
+		assert.False(t, cache.Has(1))
+	}
+	t.Run("TestShutdownWaitsForLoad", TestShutdownWaitsForLoad)
+}
+
+func TestGetOrLoad(t *testing.T) {
+	const defaultTTL = time.Millisecond * 30
+
+	TestLoadOnce := func(t *testing.T) {
+		cache := NewCache[int, int]()
+		defer cache.Close()
+
+		var calls atomic.Int32
+		var wg sync.WaitGroup
+		for i := 0; i < 10; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				value, err := GetOrLoad(cache, 1, func(key int) (int, error) {
+					calls.Add(1)
+					time.Sleep(time.Millisecond)
+					return key * 100, nil
+				})
+				assert.NoError(t, err)
+				assert.Equal(t, 100, value)
+			}()
+		}
+		wg.Wait()
+
+		assert.Equal(t, int32(1), calls.Load())
+	}
+	t.Run("TestLoadOnce", TestLoadOnce)
+
+	TestLoadError := func(t *testing.T) {
+		cache := NewCache[int, int]()
+		defer cache.Close()
+
+		_, err := GetOrLoad(cache, 1, func(key int) (int, error) {
+			return 0, fmt.Errorf("error")
+		})
+		assert.Error(t, err)
+		assert.False(t, cache.Has(1))
+	}
+	t.Run("TestLoadError", TestLoadError)
+
+	TestWithTTL := func(t *testing.T) {
+		clock := cachetest.NewFakeClock(time.Now())
+		cache := NewCache(WithExpireAfterWrite[int, int](defaultTTL), WithClock[int, int](clock))
+		defer cache.Close()
+
+		loaderFunc := func(key int) (int, error) { return key, nil }
+
+		GetOrLoad(cache, 1, loaderFunc)
+		GetOrLoadWithTTL(cache, 2, defaultTTL*2, loaderFunc)
+
+		clock.Advance(defaultTTL + 5)
+		assert.False(t, cache.Has(1))
+		assert.True(t, cache.Has(2))
+
+		clock.Advance(defaultTTL)
+		assert.False(t, cache.Has(2))
+	}
+	t.Run("TestWithTTL", TestWithTTL)
+
+	TestOtherImplementation := func(t *testing.T) {
+		cache := struct{ TTLCache[int, int] }{NewCache[int, int]().(TTLCache[int, int])}
+		defer cache.Close()
+
+		value, err := GetOrLoadWithTTL(cache, 1, time.Minute, func(key int) (int, error) { return 100, nil })
+		assert.NoError(t, err)
+		assert.Equal(t, 100, value)
+
+		ttl, found := cache.TTL(1)
+		assert.True(t, found)
+		assert.Equal(t, time.Minute, ttl.Round(time.Minute))
+	}
+	t.Run("TestOtherImplementation", TestOtherImplementation)
+
+	TestAfterClose := func(t *testing.T) {
+		cache := NewCache[int, int]()
+		cache.Close()
+
+		_, err := GetOrLoad(cache, 1, func(key int) (int, error) { return key, nil })
+		assert.ErrorIs(t, err, ErrClosed)
+	}
+	t.Run("TestAfterClose", TestAfterClose)
 }
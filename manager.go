@@ -0,0 +1,200 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+)
+
+var (
+	// Returned when a cache with the same name is already registered.
+	ErrCacheExists = errors.New("cache already exists")
+
+	// Returned when creating or registering a cache in a closed manager.
+	ErrManagerClosed = errors.New("manager is closed")
+)
+
+// The functions of a Cache that don't depend on the key and value type.
+//
+// Every Cache implements AnyCache, use it to enumerate the caches of a Manager (e.g. for metrics).
+type AnyCache interface {
+	// Returns the total count of cached items.
+	Count() int
+
+	// Returns true if the cache is empty.
+	IsEmpty() bool
+
+	// Clear all items from cache.
+	Clear()
+
+	// Removes all expired items from the cache and returns the count of removed items.
+	CleanUp() int
+
+	// Cleanup resources and timers.
+	Close()
+
+	// Closes the cache and waits until all in-flight loads are done or the context is done.
+	Shutdown(ctx context.Context) error
+}
+
+// Keeps caches by name, so they can be looked up and closed together.
+//
+// Go has no generic methods, so caches are created with 'NewManagedCache' and 'NewManagedLoadingCache'
+// and looked up with 'GetCache' and 'GetLoadingCache'.
+type Manager struct {
+	mu     sync.RWMutex
+	caches map[string]AnyCache
+	closed bool
+}
+
+func NewManager() *Manager {
+	return &Manager{
+		caches: make(map[string]AnyCache),
+	}
+}
+
+// Create a new cache that is registered under the name.
+//
+// Returns ErrCacheExists when the name is taken and ErrManagerClosed when the manager has been closed.
+func NewManagedCache[K comparable, V any](m *Manager, name string, options ...Option[K, V]) (Cache[K, V], error) {
+	return manage(m, name, func() Cache[K, V] { return NewCache(options...) })
+}
+
+// Create a new loading cache that is registered under the name, see 'NewManagedCache'.
+func NewManagedLoadingCache[K comparable, V any](
+	m *Manager,
+	name string,
+	loaderFunc LoaderFunc[K, V],
+	options ...Option[K, V],
+) (LoadingCache[K, V], error) {
+	return manage(m, name, func() LoadingCache[K, V] { return NewLoadingCache(loaderFunc, options...) })
+}
+
+// Registers an existing cache (e.g. created by another package) under the name, see 'NewManagedCache'.
+func RegisterCache[K comparable, V any](m *Manager, name string, c Cache[K, V]) error {
+	_, err := manage(m, name, func() Cache[K, V] { return c })
+	return err
+}
+
+// Returns the cache with the name, false when it does not exist or has a different key or value type.
+func GetCache[K comparable, V any](m *Manager, name string) (Cache[K, V], bool) {
+	c, found := m.Get(name)
+	if !found {
+		return nil, false
+	}
+	typed, ok := c.(Cache[K, V])
+	return typed, ok
+}
+
+// Returns the loading cache with the name, false when it does not exist or is not a LoadingCache[K, V].
+func GetLoadingCache[K comparable, V any](m *Manager, name string) (LoadingCache[K, V], bool) {
+	c, found := m.Get(name)
+	if !found {
+		return nil, false
+	}
+	// caches created by 'NewCache' implement LoadingCache as well, but have no LoaderFunc
+	if h, ok := c.(*cacheHandle[K, V]); ok && h.loaderFunc == nil {
+		return nil, false
+	}
+	typed, ok := c.(LoadingCache[K, V])
+	return typed, ok
+}
+
+// The cache is only created when the name is available.
+func manage[C AnyCache](m *Manager, name string, create func() C) (C, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var empty C
+	if m.closed {
+		return empty, ErrManagerClosed
+	}
+	if _, found := m.caches[name]; found {
+		return empty, fmt.Errorf("%w: %s", ErrCacheExists, name)
+	}
+
+	c := create()
+	m.caches[name] = c
+	return c, nil
+}
+
+// Returns the cache with the name.
+func (m *Manager) Get(name string) (AnyCache, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	c, found := m.caches[name]
+	return c, found
+}
+
+// Returns the names of all caches in sorted order.
+func (m *Manager) Names() []string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	names := make([]string, 0, len(m.caches))
+	for name := range m.caches {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Loop over each cache in the order of their names.
+func (m *Manager) ForEach(fn func(name string, c AnyCache)) {
+	for _, name := range m.Names() {
+		if c, found := m.Get(name); found {
+			fn(name, c)
+		}
+	}
+}
+
+// Closes the cache with the name and removes it from the manager, returns false when it does not exist.
+func (m *Manager) Remove(name string) bool {
+	m.mu.Lock()
+	c, found := m.caches[name]
+	delete(m.caches, name)
+	m.mu.Unlock()
+
+	if found {
+		c.Close()
+	}
+	return found
+}
+
+// Closes all caches, creating a cache afterwards returns ErrManagerClosed.
+//
+// Calling Close more than once is a no-op.
+func (m *Manager) Close() {
+	for _, c := range m.close() {
+		c.Close()
+	}
+}
+
+// Shuts down all caches and waits until all in-flight loads are done or the context is done.
+func (m *Manager) Shutdown(ctx context.Context) error {
+	var errs []error
+	for _, c := range m.close() {
+		errs = append(errs, c.Shutdown(ctx))
+	}
+	return errors.Join(errs...)
+}
+
+// Marks the manager as closed and returns the caches that still need to be closed.
+func (m *Manager) close() []AnyCache {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.closed {
+		return nil
+	}
+	m.closed = true
+
+	caches := make([]AnyCache, 0, len(m.caches))
+	for _, c := range m.caches {
+		caches = append(caches, c)
+	}
+	return caches
+}
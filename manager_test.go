@@ -0,0 +1,121 @@
+package cache
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestManager(t *testing.T) {
+	TestNewManagedCache := func(t *testing.T) {
+		m := NewManager()
+		defer m.Close()
+
+		users, err := NewManagedCache[int, string](m, "users")
+		assert.NoError(t, err)
+		users.Put(1, "alice")
+
+		found, ok := GetCache[int, string](m, "users")
+		assert.True(t, ok)
+		value, _ := found.Get(1)
+		assert.Equal(t, "alice", value)
+
+		_, ok = GetCache[string, string](m, "users")
+		assert.False(t, ok)
+
+		_, ok = GetLoadingCache[int, string](m, "users")
+		assert.False(t, ok)
+
+		_, err = NewManagedCache[int, string](m, "users")
+		assert.ErrorIs(t, err, ErrCacheExists)
+	}
+	t.Run("TestNewManagedCache", TestNewManagedCache)
+
+	TestNewManagedLoadingCache := func(t *testing.T) {
+		m := NewManager()
+		defer m.Close()
+
+		_, err := NewManagedLoadingCache(m, "squares", func(key int) (int, error) {
+			return key * key, nil
+		})
+		assert.NoError(t, err)
+
+		squares, ok := GetLoadingCache[int, int](m, "squares")
+		assert.True(t, ok)
+		value, err := squares.Load(3)
+		assert.NoError(t, err)
+		assert.Equal(t, 9, value)
+
+		_, ok = GetCache[int, int](m, "squares")
+		assert.True(t, ok)
+	}
+	t.Run("TestNewManagedLoadingCache", TestNewManagedLoadingCache)
+
+	TestEnumerate := func(t *testing.T) {
+		m := NewManager()
+		defer m.Close()
+
+		b, _ := NewManagedCache[int, int](m, "b")
+		b.Put(1, 1)
+		b.Put(2, 2)
+		NewManagedCache[string, string](m, "a")
+		assert.NoError(t, RegisterCache(m, "c", NewCache[int, int]()))
+
+		assert.Equal(t, []string{"a", "b", "c"}, m.Names())
+
+		counts := make(map[string]int)
+		m.ForEach(func(name string, c AnyCache) {
+			counts[name] = c.Count()
+		})
+		assert.Equal(t, map[string]int{"a": 0, "b": 2, "c": 0}, counts)
+	}
+	t.Run("TestEnumerate", TestEnumerate)
+
+	TestRemove := func(t *testing.T) {
+		m := NewManager()
+		defer m.Close()
+
+		c, _ := NewManagedCache[int, int](m, "a")
+
+		assert.True(t, m.Remove("a"))
+		assert.False(t, m.Remove("a"))
+
+		c.Put(1, 1)
+		assert.False(t, c.Has(1))
+
+		_, err := NewManagedCache[int, int](m, "a")
+		assert.NoError(t, err)
+	}
+	t.Run("TestRemove", TestRemove)
+
+	TestClose := func(t *testing.T) {
+		m := NewManager()
+
+		a, _ := NewManagedCache[int, int](m, "a")
+		b, _ := NewManagedLoadingCache(m, "b", NoopLoaderFunc[int, int])
+
+		m.Close()
+		m.Close()
+
+		_, err := b.Load(1)
+		assert.ErrorIs(t, err, ErrClosed)
+		a.Put(1, 1)
+		assert.False(t, a.Has(1))
+
+		_, err = NewManagedCache[int, int](m, "c")
+		assert.ErrorIs(t, err, ErrManagerClosed)
+	}
+	t.Run("TestClose", TestClose)
+
+	TestShutdown := func(t *testing.T) {
+		m := NewManager()
+
+		c, _ := NewManagedLoadingCache(m, "a", NoopLoaderFunc[int, int])
+
+		assert.NoError(t, m.Shutdown(context.Background()))
+		_, err := c.Load(1)
+		assert.ErrorIs(t, err, ErrClosed)
+	}
+	t.Run("TestShutdown", TestShutdown)
+}
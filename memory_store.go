@@ -0,0 +1,70 @@
+package cache
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// A Store that keeps items in memory, e.g. to use as L2 of a TieredCache in tests.
+type MemoryStore[K comparable, V any] struct {
+	mu    sync.RWMutex
+	items map[K]memoryItem[V]
+	clock Clock
+}
+
+type memoryItem[V any] struct {
+	value    V
+	expireAt time.Time
+}
+
+// Create a new in-memory store, the clock is used for expiration (nil uses the system clock).
+func NewMemoryStore[K comparable, V any](clock Clock) *MemoryStore[K, V] {
+	if clock == nil {
+		clock = systemClock{}
+	}
+	return &MemoryStore[K, V]{
+		items: make(map[K]memoryItem[V]),
+		clock: clock,
+	}
+}
+
+func (s *MemoryStore[K, V]) Get(_ context.Context, key K) (V, bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	item, found := s.items[key]
+	if !found || (!item.expireAt.IsZero() && s.clock.Now().After(item.expireAt)) {
+		var value V
+		return value, false, nil
+	}
+	return item.value, true, nil
+}
+
+func (s *MemoryStore[K, V]) Set(_ context.Context, key K, value V, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	item := memoryItem[V]{value: value}
+	if ttl > 0 {
+		item.expireAt = s.clock.Now().Add(ttl)
+	}
+	s.items[key] = item
+	return nil
+}
+
+func (s *MemoryStore[K, V]) Delete(_ context.Context, key K) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.items, key)
+	return nil
+}
+
+// Returns the count of items, including expired items.
+func (s *MemoryStore[K, V]) Len() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return len(s.items)
+}
@@ -0,0 +1,33 @@
+package cache
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/larscom/go-cache/cachetest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMemoryStore(t *testing.T) {
+	ctx := context.Background()
+	clock := cachetest.NewFakeClock(time.Now())
+	store := NewMemoryStore[int, int](clock)
+
+	assert.NoError(t, store.Set(ctx, 1, 100, 0))
+	assert.NoError(t, store.Set(ctx, 2, 200, time.Second))
+
+	value, found, err := store.Get(ctx, 1)
+	assert.NoError(t, err)
+	assert.True(t, found)
+	assert.Equal(t, 100, value)
+
+	clock.Advance(time.Second * 2)
+
+	_, found, _ = store.Get(ctx, 2)
+	assert.False(t, found)
+
+	assert.NoError(t, store.Delete(ctx, 1))
+	_, found, _ = store.Get(ctx, 1)
+	assert.False(t, found)
+}
@@ -0,0 +1,247 @@
+package peer
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/larscom/go-cache"
+)
+
+const (
+	defaultHotCacheTTL  = time.Minute
+	defaultTimeout      = time.Second * 5
+	defaultMaxValueSize = 32 << 20
+)
+
+type Option[K comparable, V any] func(c *DistributedLoadingCache[K, V])
+
+// Options of the cache that holds the keys owned by this peer.
+func WithCacheOptions[K comparable, V any](options ...cache.Option[K, V]) Option[K, V] {
+	return func(c *DistributedLoadingCache[K, V]) {
+		c.cacheOptions = append(c.cacheOptions, options...)
+	}
+}
+
+// The 'TTL' of values loaded from other peers in the local hot cache, defaults to 1 minute.
+func WithHotCacheTTL[K comparable, V any](ttl time.Duration) Option[K, V] {
+	return func(c *DistributedLoadingCache[K, V]) {
+		c.hotCacheTTL = ttl
+	}
+}
+
+// The HTTP client used to talk to other peers, defaults to a client with a timeout of 5 seconds.
+func WithHTTPClient[K comparable, V any](client *http.Client) Option[K, V] {
+	return func(c *DistributedLoadingCache[K, V]) {
+		c.client = client
+	}
+}
+
+// The path on which peers serve values, defaults to "/_gocache/". Must be the same for all peers.
+func WithBasePath[K comparable, V any](basePath string) Option[K, V] {
+	return func(c *DistributedLoadingCache[K, V]) {
+		c.basePath = basePath
+	}
+}
+
+// Handles errors of loads that are served to other peers, defaults to logging them with slog.
+func WithErrorHandler[K comparable, V any](errorHandler func(err error)) Option[K, V] {
+	return func(c *DistributedLoadingCache[K, V]) {
+		c.errorHandler = errorHandler
+	}
+}
+
+// The maximum size of an encoded value (or load error) that is read from another peer, defaults to 32MB.
+//
+// A larger response is handled like an unreachable owner, so the value is loaded locally instead.
+func WithMaxValueSize[K comparable, V any](size int64) Option[K, V] {
+	return func(c *DistributedLoadingCache[K, V]) {
+		c.maxValueSize = size
+	}
+}
+
+// The amount of virtual nodes per peer on the hash ring, defaults to 50. Must be the same for all peers.
+func WithReplicas[K comparable, V any](replicas int) Option[K, V] {
+	return func(c *DistributedLoadingCache[K, V]) {
+		c.replicas = replicas
+	}
+}
+
+// A LoadingCache where the LoaderFunc of a key runs on exactly one owner peer.
+//
+// Keys are assigned to peers using a consistent hash ring. 'Load' of a key that is owned by another
+// peer is routed to that peer over HTTP, the value is kept in a local hot cache. Whenever the owner
+// can't be reached (or responds with anything but a value or a load error) the value is loaded locally
+// instead, a failing LoaderFunc on the owner is returned as *LoadError.
+//
+// 'Get', 'Has', 'Count' and 'ForEach' include the values in the hot cache.
+//
+// Every peer must serve the cache over HTTP (it implements http.Handler) at the base path.
+// The embedded LoadingCache holds the keys that are owned by this peer.
+type DistributedLoadingCache[K comparable, V any] struct {
+	cache.LoadingCache[K, V]
+
+	hot  cache.LoadingCache[K, V]
+	self string
+	ring *Ring
+
+	loaderFunc   cache.LoaderFunc[K, V]
+	codec        cache.Codec[K, V]
+	client       *http.Client
+	basePath     string
+	maxValueSize int64
+	replicas     int
+	hotCacheTTL  time.Duration
+	cacheOptions []cache.Option[K, V]
+	errorHandler func(err error)
+}
+
+// Create a new distributed loading cache.
+//
+// Self is the base URL of this peer (e.g. "http://10.0.0.1:8080") and must be part of peers,
+// the codec is used to send keys and values between peers.
+func NewDistributedLoadingCache[K comparable, V any](
+	self string,
+	peers []string,
+	loaderFunc cache.LoaderFunc[K, V],
+	codec cache.Codec[K, V],
+	options ...Option[K, V],
+) *DistributedLoadingCache[K, V] {
+	c := &DistributedLoadingCache[K, V]{
+		self:         self,
+		loaderFunc:   loaderFunc,
+		codec:        codec,
+		client:       &http.Client{Timeout: defaultTimeout},
+		basePath:     defaultBasePath,
+		maxValueSize: defaultMaxValueSize,
+		hotCacheTTL:  defaultHotCacheTTL,
+	}
+
+	for _, option := range options {
+		option(c)
+	}
+
+	c.ring = NewRing(c.replicas)
+	c.ring.Set(peers...)
+
+	c.LoadingCache = cache.NewLoadingCache(loaderFunc, c.cacheOptions...)
+	c.hot = cache.NewLoadingCache(c.loadRemote, cache.WithExpireAfterWrite[K, V](c.hotCacheTTL))
+
+	return c
+}
+
+// Loads the value on the owner peer, see 'cache.LoadingCache'.
+func (c *DistributedLoadingCache[K, V]) Load(key K) (V, error) {
+	if _, remote := c.owner(key); remote {
+		return c.hot.Load(key)
+	}
+	return c.LoadingCache.Load(key)
+}
+
+// Reloads the value, for keys owned by another peer the value is fetched from that peer again.
+func (c *DistributedLoadingCache[K, V]) Reload(key K) (V, error) {
+	if _, remote := c.owner(key); remote {
+		return c.hot.Reload(key)
+	}
+	return c.LoadingCache.Reload(key)
+}
+
+// Get an item from the owned keys or the hot cache.
+func (c *DistributedLoadingCache[K, V]) Get(key K) (V, bool) {
+	if value, found := c.LoadingCache.Get(key); found {
+		return value, true
+	}
+	return c.hot.Get(key)
+}
+
+// Returns true when the item exists in the owned keys or the hot cache.
+func (c *DistributedLoadingCache[K, V]) Has(key K) bool {
+	return c.LoadingCache.Has(key) || c.hot.Has(key)
+}
+
+// Returns true when both the owned keys and the hot cache are empty.
+func (c *DistributedLoadingCache[K, V]) IsEmpty() bool {
+	return c.LoadingCache.IsEmpty() && c.hot.IsEmpty()
+}
+
+// Returns the count of the owned keys and the keys in the hot cache.
+func (c *DistributedLoadingCache[K, V]) Count() int {
+	count := 0
+	c.ForEach(func(K, V) {
+		count++
+	})
+	return count
+}
+
+// Loop over the owned keys and the keys in the hot cache, an owned key is skipped in the hot cache.
+func (c *DistributedLoadingCache[K, V]) ForEach(fn func(key K, value V)) {
+	c.LoadingCache.ForEach(fn)
+	c.hot.ForEach(func(key K, value V) {
+		if !c.LoadingCache.Has(key) {
+			fn(key, value)
+		}
+	})
+}
+
+// Deletes an item from the owned keys and the hot cache of this peer.
+func (c *DistributedLoadingCache[K, V]) Delete(key K) {
+	c.LoadingCache.Delete(key)
+	c.hot.Delete(key)
+}
+
+// Clears the owned keys and the hot cache of this peer.
+func (c *DistributedLoadingCache[K, V]) Clear() {
+	c.LoadingCache.Clear()
+	c.hot.Clear()
+}
+
+// Replace the peers, e.g. when the fleet scales.
+func (c *DistributedLoadingCache[K, V]) SetPeers(peers ...string) {
+	c.ring.Set(peers...)
+}
+
+func (c *DistributedLoadingCache[K, V]) Close() {
+	c.LoadingCache.Close()
+	c.hot.Close()
+}
+
+// Shuts down the owned keys and the hot cache, waits for the in-flight loads of both.
+func (c *DistributedLoadingCache[K, V]) Shutdown(ctx context.Context) error {
+	return errors.Join(c.LoadingCache.Shutdown(ctx), c.hot.Shutdown(ctx))
+}
+
+// Returns the owner of the key and whether it is another peer.
+func (c *DistributedLoadingCache[K, V]) owner(key K) (string, bool) {
+	data, err := c.codec.EncodeKey(key)
+	if err != nil {
+		return c.self, false
+	}
+	owner, found := c.ring.Get(string(data))
+	return owner, found && owner != c.self
+}
+
+func (c *DistributedLoadingCache[K, V]) handleError(err error) {
+	if c.errorHandler != nil {
+		c.errorHandler(err)
+		return
+	}
+	slog.Error("go-cache", "error", err)
+}
+
+// The LoaderFunc of the hot cache, falls back to the local LoaderFunc when the owner fails.
+func (c *DistributedLoadingCache[K, V]) loadRemote(key K) (V, error) {
+	owner, remote := c.owner(key)
+	if !remote {
+		return c.LoadingCache.Load(key)
+	}
+
+	value, err := c.fetch(context.Background(), owner, key)
+	var loadErr *LoadError
+	if err != nil && !errors.As(err, &loadErr) {
+		return c.loaderFunc(key)
+	}
+
+	return value, err
+}
@@ -0,0 +1,304 @@
+package peer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/larscom/go-cache"
+	"github.com/stretchr/testify/assert"
+)
+
+type fleet struct {
+	servers []*httptest.Server
+	caches  []*DistributedLoadingCache[int, string]
+	loads   map[string]*atomic.Int64
+}
+
+func newFleet(t *testing.T, size int, loaderErr error) *fleet {
+	f := &fleet{loads: make(map[string]*atomic.Int64)}
+
+	handlers := make([]http.Handler, size)
+	peers := make([]string, size)
+	for i := 0; i < size; i++ {
+		i := i
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			handlers[i].ServeHTTP(w, r)
+		}))
+		f.servers = append(f.servers, server)
+		peers[i] = server.URL
+	}
+
+	for i := 0; i < size; i++ {
+		self := peers[i]
+		loads := new(atomic.Int64)
+		f.loads[self] = loads
+
+		c := NewDistributedLoadingCache(self, peers, func(key int) (string, error) {
+			loads.Add(1)
+			if loaderErr != nil {
+				return "", loaderErr
+			}
+			return fmt.Sprint(key), nil
+		}, cache.GobCodec[int, string]{})
+
+		handlers[i] = c
+		f.caches = append(f.caches, c)
+	}
+
+	t.Cleanup(func() {
+		for i := range f.caches {
+			f.caches[i].Close()
+			f.servers[i].Close()
+		}
+	})
+
+	return f
+}
+
+func (f *fleet) totalLoads() int64 {
+	var total int64
+	for _, loads := range f.loads {
+		total += loads.Load()
+	}
+	return total
+}
+
+func TestDistributedLoadingCache(t *testing.T) {
+	TestLoadOncePerKey := func(t *testing.T) {
+		f := newFleet(t, 3, nil)
+
+		keys := 50
+		var wg sync.WaitGroup
+		for _, c := range f.caches {
+			for key := 0; key < keys; key++ {
+				wg.Add(1)
+				go func(c *DistributedLoadingCache[int, string], key int) {
+					defer wg.Done()
+					value, err := c.Load(key)
+					assert.NoError(t, err)
+					assert.Equal(t, fmt.Sprint(key), value)
+				}(c, key)
+			}
+		}
+		wg.Wait()
+
+		assert.Equal(t, int64(keys), f.totalLoads())
+		for _, loads := range f.loads {
+			assert.Greater(t, loads.Load(), int64(0))
+		}
+	}
+
+	TestOwnerLoadsKey := func(t *testing.T) {
+		f := newFleet(t, 3, nil)
+
+		key := 1
+		owner, _ := f.caches[0].ring.Get(keyString(t, key))
+
+		for _, c := range f.caches {
+			value, err := c.Load(key)
+			assert.NoError(t, err)
+			assert.Equal(t, "1", value)
+
+			v, found := c.Get(key)
+			assert.True(t, found)
+			assert.Equal(t, "1", v)
+		}
+
+		assert.Equal(t, int64(1), f.loads[owner].Load())
+	}
+
+	TestFallbackWhenOwnerDown := func(t *testing.T) {
+		f := newFleet(t, 2, nil)
+
+		var key int
+		for key = 0; ; key++ {
+			if owner, _ := f.caches[0].ring.Get(keyString(t, key)); owner == f.servers[1].URL {
+				break
+			}
+		}
+		f.servers[1].Close()
+
+		value, err := f.caches[0].Load(key)
+		assert.NoError(t, err)
+		assert.Equal(t, fmt.Sprint(key), value)
+		assert.Equal(t, int64(1), f.loads[f.servers[0].URL].Load())
+	}
+
+	TestLoaderError := func(t *testing.T) {
+		loaderErr := errors.New("error")
+		f := newFleet(t, 2, loaderErr)
+
+		for key := 0; key < 10; key++ {
+			_, err := f.caches[0].Load(key)
+			assert.Error(t, err)
+			if owner, _ := f.caches[0].ring.Get(keyString(t, key)); owner == f.caches[0].self {
+				assert.ErrorIs(t, err, loaderErr)
+			} else {
+				var peerErr *LoadError
+				assert.ErrorAs(t, err, &peerErr)
+				assert.Equal(t, owner, peerErr.Peer)
+				// the error of the loader is not exposed to other peers
+				assert.Equal(t, "load failed", peerErr.Message)
+			}
+		}
+		assert.Equal(t, int64(10), f.totalLoads())
+	}
+
+	TestFallbackOnUnexpectedStatus := func(t *testing.T) {
+		proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			http.Error(w, "bad gateway", http.StatusBadGateway)
+		}))
+		defer proxy.Close()
+
+		self := "http://self"
+		c := NewDistributedLoadingCache(self, []string{self, proxy.URL}, func(key int) (string, error) {
+			return fmt.Sprint(key), nil
+		}, cache.GobCodec[int, string]{})
+		defer c.Close()
+
+		var key int
+		for key = 0; ; key++ {
+			if owner, _ := c.ring.Get(keyString(t, key)); owner == proxy.URL {
+				break
+			}
+		}
+
+		value, err := c.Load(key)
+		assert.NoError(t, err)
+		assert.Equal(t, fmt.Sprint(key), value)
+	}
+
+	TestFallbackOnLargeResponse := func(t *testing.T) {
+		proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write(make([]byte, 64))
+		}))
+		defer proxy.Close()
+
+		self := "http://self"
+		c := NewDistributedLoadingCache(self, []string{self, proxy.URL}, func(key int) (string, error) {
+			return fmt.Sprint(key), nil
+		}, cache.GobCodec[int, string]{}, WithMaxValueSize[int, string](32))
+		defer c.Close()
+
+		var key int
+		for key = 0; ; key++ {
+			if owner, _ := c.ring.Get(keyString(t, key)); owner == proxy.URL {
+				break
+			}
+		}
+
+		_, err := c.fetch(context.Background(), proxy.URL, key)
+		assert.EqualError(t, err, fmt.Sprintf("peer %s: response exceeds 32 bytes", proxy.URL))
+
+		value, err := c.Load(key)
+		assert.NoError(t, err)
+		assert.Equal(t, fmt.Sprint(key), value)
+	}
+
+	TestHotCache := func(t *testing.T) {
+		f := newFleet(t, 2, nil)
+		c := f.caches[0]
+
+		var owned, remote int
+		for key := 0; owned == 0 || remote == 0; key++ {
+			if _, isRemote := c.owner(key); isRemote {
+				remote = key
+			} else {
+				owned = key
+			}
+		}
+
+		assert.True(t, c.IsEmpty())
+
+		_, err := c.Load(owned)
+		assert.NoError(t, err)
+		_, err = c.Load(remote)
+		assert.NoError(t, err)
+
+		assert.True(t, c.Has(owned))
+		assert.True(t, c.Has(remote))
+		assert.Equal(t, 2, c.Count())
+
+		seen := make(map[int]string)
+		c.ForEach(func(key int, value string) {
+			seen[key] = value
+		})
+		assert.Equal(t, map[int]string{owned: fmt.Sprint(owned), remote: fmt.Sprint(remote)}, seen)
+
+		c.Clear()
+		assert.True(t, c.IsEmpty())
+	}
+
+	TestShutdown := func(t *testing.T) {
+		f := newFleet(t, 2, nil)
+		c := f.caches[0]
+
+		var owned, remote int
+		for key := 0; owned == 0 || remote == 0; key++ {
+			if _, isRemote := c.owner(key); isRemote {
+				remote = key
+			} else {
+				owned = key
+			}
+		}
+
+		_, err := c.Load(owned)
+		assert.NoError(t, err)
+		_, err = c.Load(remote)
+		assert.NoError(t, err)
+
+		assert.NoError(t, c.Shutdown(context.Background()))
+
+		assert.True(t, c.IsEmpty())
+		_, err = c.Load(owned)
+		assert.ErrorIs(t, err, cache.ErrClosed)
+		_, err = c.Load(remote)
+		assert.ErrorIs(t, err, cache.ErrClosed)
+	}
+
+	TestServeHTTP := func(t *testing.T) {
+		f := newFleet(t, 1, nil)
+		c := f.caches[0]
+
+		res, err := http.Post(f.servers[0].URL+defaultBasePath+"AQ", "", nil)
+		assert.NoError(t, err)
+		res.Body.Close()
+		assert.Equal(t, http.StatusMethodNotAllowed, res.StatusCode)
+
+		res, err = http.Get(f.servers[0].URL + "/other")
+		assert.NoError(t, err)
+		res.Body.Close()
+		assert.Equal(t, http.StatusNotFound, res.StatusCode)
+
+		res, err = http.Get(f.servers[0].URL + defaultBasePath + "!")
+		assert.NoError(t, err)
+		res.Body.Close()
+		assert.Equal(t, http.StatusBadRequest, res.StatusCode)
+
+		value, err := c.fetch(context.Background(), f.servers[0].URL, 5)
+		assert.NoError(t, err)
+		assert.Equal(t, "5", value)
+	}
+
+	t.Run("TestLoadOncePerKey", TestLoadOncePerKey)
+	t.Run("TestOwnerLoadsKey", TestOwnerLoadsKey)
+	t.Run("TestFallbackWhenOwnerDown", TestFallbackWhenOwnerDown)
+	t.Run("TestLoaderError", TestLoaderError)
+	t.Run("TestFallbackOnUnexpectedStatus", TestFallbackOnUnexpectedStatus)
+	t.Run("TestFallbackOnLargeResponse", TestFallbackOnLargeResponse)
+	t.Run("TestHotCache", TestHotCache)
+	t.Run("TestShutdown", TestShutdown)
+	t.Run("TestServeHTTP", TestServeHTTP)
+}
+
+func keyString(t *testing.T, key int) string {
+	data, err := cache.GobCodec[int, string]{}.EncodeKey(key)
+	assert.NoError(t, err)
+	return string(data)
+}
@@ -0,0 +1,111 @@
+package peer
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+const defaultBasePath = "/_gocache/"
+
+// Set on the response when the LoaderFunc failed on the owner peer, any other failed response
+// (e.g. from a proxy in between) is handled like an unreachable owner.
+const loadErrorHeader = "X-Go-Cache-Load-Error"
+
+// Serves 'GET {basePath}{key}' for other peers, the key is base64 (URL) encoded.
+//
+// The value is loaded with the local LoadingCache, so the LoaderFunc only runs on this peer.
+// Errors are passed to the error handler, the response only tells that the load failed.
+func (c *DistributedLoadingCache[K, V]) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	encoded, found := strings.CutPrefix(r.URL.Path, c.basePath)
+	if !found {
+		http.NotFound(w, r)
+		return
+	}
+
+	keyData, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		http.Error(w, "invalid key", http.StatusBadRequest)
+		return
+	}
+	key, err := c.codec.DecodeKey(keyData)
+	if err != nil {
+		http.Error(w, "invalid key", http.StatusBadRequest)
+		return
+	}
+
+	value, err := c.LoadingCache.Load(key)
+	if err != nil {
+		c.handleError(fmt.Errorf("load %v: %w", key, err))
+		w.Header().Set(loadErrorHeader, "true")
+		http.Error(w, "load failed", http.StatusInternalServerError)
+		return
+	}
+
+	data, err := c.codec.EncodeValue(value)
+	if err != nil {
+		c.handleError(fmt.Errorf("encode value %v: %w", key, err))
+		http.Error(w, "encode failed", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Write(data)
+}
+
+// Returned by 'Load' when the LoaderFunc failed on the owner peer.
+type LoadError struct {
+	Peer    string
+	Message string
+}
+
+func (e *LoadError) Error() string {
+	return fmt.Sprintf("peer %s: %s", e.Peer, e.Message)
+}
+
+// Loads the value from the owner peer.
+func (c *DistributedLoadingCache[K, V]) fetch(ctx context.Context, owner string, key K) (V, error) {
+	var value V
+
+	keyData, err := c.codec.EncodeKey(key)
+	if err != nil {
+		return value, err
+	}
+
+	url := strings.TrimSuffix(owner, "/") + c.basePath + base64.RawURLEncoding.EncodeToString(keyData)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return value, err
+	}
+
+	res, err := c.client.Do(req)
+	if err != nil {
+		return value, err
+	}
+	defer res.Body.Close()
+
+	// one more byte than allowed tells an oversized response apart
+	data, err := io.ReadAll(io.LimitReader(res.Body, c.maxValueSize+1))
+	if err != nil {
+		return value, err
+	}
+	if int64(len(data)) > c.maxValueSize {
+		return value, fmt.Errorf("peer %s: response exceeds %d bytes", owner, c.maxValueSize)
+	}
+	if res.StatusCode != http.StatusOK {
+		if res.Header.Get(loadErrorHeader) != "" {
+			return value, &LoadError{Peer: owner, Message: strings.TrimSpace(string(data))}
+		}
+		return value, fmt.Errorf("peer %s: unexpected status %s", owner, res.Status)
+	}
+
+	return c.codec.DecodeValue(data)
+}
@@ -0,0 +1,69 @@
+// Package peer provides a distributed loading cache where each key is loaded by exactly one owner peer.
+package peer
+
+import (
+	"hash/crc32"
+	"sort"
+	"strconv"
+	"sync"
+)
+
+const defaultReplicas = 50
+
+// A consistent hash ring that maps keys to peers.
+//
+// Every peer is placed on the ring multiple times (replicas) to spread the keys evenly,
+// adding or removing a peer only moves the keys of that peer.
+type Ring struct {
+	mu       sync.RWMutex
+	replicas int
+	hashes   []uint32
+	peers    map[uint32]string
+}
+
+// Create a new ring, replicas is the amount of virtual nodes per peer (defaults to 50 when <= 0).
+func NewRing(replicas int) *Ring {
+	if replicas <= 0 {
+		replicas = defaultReplicas
+	}
+	return &Ring{
+		replicas: replicas,
+		peers:    make(map[uint32]string),
+	}
+}
+
+// Replace all peers of the ring.
+func (r *Ring) Set(peers ...string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.hashes = r.hashes[:0]
+	r.peers = make(map[uint32]string, len(peers)*r.replicas)
+
+	for _, peer := range peers {
+		for i := 0; i < r.replicas; i++ {
+			hash := crc32.ChecksumIEEE([]byte(strconv.Itoa(i) + peer))
+			r.hashes = append(r.hashes, hash)
+			r.peers[hash] = peer
+		}
+	}
+	sort.Slice(r.hashes, func(i, j int) bool { return r.hashes[i] < r.hashes[j] })
+}
+
+// Returns the peer that owns the key, false when the ring is empty.
+func (r *Ring) Get(key string) (string, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if len(r.hashes) == 0 {
+		return "", false
+	}
+
+	hash := crc32.ChecksumIEEE([]byte(key))
+	i := sort.Search(len(r.hashes), func(i int) bool { return r.hashes[i] >= hash })
+	if i == len(r.hashes) {
+		i = 0
+	}
+
+	return r.peers[r.hashes[i]], true
+}
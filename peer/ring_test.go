@@ -0,0 +1,73 @@
+package peer
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRing(t *testing.T) {
+	TestEmpty := func(t *testing.T) {
+		ring := NewRing(0)
+
+		_, found := ring.Get("key")
+		assert.False(t, found)
+	}
+
+	TestConsistent := func(t *testing.T) {
+		ring := NewRing(0)
+		ring.Set("a", "b", "c")
+
+		for i := 0; i < 100; i++ {
+			key := fmt.Sprint(i)
+			first, found := ring.Get(key)
+			assert.True(t, found)
+			second, _ := ring.Get(key)
+			assert.Equal(t, first, second)
+		}
+	}
+
+	TestSpread := func(t *testing.T) {
+		ring := NewRing(0)
+		ring.Set("a", "b", "c")
+
+		owners := make(map[string]int)
+		for i := 0; i < 1000; i++ {
+			owner, _ := ring.Get(fmt.Sprint(i))
+			owners[owner]++
+		}
+
+		assert.Len(t, owners, 3)
+		for _, count := range owners {
+			assert.Greater(t, count, 100)
+		}
+	}
+
+	TestRemovePeer := func(t *testing.T) {
+		ring := NewRing(0)
+		ring.Set("a", "b", "c")
+
+		before := make(map[string]string)
+		for i := 0; i < 1000; i++ {
+			key := fmt.Sprint(i)
+			before[key], _ = ring.Get(key)
+		}
+
+		ring.Set("a", "b")
+
+		for key, owner := range before {
+			after, _ := ring.Get(key)
+			if owner != "c" {
+				assert.Equal(t, owner, after)
+			} else {
+				assert.NotEqual(t, "c", after)
+			}
+		}
+	}
+
+	t.Run("TestEmpty", TestEmpty)
+	t.Run("TestConsistent", TestConsistent)
+	t.Run("TestSpread", TestSpread)
+	t.Run("TestRemovePeer", TestRemovePeer)
+}
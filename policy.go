@@ -0,0 +1,101 @@
+package cache
+
+import "time"
+
+// Changes the expiration and cleanup of a live cache, e.g. to tighten the 'TTL' during an incident.
+type Policy interface {
+	// Returns the 'TTL' of items after they have been written, see 'WithExpireAfterWrite'.
+	ExpireAfterWrite() time.Duration
+
+	// Changes the 'TTL' of items that are written from now on, 0 means items do not expire.
+	//
+	// When restamp is true, existing items expire after the new 'TTL' from now on as well.
+	// Items written with 'PutWithTTL' are restamped too.
+	SetExpireAfterWrite(expireAfterWrite time.Duration, restamp bool)
+
+	// Returns the interval at which expired items are removed, see 'WithCleanupInterval'.
+	CleanupInterval() time.Duration
+
+	// Changes the interval at which expired items are removed, 0 disables the background cleanup.
+	SetCleanupInterval(cleanupInterval time.Duration)
+}
+
+func (c *cache[K, V]) Policy() Policy {
+	return cachePolicy[K, V]{c}
+}
+
+type cachePolicy[K comparable, V any] struct {
+	c *cache[K, V]
+}
+
+func (p cachePolicy[K, V]) ExpireAfterWrite() time.Duration {
+	return p.c.getExpireAfterWrite()
+}
+
+func (p cachePolicy[K, V]) SetExpireAfterWrite(expireAfterWrite time.Duration, restamp bool) {
+	expireAfterWrite = max(expireAfterWrite, 0)
+	p.c.expireAfterWrite.Store(int64(expireAfterWrite))
+
+	if expireAfterWrite > 0 {
+		p.c.startCleaner()
+	}
+	if restamp {
+		p.c.restamp(expireAfterWrite)
+	}
+}
+
+func (p cachePolicy[K, V]) CleanupInterval() time.Duration {
+	return p.c.getCleanupInterval()
+}
+
+func (p cachePolicy[K, V]) SetCleanupInterval(cleanupInterval time.Duration) {
+	c := p.c
+	c.lifecycle.Lock()
+	defer c.lifecycle.Unlock()
+
+	c.cleanupInterval.Store(int64(max(cleanupInterval, 0)))
+	c.restartCleaner()
+}
+
+// Replaces every valid entry with an entry that expires after ttl from now, 0 means it does not expire.
+func (c *cache[K, V]) restamp(ttl time.Duration) {
+	if c.closed.Load() {
+		return
+	}
+	if c.journal != nil {
+		c.journal.mu.Lock()
+		defer c.journal.mu.Unlock()
+	}
+
+	now := c.now()
+	var expireAt int64
+	if ttl > 0 {
+		expireAt = now + int64(ttl)
+	}
+
+	keys := make([]K, 0)
+	c.forEachEntry(func(key K, entry entry[K, V]) {
+		keys = append(keys, key)
+	})
+
+	for _, key := range keys {
+		var (
+			restamped entry[K, V]
+			found     bool
+		)
+		c.data.SetIf(key, func(previous entry[K, V], previousFound bool) (entry[K, V], bool) {
+			if !previousFound || previous.isExpired(now) {
+				return previous, false
+			}
+			restamped, found = previous, true
+			restamped.expireAt = expireAt
+			return restamped, true
+		})
+
+		if found && c.journal != nil {
+			if value, valid := restamped.load(); valid {
+				c.journalPut(key, value, restamped.expireAtTime())
+			}
+		}
+	}
+}
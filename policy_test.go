@@ -0,0 +1,134 @@
+package cache
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/larscom/go-cache/cachetest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPolicy(t *testing.T) {
+	const defaultTTL = time.Minute
+
+	TestSetExpireAfterWrite := func(t *testing.T) {
+		clock := cachetest.NewFakeClock(time.Now())
+		cache := newCache(WithClock[int, int](clock))
+		defer cache.Close()
+
+		policy := cache.Policy()
+		assert.Zero(t, policy.ExpireAfterWrite())
+		assert.False(t, cache.cleanerStarted.Load())
+
+		cache.Put(1, 1)
+		policy.SetExpireAfterWrite(defaultTTL, false)
+		cache.Put(2, 2)
+
+		assert.Equal(t, defaultTTL, policy.ExpireAfterWrite())
+		assert.True(t, cache.cleanerStarted.Load())
+
+		clock.Advance(defaultTTL * 2)
+
+		assert.True(t, cache.Has(1))
+		assert.False(t, cache.data.Has(2))
+	}
+	t.Run("TestSetExpireAfterWrite", TestSetExpireAfterWrite)
+
+	TestRestamp := func(t *testing.T) {
+		clock := cachetest.NewFakeClock(time.Now())
+		cache := NewCache(WithExpireAfterWrite[int, int](defaultTTL*10), WithClock[int, int](clock)).(TTLCache[int, int])
+		defer cache.Close()
+
+		cache.Put(1, 1)
+		cache.PutWithTTL(2, 2, 0)
+
+		cache.Policy().SetExpireAfterWrite(defaultTTL, true)
+
+		for _, key := range []int{1, 2} {
+			ttl, found := cache.TTL(key)
+			assert.True(t, found)
+			assert.Equal(t, defaultTTL, ttl)
+		}
+
+		cache.Policy().SetExpireAfterWrite(0, true)
+
+		ttl, _ := cache.TTL(1)
+		assert.Zero(t, ttl)
+
+		clock.Advance(defaultTTL * 20)
+		assert.Equal(t, 2, cache.Count())
+	}
+	t.Run("TestRestamp", TestRestamp)
+
+	TestRestampJournal := func(t *testing.T) {
+		clock := cachetest.NewFakeClock(time.Now())
+		path := filepath.Join(t.TempDir(), "cache.journal")
+		options := []Option[int, int]{
+			WithJournal(path, GobCodec[int, int]{}),
+			WithClock[int, int](clock),
+		}
+
+		cache := NewCache(options...)
+		cache.Put(1, 1)
+		cache.Policy().SetExpireAfterWrite(defaultTTL, true)
+		cache.Close()
+
+		restored := NewCache(options...).(TTLCache[int, int])
+		defer restored.Close()
+
+		ttl, found := restored.TTL(1)
+		assert.True(t, found)
+		assert.Equal(t, defaultTTL, ttl)
+	}
+	t.Run("TestRestampJournal", TestRestampJournal)
+
+	TestSetCleanupInterval := func(t *testing.T) {
+		clock := cachetest.NewFakeClock(time.Now())
+		cache := newCache(
+			WithExpireAfterWrite[int, int](defaultTTL),
+			WithCleanupInterval[int, int](0),
+			WithClock[int, int](clock),
+		)
+		defer cache.Close()
+
+		policy := cache.Policy()
+		assert.Zero(t, policy.CleanupInterval())
+		assert.False(t, cache.cleanerStarted.Load())
+
+		cache.Put(1, 1)
+		clock.Advance(defaultTTL * 2)
+		assert.True(t, cache.data.Has(1))
+
+		policy.SetCleanupInterval(time.Second)
+		assert.Equal(t, time.Second, policy.CleanupInterval())
+		assert.True(t, cache.cleanerStarted.Load())
+
+		clock.Advance(time.Second)
+		assert.False(t, cache.data.Has(1))
+
+		policy.SetCleanupInterval(0)
+		assert.False(t, cache.cleanerStarted.Load())
+	}
+	t.Run("TestSetCleanupInterval", TestSetCleanupInterval)
+
+	TestRestartCleaner := func(t *testing.T) {
+		cleaner := &mockCleaner{}
+		cache := newCache(
+			withCleaner[int, int](cleaner),
+			WithExpireAfterWrite[int, int](defaultTTL),
+		)
+
+		cleaner.started = false
+		cache.Policy().SetCleanupInterval(time.Second)
+
+		assert.True(t, cleaner.stopped)
+		assert.True(t, cleaner.started)
+
+		cache.Close()
+		cleaner.started = false
+		cache.Policy().SetCleanupInterval(time.Second * 2)
+		assert.False(t, cleaner.started)
+	}
+	t.Run("TestRestartCleaner", TestRestartCleaner)
+}
@@ -0,0 +1,238 @@
+package redisstore
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/larscom/go-cache"
+)
+
+// Implements 'cache.Cache' on top of a Store.
+//
+// Functions that can't return an error pass errors to the error handler and behave like a miss.
+// Events only contain changes made through this cache, not changes made by other Redis clients or expirations.
+type redisCache[K comparable, V any] struct {
+	store  *Store[K, V]
+	closed atomic.Bool
+
+	mu          sync.RWMutex
+	subscribers map[chan cache.Event[K, V]]struct{}
+	count       atomic.Int32
+}
+
+var _ cache.Cache[string, string] = (*redisCache[string, string])(nil)
+
+func newRedisCache[K comparable, V any](store *Store[K, V]) *redisCache[K, V] {
+	return &redisCache[K, V]{
+		store:       store,
+		subscribers: make(map[chan cache.Event[K, V]]struct{}),
+	}
+}
+
+func (c *redisCache[K, V]) Get(key K) (V, bool) {
+	if c.closed.Load() {
+		var value V
+		return value, false
+	}
+	value, found, err := c.store.Get(context.Background(), key)
+	if err != nil {
+		c.store.handleError(fmt.Errorf("get %v: %w", key, err))
+	}
+	return value, found
+}
+
+func (c *redisCache[K, V]) Put(key K, value V) {
+	if err := c.TryPut(key, value); err != nil && err != cache.ErrClosed {
+		c.store.handleError(fmt.Errorf("put %v: %w", key, err))
+	}
+}
+
+func (c *redisCache[K, V]) TryPut(key K, value V) error {
+	return c.tryPut(key, value, c.store.getTTL())
+}
+
+func (c *redisCache[K, V]) PutWithTTL(key K, value V, ttl time.Duration) {
+	if err := c.tryPut(key, value, ttl); err != nil && err != cache.ErrClosed {
+		c.store.handleError(fmt.Errorf("put %v: %w", key, err))
+	}
+}
+
+func (c *redisCache[K, V]) TTL(key K) (time.Duration, bool) {
+	if c.closed.Load() {
+		return 0, false
+	}
+	ttl, found, err := c.store.TTL(context.Background(), key)
+	if err != nil {
+		c.store.handleError(fmt.Errorf("ttl %v: %w", key, err))
+	}
+	return ttl, found
+}
+
+func (c *redisCache[K, V]) tryPut(key K, value V, ttl time.Duration) error {
+	if c.closed.Load() {
+		return cache.ErrClosed
+	}
+
+	active := c.count.Load() > 0
+	previous, found, err := c.store.set(context.Background(), key, value, ttl, active)
+	if err != nil || !active {
+		return err
+	}
+
+	if found {
+		c.publish(cache.Event[K, V]{Type: cache.EventUpdate, Key: key, OldValue: previous, NewValue: value})
+	} else {
+		c.publish(cache.Event[K, V]{Type: cache.EventPut, Key: key, NewValue: value})
+	}
+	return nil
+}
+
+func (c *redisCache[K, V]) Has(key K) bool {
+	if c.closed.Load() {
+		return false
+	}
+	found, err := c.store.Has(context.Background(), key)
+	if err != nil {
+		c.store.handleError(fmt.Errorf("has %v: %w", key, err))
+	}
+	return found
+}
+
+func (c *redisCache[K, V]) IsEmpty() bool {
+	return c.Count() == 0
+}
+
+func (c *redisCache[K, V]) Count() int {
+	if c.closed.Load() {
+		return 0
+	}
+	count, err := c.store.Count(context.Background())
+	if err != nil {
+		c.store.handleError(fmt.Errorf("count: %w", err))
+	}
+	return count
+}
+
+func (c *redisCache[K, V]) ForEach(fn func(key K, value V)) {
+	if c.closed.Load() {
+		return
+	}
+	if err := c.store.ForEach(context.Background(), fn); err != nil {
+		c.store.handleError(fmt.Errorf("for each: %w", err))
+	}
+}
+
+func (c *redisCache[K, V]) Delete(key K) {
+	if err := c.TryDelete(key); err != nil && err != cache.ErrClosed {
+		c.store.handleError(fmt.Errorf("delete %v: %w", key, err))
+	}
+}
+
+func (c *redisCache[K, V]) TryDelete(key K) error {
+	if c.closed.Load() {
+		return cache.ErrClosed
+	}
+
+	active := c.count.Load() > 0
+	previous, found, err := c.store.delete(context.Background(), key, active)
+	if err != nil || !found {
+		return err
+	}
+
+	c.publish(cache.Event[K, V]{Type: cache.EventDelete, Key: key, OldValue: previous})
+	return nil
+}
+
+func (c *redisCache[K, V]) Clear() {
+	if c.closed.Load() {
+		return
+	}
+	if err := c.store.Clear(context.Background()); err != nil {
+		c.store.handleError(fmt.Errorf("clear: %w", err))
+		return
+	}
+	c.publish(cache.Event[K, V]{Type: cache.EventClear})
+}
+
+func (c *redisCache[K, V]) Subscribe(buffer int) (<-chan cache.Event[K, V], func()) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	eventchn := make(chan cache.Event[K, V], buffer)
+	if c.closed.Load() {
+		close(eventchn)
+		return eventchn, func() {}
+	}
+
+	c.subscribers[eventchn] = struct{}{}
+	c.count.Add(1)
+
+	return eventchn, func() {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		c.unsubscribe(eventchn)
+	}
+}
+
+func (c *redisCache[K, V]) SaveTo(w io.Writer, codec cache.Codec[K, V]) error {
+	if c.closed.Load() {
+		return cache.ErrClosed
+	}
+	return c.store.SaveTo(context.Background(), w, codec)
+}
+
+func (c *redisCache[K, V]) LoadFrom(r io.Reader, codec cache.Codec[K, V]) error {
+	if c.closed.Load() {
+		return cache.ErrClosed
+	}
+	return c.store.LoadFrom(context.Background(), r, codec)
+}
+
+// Redis removes expired keys itself.
+func (c *redisCache[K, V]) CleanUp() int {
+	return 0
+}
+
+// Closes the subscriptions, the Redis client is not closed.
+func (c *redisCache[K, V]) Close() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.closed.Swap(true) {
+		return
+	}
+	for eventchn := range c.subscribers {
+		c.unsubscribe(eventchn)
+	}
+}
+
+func (c *redisCache[K, V]) Shutdown(context.Context) error {
+	c.Close()
+	return nil
+}
+
+// Sends the event to every subscriber without blocking, the event is dropped for subscribers that are full.
+func (c *redisCache[K, V]) publish(event cache.Event[K, V]) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	for eventchn := range c.subscribers {
+		select {
+		case eventchn <- event:
+		default:
+		}
+	}
+}
+
+func (c *redisCache[K, V]) unsubscribe(eventchn chan cache.Event[K, V]) {
+	if _, found := c.subscribers[eventchn]; !found {
+		return
+	}
+	delete(c.subscribers, eventchn)
+	c.count.Add(-1)
+	close(eventchn)
+}
@@ -0,0 +1,120 @@
+package redisstore
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/larscom/go-cache"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCache(t *testing.T) {
+	TestPutAndGet := func(t *testing.T) {
+		store, _ := newTestStore(t)
+		c := store.Cache()
+		defer c.Close()
+
+		c.Put("a", 1)
+		assert.NoError(t, c.TryPut("b", 2))
+
+		value, found := c.Get("a")
+		assert.True(t, found)
+		assert.Equal(t, 1, value)
+		assert.True(t, c.Has("b"))
+		assert.Equal(t, 2, c.Count())
+		assert.False(t, c.IsEmpty())
+
+		c.Delete("a")
+		assert.False(t, c.Has("a"))
+
+		c.Clear()
+		assert.True(t, c.IsEmpty())
+	}
+	t.Run("TestPutAndGet", TestPutAndGet)
+
+	TestPutWithTTL := func(t *testing.T) {
+		store, server := newTestStore(t)
+		c := store.Cache()
+		defer c.Close()
+
+		c.PutWithTTL("a", 1, time.Minute)
+		c.PutWithTTL("b", 2, 0)
+
+		ttl, found := c.TTL("a")
+		assert.True(t, found)
+		assert.Equal(t, time.Minute, ttl)
+
+		ttl, found = c.TTL("b")
+		assert.True(t, found)
+		assert.Zero(t, ttl)
+
+		_, found = c.TTL("c")
+		assert.False(t, found)
+
+		server.FastForward(time.Minute)
+		assert.False(t, c.Has("a"))
+		assert.True(t, c.Has("b"))
+	}
+	t.Run("TestPutWithTTL", TestPutWithTTL)
+
+	TestEvents := func(t *testing.T) {
+		store, _ := newTestStore(t)
+		c := store.Cache()
+		defer c.Close()
+
+		events, cancel := c.Subscribe(10)
+		defer cancel()
+
+		c.Put("a", 1)
+		c.Put("a", 2)
+		c.Delete("a")
+		c.Delete("a")
+		c.Clear()
+
+		assert.Equal(t, cache.Event[string, int]{Type: cache.EventPut, Key: "a", NewValue: 1}, <-events)
+		assert.Equal(t, cache.Event[string, int]{Type: cache.EventUpdate, Key: "a", OldValue: 1, NewValue: 2}, <-events)
+		assert.Equal(t, cache.Event[string, int]{Type: cache.EventDelete, Key: "a", OldValue: 2}, <-events)
+		assert.Equal(t, cache.Event[string, int]{Type: cache.EventClear}, <-events)
+		assert.Empty(t, events)
+	}
+	t.Run("TestEvents", TestEvents)
+
+	TestErrorHandler := func(t *testing.T) {
+		var handled error
+		store, server := newTestStore(t, WithErrorHandler(func(err error) { handled = err }))
+		c := store.Cache()
+		defer c.Close()
+
+		server.SetError("unavailable")
+
+		_, found := c.Get("a")
+		assert.False(t, found)
+		assert.Error(t, handled)
+		assert.Error(t, c.TryPut("a", 1))
+	}
+	t.Run("TestErrorHandler", TestErrorHandler)
+
+	TestClose := func(t *testing.T) {
+		store, _ := newTestStore(t)
+		c := store.Cache()
+
+		events, _ := c.Subscribe(1)
+
+		c.Put("a", 1)
+		<-events
+
+		assert.NoError(t, c.Shutdown(context.Background()))
+		c.Close()
+
+		_, ok := <-events
+		assert.False(t, ok)
+		assert.ErrorIs(t, c.TryPut("a", 1), cache.ErrClosed)
+		assert.False(t, c.Has("a"))
+
+		// the data in Redis is kept
+		found, _ := store.Has(context.Background(), "a")
+		assert.True(t, found)
+	}
+	t.Run("TestClose", TestClose)
+}
@@ -0,0 +1,20 @@
+module github.com/larscom/go-cache/redisstore
+
+go 1.24.0
+
+require (
+	github.com/alicebob/miniredis/v2 v2.35.0
+	github.com/larscom/go-cache v0.0.0-20261018152619-aa72783b62fc
+	github.com/redis/go-redis/v9 v9.17.0
+	github.com/stretchr/testify v1.11.1
+)
+
+require (
+	github.com/cespare/xxhash/v2 v2.3.0 // indirect
+	github.com/davecgh/go-spew v1.1.1 // indirect
+	github.com/dgryski/go-rendezvous v0.0.0-20200823014737-9f7001d12a5f // indirect
+	github.com/mhmtszr/concurrent-swiss-map v1.0.9 // indirect
+	github.com/pmezard/go-difflib v1.0.0 // indirect
+	github.com/yuin/gopher-lua v1.1.1 // indirect
+	gopkg.in/yaml.v3 v3.0.1 // indirect
+)
@@ -0,0 +1,75 @@
+package redisstore
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+
+	"github.com/larscom/go-cache"
+	"github.com/redis/go-redis/v9"
+)
+
+// A 'cache.InvalidationBus' that uses Redis pub/sub.
+type InvalidationBus struct {
+	client  redis.UniversalClient
+	channel string
+	options
+}
+
+var _ cache.InvalidationBus = (*InvalidationBus)(nil)
+
+// Create a new invalidation bus that publishes to the given Redis channel.
+//
+// Only 'WithErrorHandler' applies, it handles invalidations that can't be decoded.
+func NewInvalidationBus(client redis.UniversalClient, channel string, opts ...Option) *InvalidationBus {
+	b := &InvalidationBus{
+		client:  client,
+		channel: channel,
+	}
+
+	for _, opt := range opts {
+		opt(&b.options)
+	}
+
+	return b
+}
+
+func (b *InvalidationBus) Publish(ctx context.Context, invalidation cache.Invalidation) error {
+	data, err := json.Marshal(invalidation)
+	if err != nil {
+		return err
+	}
+	return b.client.Publish(ctx, b.channel, data).Err()
+}
+
+// Subscribes to the channel, returns once the subscription is confirmed by Redis.
+func (b *InvalidationBus) Subscribe(fn func(invalidation cache.Invalidation)) (func(), error) {
+	pubsub := b.client.Subscribe(context.Background(), b.channel)
+	if _, err := pubsub.Receive(context.Background()); err != nil {
+		pubsub.Close()
+		return nil, err
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for message := range pubsub.Channel() {
+			var invalidation cache.Invalidation
+			if err := json.Unmarshal([]byte(message.Payload), &invalidation); err != nil {
+				b.handleError(fmt.Errorf("decode invalidation: %w", err))
+				continue
+			}
+			fn(invalidation)
+		}
+	}()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			pubsub.Close()
+			wg.Wait()
+		})
+	}, nil
+}
@@ -0,0 +1,49 @@
+package redisstore
+
+import (
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/larscom/go-cache"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInvalidationBus(t *testing.T) {
+	server := miniredis.RunT(t)
+	codec := cache.JSONCodec[string, int]{}
+
+	newReplica := func() cache.LoadingCache[string, int] {
+		client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+		t.Cleanup(func() { client.Close() })
+
+		bus := NewInvalidationBus(client, "invalidations")
+		c := cache.NewLoadingCache(func(key string) (int, error) {
+			return 2, nil
+		}, cache.WithInvalidationBus[string, int](bus, codec))
+		t.Cleanup(c.Close)
+		return c
+	}
+
+	a := newReplica()
+	b := newReplica()
+
+	// loaded values are not broadcast
+	_, err := b.Load("key")
+	assert.NoError(t, err)
+
+	a.Put("key", 1)
+
+	assert.Eventually(t, func() bool {
+		return !b.Has("key")
+	}, time.Second, time.Millisecond)
+
+	value, found := a.Get("key")
+	assert.True(t, found)
+	assert.Equal(t, 1, value)
+
+	b.Clear()
+
+	assert.Eventually(t, a.IsEmpty, time.Second, time.Millisecond)
+}
@@ -0,0 +1,40 @@
+package redisstore
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/larscom/go-cache"
+)
+
+func (c *redisCache[K, V]) Policy() cache.Policy {
+	return redisPolicy[K, V]{c.store}
+}
+
+// Redis removes expired keys itself, so there is no cleanup interval.
+type redisPolicy[K comparable, V any] struct {
+	store *Store[K, V]
+}
+
+func (p redisPolicy[K, V]) ExpireAfterWrite() time.Duration {
+	return p.store.getTTL()
+}
+
+// Restamping sets the Redis TTL of every key in the namespace.
+func (p redisPolicy[K, V]) SetExpireAfterWrite(expireAfterWrite time.Duration, restamp bool) {
+	expireAfterWrite = max(expireAfterWrite, 0)
+	p.store.ttl.Store(int64(expireAfterWrite))
+
+	if restamp {
+		if err := p.store.restamp(context.Background(), expireAfterWrite); err != nil {
+			p.store.handleError(fmt.Errorf("restamp: %w", err))
+		}
+	}
+}
+
+func (p redisPolicy[K, V]) CleanupInterval() time.Duration {
+	return 0
+}
+
+func (p redisPolicy[K, V]) SetCleanupInterval(time.Duration) {}
@@ -0,0 +1,61 @@
+package redisstore
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPolicy(t *testing.T) {
+	TestSetExpireAfterWrite := func(t *testing.T) {
+		store, server := newTestStore(t)
+		c := store.Cache()
+		defer c.Close()
+
+		c.Put("a", 1)
+		c.Policy().SetExpireAfterWrite(time.Minute, false)
+		c.Put("b", 2)
+
+		assert.Equal(t, time.Minute, c.Policy().ExpireAfterWrite())
+
+		server.FastForward(time.Minute)
+		assert.True(t, c.Has("a"))
+		assert.False(t, c.Has("b"))
+	}
+	t.Run("TestSetExpireAfterWrite", TestSetExpireAfterWrite)
+
+	TestRestamp := func(t *testing.T) {
+		store, _ := newTestStore(t)
+		c := store.Cache()
+		defer c.Close()
+
+		c.Put("a", 1)
+		c.PutWithTTL("b", 2, time.Hour)
+
+		c.Policy().SetExpireAfterWrite(time.Minute, true)
+
+		for _, key := range []string{"a", "b"} {
+			ttl, found := c.TTL(key)
+			assert.True(t, found)
+			assert.Equal(t, time.Minute, ttl)
+		}
+
+		c.Policy().SetExpireAfterWrite(0, true)
+
+		ttl, found := c.TTL("a")
+		assert.True(t, found)
+		assert.Zero(t, ttl)
+	}
+	t.Run("TestRestamp", TestRestamp)
+
+	TestCleanupInterval := func(t *testing.T) {
+		store, _ := newTestStore(t)
+		c := store.Cache()
+		defer c.Close()
+
+		c.Policy().SetCleanupInterval(time.Second)
+		assert.Zero(t, c.Policy().CleanupInterval())
+	}
+	t.Run("TestCleanupInterval", TestCleanupInterval)
+}
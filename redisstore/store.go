@@ -0,0 +1,452 @@
+// Package redisstore provides a Redis backed store, usable as L2 of a 'cache.TieredCache' or as standalone 'cache.Cache'.
+package redisstore
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/larscom/go-cache"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	defaultNamespace = "go-cache"
+
+	// The amount of keys that are requested per SCAN call.
+	scanCount = 100
+)
+
+type Option func(o *options)
+
+type options struct {
+	namespace        string
+	expireAfterWrite time.Duration
+	errorHandler     func(err error)
+}
+
+// The prefix of every key ("namespace:key"), defaults to "go-cache".
+//
+// 'Clear' only deletes keys within the namespace.
+func WithNamespace(namespace string) Option {
+	return func(o *options) {
+		o.namespace = namespace
+	}
+}
+
+// The 'TTL' of items that are written with 'Put', mapped to the Redis TTL of the key.
+func WithExpireAfterWrite(expireAfterWrite time.Duration) Option {
+	return func(o *options) {
+		o.expireAfterWrite = expireAfterWrite
+	}
+}
+
+// Handles errors of 'Cache' functions that can't return an error (e.g. 'Get'), defaults to logging them with slog.
+func WithErrorHandler(errorHandler func(err error)) Option {
+	return func(o *options) {
+		o.errorHandler = errorHandler
+	}
+}
+
+// The context-aware variant of 'cache.Cache', every function talks to Redis and may return an error.
+type ContextCache[K comparable, V any] interface {
+	// Get an item, found is false when the item does not exist.
+	Get(ctx context.Context, key K) (value V, found bool, err error)
+
+	// Put an item using the expire after write TTL.
+	Put(ctx context.Context, key K, value V) error
+
+	// Set an item with the given ttl, a ttl of 0 means the item does not expire.
+	Set(ctx context.Context, key K, value V, ttl time.Duration) error
+
+	// Returns the remaining TTL of an item, the TTL is 0 when the item does not expire.
+	TTL(ctx context.Context, key K) (ttl time.Duration, found bool, err error)
+
+	// Returns true when the item exists.
+	Has(ctx context.Context, key K) (bool, error)
+
+	// Returns the total count of items in the namespace.
+	Count(ctx context.Context) (int, error)
+
+	// Loop over each item in the namespace.
+	ForEach(ctx context.Context, fn func(key K, value V)) error
+
+	// Deletes an item.
+	Delete(ctx context.Context, key K) error
+
+	// Deletes all items in the namespace.
+	Clear(ctx context.Context) error
+
+	// Writes all items including their expiration to w, see 'cache.Cache'.
+	SaveTo(ctx context.Context, w io.Writer, codec cache.Codec[K, V]) error
+
+	// Reads items written by 'SaveTo' from r, see 'cache.Cache'.
+	LoadFrom(ctx context.Context, r io.Reader, codec cache.Codec[K, V]) error
+}
+
+// A Redis backed store, it implements 'cache.Store' and 'ContextCache'.
+type Store[K comparable, V any] struct {
+	client redis.UniversalClient
+	codec  cache.Codec[K, V]
+	options
+
+	// the expire after write TTL, changed at runtime by 'Policy'
+	ttl atomic.Int64
+}
+
+var (
+	_ cache.Store[string, string]  = (*Store[string, string])(nil)
+	_ ContextCache[string, string] = (*Store[string, string])(nil)
+)
+
+// Create a new store, the codec is used to encode the keys and values.
+func New[K comparable, V any](
+	client redis.UniversalClient,
+	codec cache.Codec[K, V],
+	opts ...Option,
+) *Store[K, V] {
+	s := &Store[K, V]{
+		client: client,
+		codec:  codec,
+		options: options{
+			namespace: defaultNamespace,
+		},
+	}
+
+	for _, opt := range opts {
+		opt(&s.options)
+	}
+	s.ttl.Store(int64(s.expireAfterWrite))
+
+	return s
+}
+
+func (s *Store[K, V]) Get(ctx context.Context, key K) (V, bool, error) {
+	var value V
+
+	redisKey, err := s.redisKey(key)
+	if err != nil {
+		return value, false, err
+	}
+
+	data, err := s.client.Get(ctx, redisKey).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return value, false, nil
+	}
+	if err != nil {
+		return value, false, err
+	}
+
+	value, err = s.codec.DecodeValue(data)
+	return value, err == nil, err
+}
+
+func (s *Store[K, V]) Put(ctx context.Context, key K, value V) error {
+	return s.Set(ctx, key, value, s.getTTL())
+}
+
+func (s *Store[K, V]) Set(ctx context.Context, key K, value V, ttl time.Duration) error {
+	_, _, err := s.set(ctx, key, value, ttl, false)
+	return err
+}
+
+func (s *Store[K, V]) TTL(ctx context.Context, key K) (time.Duration, bool, error) {
+	redisKey, err := s.redisKey(key)
+	if err != nil {
+		return 0, false, err
+	}
+
+	ttl, err := s.client.PTTL(ctx, redisKey).Result()
+	if err != nil {
+		return 0, false, err
+	}
+
+	// -2 when the key does not exist, -1 when the key has no TTL
+	switch ttl {
+	case -2:
+		return 0, false, nil
+	case -1:
+		return 0, true, nil
+	}
+	return ttl, true, nil
+}
+
+func (s *Store[K, V]) Has(ctx context.Context, key K) (bool, error) {
+	redisKey, err := s.redisKey(key)
+	if err != nil {
+		return false, err
+	}
+
+	count, err := s.client.Exists(ctx, redisKey).Result()
+	return count > 0, err
+}
+
+func (s *Store[K, V]) Count(ctx context.Context) (int, error) {
+	count := 0
+	err := s.scan(ctx, func(keys []string) error {
+		count += len(keys)
+		return nil
+	})
+	return count, err
+}
+
+func (s *Store[K, V]) ForEach(ctx context.Context, fn func(key K, value V)) error {
+	return s.scan(ctx, func(keys []string) error {
+		values, err := s.client.MGet(ctx, keys...).Result()
+		if err != nil {
+			return err
+		}
+
+		for i, raw := range values {
+			data, ok := raw.(string)
+			if !ok {
+				// expired in the meantime
+				continue
+			}
+			key, err := s.decodeKey(keys[i])
+			if err != nil {
+				return err
+			}
+			value, err := s.codec.DecodeValue([]byte(data))
+			if err != nil {
+				return err
+			}
+			fn(key, value)
+		}
+
+		return nil
+	})
+}
+
+func (s *Store[K, V]) Delete(ctx context.Context, key K) error {
+	_, _, err := s.delete(ctx, key, false)
+	return err
+}
+
+func (s *Store[K, V]) Clear(ctx context.Context) error {
+	return s.scan(ctx, func(keys []string) error {
+		return s.client.Unlink(ctx, keys...).Err()
+	})
+}
+
+func (s *Store[K, V]) SaveTo(ctx context.Context, w io.Writer, codec cache.Codec[K, V]) error {
+	sw, err := cache.NewSnapshotWriter(w, codec)
+	if err != nil {
+		return err
+	}
+
+	err = s.scan(ctx, func(keys []string) error {
+		for _, redisKey := range keys {
+			data, err := s.client.Get(ctx, redisKey).Bytes()
+			if errors.Is(err, redis.Nil) {
+				continue
+			}
+			if err != nil {
+				return err
+			}
+			ttl, err := s.client.PTTL(ctx, redisKey).Result()
+			if err != nil {
+				return err
+			}
+
+			key, err := s.decodeKey(redisKey)
+			if err != nil {
+				return err
+			}
+			value, err := s.codec.DecodeValue(data)
+			if err != nil {
+				return err
+			}
+
+			var expireAt time.Time
+			if ttl > 0 {
+				expireAt = time.Now().Add(ttl)
+			}
+			if err := sw.Write(key, value, expireAt); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	return sw.Flush()
+}
+
+func (s *Store[K, V]) LoadFrom(ctx context.Context, r io.Reader, codec cache.Codec[K, V]) error {
+	sr, err := cache.NewSnapshotReader(r, codec)
+	if err != nil {
+		return err
+	}
+
+	for {
+		key, value, expireAt, err := sr.Read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		var ttl time.Duration
+		if !expireAt.IsZero() {
+			if ttl = time.Until(expireAt); ttl <= 0 {
+				continue
+			}
+		}
+		if err := s.Set(ctx, key, value, ttl); err != nil {
+			return err
+		}
+	}
+}
+
+// Returns a 'cache.TTLCache' that uses this store, see 'Cache'.
+func (s *Store[K, V]) Cache() cache.TTLCache[K, V] {
+	return newRedisCache(s)
+}
+
+// Sets the value, returns the previous value when withPrevious is true.
+func (s *Store[K, V]) set(
+	ctx context.Context,
+	key K,
+	value V,
+	ttl time.Duration,
+	withPrevious bool,
+) (previous V, found bool, err error) {
+	redisKey, err := s.redisKey(key)
+	if err != nil {
+		return previous, false, err
+	}
+	data, err := s.codec.EncodeValue(value)
+	if err != nil {
+		return previous, false, err
+	}
+
+	if !withPrevious {
+		return previous, false, s.client.Set(ctx, redisKey, data, ttl).Err()
+	}
+
+	old, err := s.client.SetArgs(ctx, redisKey, data, redis.SetArgs{TTL: ttl, Get: true}).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return previous, false, nil
+	}
+	if err != nil {
+		return previous, false, err
+	}
+
+	previous, err = s.codec.DecodeValue(old)
+	return previous, err == nil, err
+}
+
+// Deletes the key, returns the previous value when withPrevious is true.
+func (s *Store[K, V]) delete(
+	ctx context.Context,
+	key K,
+	withPrevious bool,
+) (previous V, found bool, err error) {
+	redisKey, err := s.redisKey(key)
+	if err != nil {
+		return previous, false, err
+	}
+
+	if !withPrevious {
+		return previous, false, s.client.Unlink(ctx, redisKey).Err()
+	}
+
+	old, err := s.client.GetDel(ctx, redisKey).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return previous, false, nil
+	}
+	if err != nil {
+		return previous, false, err
+	}
+
+	previous, err = s.codec.DecodeValue(old)
+	return previous, err == nil, err
+}
+
+// Calls fn with every batch of keys in the namespace.
+func (s *Store[K, V]) scan(ctx context.Context, fn func(keys []string) error) error {
+	match := escapePattern(s.prefix()) + "*"
+
+	var cursor uint64
+	for {
+		keys, next, err := s.client.Scan(ctx, cursor, match, scanCount).Result()
+		if err != nil {
+			return err
+		}
+		if len(keys) > 0 {
+			if err := fn(keys); err != nil {
+				return err
+			}
+		}
+		if next == 0 {
+			return nil
+		}
+		cursor = next
+	}
+}
+
+func (s *Store[K, V]) getTTL() time.Duration {
+	return time.Duration(s.ttl.Load())
+}
+
+// Sets the TTL of every key in the namespace, a ttl of 0 removes the TTL.
+func (s *Store[K, V]) restamp(ctx context.Context, ttl time.Duration) error {
+	return s.scan(ctx, func(keys []string) error {
+		_, err := s.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+			for _, key := range keys {
+				if ttl > 0 {
+					pipe.PExpire(ctx, key, ttl)
+				} else {
+					pipe.Persist(ctx, key)
+				}
+			}
+			return nil
+		})
+		return err
+	})
+}
+
+func (s *Store[K, V]) prefix() string {
+	return s.namespace + ":"
+}
+
+func (s *Store[K, V]) redisKey(key K) (string, error) {
+	data, err := s.codec.EncodeKey(key)
+	if err != nil {
+		return "", err
+	}
+	return s.prefix() + string(data), nil
+}
+
+func (s *Store[K, V]) decodeKey(redisKey string) (K, error) {
+	return s.codec.DecodeKey([]byte(strings.TrimPrefix(redisKey, s.prefix())))
+}
+
+func (o *options) handleError(err error) {
+	if o.errorHandler != nil {
+		o.errorHandler(err)
+		return
+	}
+	slog.Error("go-cache", "error", err)
+}
+
+// Escapes the glob characters of a SCAN MATCH pattern.
+func escapePattern(pattern string) string {
+	var sb strings.Builder
+	for _, r := range pattern {
+		switch r {
+		case '*', '?', '[', ']', '\\':
+			sb.WriteRune('\\')
+		}
+		sb.WriteRune(r)
+	}
+	return sb.String()
+}
@@ -0,0 +1,147 @@
+package redisstore
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/larscom/go-cache"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestStore(t *testing.T, opts ...Option) (*Store[string, int], *miniredis.Miniredis) {
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	t.Cleanup(func() { client.Close() })
+
+	return New[string, int](client, cache.JSONCodec[string, int]{}, opts...), server
+}
+
+func TestStore(t *testing.T) {
+	ctx := context.Background()
+
+	TestGetAndSet := func(t *testing.T) {
+		store, _ := newTestStore(t)
+
+		assert.NoError(t, store.Set(ctx, "a", 1, 0))
+
+		value, found, err := store.Get(ctx, "a")
+		assert.NoError(t, err)
+		assert.True(t, found)
+		assert.Equal(t, 1, value)
+
+		_, found, err = store.Get(ctx, "b")
+		assert.NoError(t, err)
+		assert.False(t, found)
+
+		found, err = store.Has(ctx, "a")
+		assert.NoError(t, err)
+		assert.True(t, found)
+	}
+	t.Run("TestGetAndSet", TestGetAndSet)
+
+	TestExpireAfterWrite := func(t *testing.T) {
+		store, server := newTestStore(t, WithExpireAfterWrite(time.Minute))
+
+		assert.NoError(t, store.Put(ctx, "a", 1))
+		assert.Equal(t, time.Minute, server.TTL(`go-cache:"a"`))
+
+		server.FastForward(time.Minute)
+
+		_, found, err := store.Get(ctx, "a")
+		assert.NoError(t, err)
+		assert.False(t, found)
+	}
+	t.Run("TestExpireAfterWrite", TestExpireAfterWrite)
+
+	TestNamespace := func(t *testing.T) {
+		store, server := newTestStore(t, WithNamespace("users"))
+		server.Set("other", "value")
+
+		assert.NoError(t, store.Set(ctx, "a", 1, 0))
+		assert.NoError(t, store.Set(ctx, "b", 2, 0))
+		assert.True(t, server.Exists(`users:"a"`))
+
+		count, err := store.Count(ctx)
+		assert.NoError(t, err)
+		assert.Equal(t, 2, count)
+
+		items := make(map[string]int)
+		assert.NoError(t, store.ForEach(ctx, func(key string, value int) {
+			items[key] = value
+		}))
+		assert.Equal(t, map[string]int{"a": 1, "b": 2}, items)
+
+		assert.NoError(t, store.Clear(ctx))
+
+		count, err = store.Count(ctx)
+		assert.NoError(t, err)
+		assert.Zero(t, count)
+		assert.True(t, server.Exists("other"))
+	}
+	t.Run("TestNamespace", TestNamespace)
+
+	TestScanMany := func(t *testing.T) {
+		store, _ := newTestStore(t)
+
+		for i := 0; i < scanCount*3; i++ {
+			assert.NoError(t, store.Set(ctx, string(rune('a'+i%26))+string(rune(i)), i, 0))
+		}
+
+		count, err := store.Count(ctx)
+		assert.NoError(t, err)
+		assert.Equal(t, scanCount*3, count)
+	}
+	t.Run("TestScanMany", TestScanMany)
+
+	TestDelete := func(t *testing.T) {
+		store, _ := newTestStore(t)
+
+		assert.NoError(t, store.Set(ctx, "a", 1, 0))
+		assert.NoError(t, store.Delete(ctx, "a"))
+
+		found, err := store.Has(ctx, "a")
+		assert.NoError(t, err)
+		assert.False(t, found)
+	}
+	t.Run("TestDelete", TestDelete)
+
+	TestSaveAndLoad := func(t *testing.T) {
+		source, _ := newTestStore(t)
+		target, server := newTestStore(t)
+		codec := cache.GobCodec[string, int]{}
+
+		assert.NoError(t, source.Set(ctx, "a", 1, time.Minute))
+		assert.NoError(t, source.Set(ctx, "b", 2, 0))
+
+		var buf bytes.Buffer
+		assert.NoError(t, source.SaveTo(ctx, &buf, codec))
+		assert.NoError(t, target.LoadFrom(ctx, &buf, codec))
+
+		count, err := target.Count(ctx)
+		assert.NoError(t, err)
+		assert.Equal(t, 2, count)
+
+		assert.Greater(t, server.TTL(`go-cache:"a"`), time.Second*50)
+		assert.Zero(t, server.TTL(`go-cache:"b"`))
+	}
+	t.Run("TestSaveAndLoad", TestSaveAndLoad)
+
+	TestAsTieredCacheL2 := func(t *testing.T) {
+		store, _ := newTestStore(t)
+
+		tiered := cache.NewTieredCache[string, int](cache.NewCache[string, int](), store)
+		defer tiered.Close()
+
+		assert.NoError(t, tiered.Put(ctx, "a", 1))
+
+		value, found, err := store.Get(ctx, "a")
+		assert.NoError(t, err)
+		assert.True(t, found)
+		assert.Equal(t, 1, value)
+	}
+	t.Run("TestAsTieredCacheL2", TestAsTieredCacheL2)
+}
@@ -0,0 +1,329 @@
+package cache
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+const (
+	snapshotMagic = "GOCACHE1"
+
+	// Guards against reading huge fields from corrupted data.
+	maxRecordFieldSize = 1 << 30
+
+	// Fields are read in chunks of this size, so a corrupted size only allocates what is actually there.
+	readChunkSize = 64 << 10
+)
+
+var errInvalidSnapshot = errors.New("invalid snapshot")
+
+// Periodically writes a snapshot of the cache to a file.
+//
+// Whenever the file exists, the cache is restored from it when created. A snapshot is written
+// at every interval and when the cache gets closed. Snapshots are written to a temporary file first
+// and then atomically renamed, so the file always contains a complete snapshot.
+//
+// An interval of 0 only writes a snapshot when the cache gets closed.
+func WithSnapshot[K comparable, V any](
+	path string,
+	codec Codec[K, V],
+	interval time.Duration,
+) Option[K, V] {
+	return func(c *cache[K, V]) {
+		c.snapshot = &snapshotFile[K, V]{
+			path:     path,
+			codec:    codec,
+			interval: interval,
+		}
+	}
+}
+
+type snapshotFile[K comparable, V any] struct {
+	path     string
+	codec    Codec[K, V]
+	interval time.Duration
+	stop     func()
+}
+
+func (c *cache[K, V]) SaveTo(w io.Writer, codec Codec[K, V]) error {
+	if c.closed.Load() {
+		return ErrClosed
+	}
+	return c.saveTo(w, codec)
+}
+
+func (c *cache[K, V]) LoadFrom(r io.Reader, codec Codec[K, V]) error {
+	if c.closed.Load() {
+		return ErrClosed
+	}
+	return c.loadFrom(r, codec)
+}
+
+func (c *cache[K, V]) saveTo(w io.Writer, codec Codec[K, V]) error {
+	sw, err := NewSnapshotWriter(w, codec)
+	if err != nil {
+		return err
+	}
+
+	now := c.now()
+	c.data.Range(func(key K, entry entry[K, V]) (stop bool) {
+		value, valid := entry.loadAt(now)
+		if !valid {
+			return false
+		}
+		err = sw.Write(key, value, entry.expireAtTime())
+		return err != nil
+	})
+	if err != nil {
+		return err
+	}
+
+	return sw.Flush()
+}
+
+func (c *cache[K, V]) loadFrom(r io.Reader, codec Codec[K, V]) error {
+	sr, err := NewSnapshotReader(r, codec)
+	if err != nil {
+		return err
+	}
+
+	for {
+		key, value, expireAt, err := sr.Read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		created := newEntry(key, value, unixNano(expireAt))
+		if created.expireAt != 0 {
+			if c.now() >= created.expireAt {
+				continue
+			}
+			c.startCleaner()
+		}
+		c.storeEntry(created)
+	}
+}
+
+// Writes items in the format of 'SaveTo', for Cache implementations outside of this package.
+type SnapshotWriter[K any, V any] struct {
+	w     *bufio.Writer
+	codec Codec[K, V]
+}
+
+// Create a new snapshot writer, the snapshot header is written immediately.
+func NewSnapshotWriter[K any, V any](w io.Writer, codec Codec[K, V]) (*SnapshotWriter[K, V], error) {
+	bw := bufio.NewWriter(w)
+	if _, err := bw.WriteString(snapshotMagic); err != nil {
+		return nil, err
+	}
+	return &SnapshotWriter[K, V]{w: bw, codec: codec}, nil
+}
+
+// Write a single item, a zero expireAt means the item does not expire.
+func (s *SnapshotWriter[K, V]) Write(key K, value V, expireAt time.Time) error {
+	return writeRecord(s.w, s.codec, key, value, expireAt)
+}
+
+// Flush the buffered items to the underlying writer, must be called after the last item.
+func (s *SnapshotWriter[K, V]) Flush() error {
+	return s.w.Flush()
+}
+
+// Reads items in the format of 'SaveTo', for Cache implementations outside of this package.
+type SnapshotReader[K any, V any] struct {
+	r     *bufio.Reader
+	codec Codec[K, V]
+}
+
+// Create a new snapshot reader, returns an error when r does not start with a snapshot header.
+func NewSnapshotReader[K any, V any](r io.Reader, codec Codec[K, V]) (*SnapshotReader[K, V], error) {
+	br := bufio.NewReader(r)
+
+	magic := make([]byte, len(snapshotMagic))
+	if _, err := io.ReadFull(br, magic); err != nil || string(magic) != snapshotMagic {
+		return nil, errInvalidSnapshot
+	}
+
+	return &SnapshotReader[K, V]{r: br, codec: codec}, nil
+}
+
+// Read the next item, returns io.EOF when there are no more items.
+func (s *SnapshotReader[K, V]) Read() (key K, value V, expireAt time.Time, err error) {
+	return readRecord(s.r, s.codec)
+}
+
+func (c *cache[K, V]) startSnapshots() {
+	if c.snapshot == nil {
+		return
+	}
+
+	if err := c.restoreSnapshot(); err != nil {
+		c.handleError(fmt.Errorf("restore snapshot %s: %w", c.snapshot.path, err))
+	}
+
+	if c.snapshot.interval > 0 {
+		c.snapshot.stop = c.clock.NewTicker(c.snapshot.interval, func() {
+			if err := c.writeSnapshot(); err != nil {
+				c.handleError(fmt.Errorf("write snapshot %s: %w", c.snapshot.path, err))
+			}
+		})
+	}
+}
+
+// Stops the periodic snapshots and writes the final snapshot.
+func (c *cache[K, V]) stopSnapshots() {
+	if c.snapshot == nil {
+		return
+	}
+
+	if c.snapshot.stop != nil {
+		c.snapshot.stop()
+	}
+
+	if err := c.writeSnapshot(); err != nil {
+		c.handleError(fmt.Errorf("write snapshot %s: %w", c.snapshot.path, err))
+	}
+}
+
+func (c *cache[K, V]) restoreSnapshot() error {
+	file, err := os.Open(c.snapshot.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	return c.loadFrom(file, c.snapshot.codec)
+}
+
+func (c *cache[K, V]) writeSnapshot() error {
+	return writeFileAtomic(c.snapshot.path, func(w io.Writer) error {
+		return c.saveTo(w, c.snapshot.codec)
+	})
+}
+
+// Writes to a temporary file in the same directory and renames it to path once it is complete.
+func writeFileAtomic(path string, write func(w io.Writer) error) error {
+	file, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(file.Name())
+
+	if err := write(file); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Sync(); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(file.Name(), path)
+}
+
+// Writes a single item as: key length, key, value length, value, expiration (unix nano, 0 = never).
+func writeRecord[K any, V any](
+	w io.Writer,
+	codec Codec[K, V],
+	key K,
+	value V,
+	expireAt time.Time,
+) error {
+	keyData, err := codec.EncodeKey(key)
+	if err != nil {
+		return fmt.Errorf("encode key: %w", err)
+	}
+	valueData, err := codec.EncodeValue(value)
+	if err != nil {
+		return fmt.Errorf("encode value: %w", err)
+	}
+
+	var expireAtNano int64
+	if !expireAt.IsZero() {
+		expireAtNano = expireAt.UnixNano()
+	}
+
+	buf := make([]byte, 0, len(keyData)+len(valueData)+3*binary.MaxVarintLen64)
+	buf = binary.AppendUvarint(buf, uint64(len(keyData)))
+	buf = append(buf, keyData...)
+	buf = binary.AppendUvarint(buf, uint64(len(valueData)))
+	buf = append(buf, valueData...)
+	buf = binary.AppendVarint(buf, expireAtNano)
+
+	_, err = w.Write(buf)
+	return err
+}
+
+// Reads a single item written by writeRecord, returns io.EOF when there are no more items.
+func readRecord[K any, V any](
+	r *bufio.Reader,
+	codec Codec[K, V],
+) (key K, value V, expireAt time.Time, err error) {
+	keyData, err := readField(r)
+	if err != nil {
+		return key, value, expireAt, err
+	}
+	valueData, err := readField(r)
+	if err != nil {
+		return key, value, expireAt, noEOF(err)
+	}
+	expireAtNano, err := binary.ReadVarint(r)
+	if err != nil {
+		return key, value, expireAt, noEOF(err)
+	}
+
+	if key, err = codec.DecodeKey(keyData); err != nil {
+		return key, value, expireAt, fmt.Errorf("decode key: %w", err)
+	}
+	if value, err = codec.DecodeValue(valueData); err != nil {
+		return key, value, expireAt, fmt.Errorf("decode value: %w", err)
+	}
+	if expireAtNano != 0 {
+		expireAt = time.Unix(0, expireAtNano)
+	}
+
+	return key, value, expireAt, nil
+}
+
+func readField(r *bufio.Reader) ([]byte, error) {
+	size, err := binary.ReadUvarint(r)
+	if err != nil {
+		return nil, err
+	}
+	if size > maxRecordFieldSize {
+		return nil, errInvalidSnapshot
+	}
+	return readSized(r, int64(size))
+}
+
+// Reads size bytes, the buffer grows with the data that arrives instead of being allocated up front.
+func readSized(r io.Reader, size int64) ([]byte, error) {
+	buf := bytes.NewBuffer(make([]byte, 0, min(size, readChunkSize)))
+	if _, err := io.CopyN(buf, r, size); err != nil {
+		return nil, noEOF(err)
+	}
+	return buf.Bytes(), nil
+}
+
+// A record that ends halfway is corrupted, not the regular end of the stream.
+func noEOF(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
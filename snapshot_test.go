@@ -0,0 +1,208 @@
+package cache
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/larscom/go-cache/cachetest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSnapshot(t *testing.T) {
+	const defaultTTL = time.Minute
+
+	TestSaveAndLoad := func(t *testing.T) {
+		codecs := map[string]Codec[int, string]{
+			"gob":  GobCodec[int, string]{},
+			"json": JSONCodec[int, string]{},
+		}
+
+		for name, codec := range codecs {
+			t.Run(name, func(t *testing.T) {
+				clock := cachetest.NewFakeClock(time.Now())
+
+				source := NewCache(WithExpireAfterWrite[int, string](defaultTTL), WithClock[int, string](clock))
+				defer source.Close()
+
+				source.Put(1, "one")
+				clock.Advance(defaultTTL / 2)
+				source.Put(2, "two")
+
+				var buf bytes.Buffer
+				assert.NoError(t, source.SaveTo(&buf, codec))
+
+				target := NewCache(WithClock[int, string](clock))
+				defer target.Close()
+
+				assert.NoError(t, target.LoadFrom(&buf, codec))
+				assert.Equal(t, 2, target.Count())
+
+				value, _ := target.Get(2)
+				assert.Equal(t, "two", value)
+
+				// items keep their original expiration
+				clock.Advance(defaultTTL/2 + 1)
+				assert.False(t, target.Has(1))
+				assert.True(t, target.Has(2))
+			})
+		}
+	}
+	t.Run("TestSaveAndLoad", TestSaveAndLoad)
+
+	TestLoadSkipsExpired := func(t *testing.T) {
+		clock := cachetest.NewFakeClock(time.Now())
+		codec := GobCodec[int, int]{}
+
+		source := NewCache(WithExpireAfterWrite[int, int](defaultTTL), WithClock[int, int](clock))
+		defer source.Close()
+
+		source.Put(1, 100)
+
+		var buf bytes.Buffer
+		assert.NoError(t, source.SaveTo(&buf, codec))
+
+		clock.Advance(defaultTTL * 2)
+
+		target := NewCache(WithClock[int, int](clock))
+		defer target.Close()
+
+		assert.NoError(t, target.LoadFrom(&buf, codec))
+		assert.True(t, target.IsEmpty())
+	}
+	t.Run("TestLoadSkipsExpired", TestLoadSkipsExpired)
+
+	TestLoadStartsCleaner := func(t *testing.T) {
+		codec := GobCodec[int, int]{}
+
+		source := NewCache[int, int]()
+		defer source.Close()
+
+		source.Put(1, 100)
+
+		var buf bytes.Buffer
+		assert.NoError(t, source.SaveTo(&buf, codec))
+
+		cleaner := &mockCleaner{}
+		target := newCache(withCleaner[int, int](cleaner))
+		defer target.Close()
+
+		// items that don't expire don't need the cleaner
+		assert.NoError(t, target.LoadFrom(&buf, codec))
+		assert.False(t, cleaner.started)
+
+		expiring := NewCache(WithExpireAfterWrite[int, int](defaultTTL))
+		defer expiring.Close()
+
+		expiring.Put(2, 200)
+		assert.NoError(t, expiring.SaveTo(&buf, codec))
+
+		assert.NoError(t, target.LoadFrom(&buf, codec))
+		assert.True(t, cleaner.started)
+	}
+	t.Run("TestLoadStartsCleaner", TestLoadStartsCleaner)
+
+	TestLoadInvalid := func(t *testing.T) {
+		cache := NewCache[int, int]()
+		defer cache.Close()
+
+		err := cache.LoadFrom(bytes.NewBufferString("invalid"), GobCodec[int, int]{})
+		assert.ErrorIs(t, err, errInvalidSnapshot)
+	}
+	t.Run("TestLoadInvalid", TestLoadInvalid)
+
+	TestLoadTruncated := func(t *testing.T) {
+		source := NewCache[int, int]()
+		defer source.Close()
+
+		source.Put(1, 100)
+
+		var buf bytes.Buffer
+		assert.NoError(t, source.SaveTo(&buf, GobCodec[int, int]{}))
+		buf.Truncate(buf.Len() - 2)
+
+		target := NewCache[int, int]()
+		defer target.Close()
+
+		err := target.LoadFrom(&buf, GobCodec[int, int]{})
+		assert.Error(t, err)
+	}
+	t.Run("TestLoadTruncated", TestLoadTruncated)
+
+	TestLoadCorruptedSize := func(t *testing.T) {
+		// a field that claims the maximum size, followed by a few bytes
+		data := binary.AppendUvarint([]byte(snapshotMagic), maxRecordFieldSize)
+		data = append(data, "abc"...)
+
+		cache := NewCache[int, int]()
+		defer cache.Close()
+
+		var before, after runtime.MemStats
+		runtime.ReadMemStats(&before)
+		err := cache.LoadFrom(bytes.NewReader(data), GobCodec[int, int]{})
+		runtime.ReadMemStats(&after)
+
+		assert.ErrorIs(t, err, io.ErrUnexpectedEOF)
+		assert.Less(t, after.TotalAlloc-before.TotalAlloc, uint64(readChunkSize*4))
+	}
+	t.Run("TestLoadCorruptedSize", TestLoadCorruptedSize)
+
+	TestClosed := func(t *testing.T) {
+		cache := NewCache[int, int]()
+		cache.Close()
+
+		var buf bytes.Buffer
+		assert.ErrorIs(t, cache.SaveTo(&buf, GobCodec[int, int]{}), ErrClosed)
+		assert.ErrorIs(t, cache.LoadFrom(&buf, GobCodec[int, int]{}), ErrClosed)
+	}
+	t.Run("TestClosed", TestClosed)
+
+	TestWithSnapshot := func(t *testing.T) {
+		clock := cachetest.NewFakeClock(time.Now())
+		path := filepath.Join(t.TempDir(), "cache.snapshot")
+		codec := JSONCodec[int, int]{}
+
+		cache := NewCache(WithSnapshot(path, codec, time.Second), WithClock[int, int](clock))
+		cache.Put(1, 100)
+
+		_, err := os.Stat(path)
+		assert.ErrorIs(t, err, os.ErrNotExist)
+
+		clock.Advance(time.Second)
+		assert.FileExists(t, path)
+
+		cache.Put(2, 200)
+		cache.Close()
+
+		restored := NewCache(WithSnapshot(path, codec, 0), WithClock[int, int](clock))
+		defer restored.Close()
+
+		assert.Equal(t, 2, restored.Count())
+
+		entries, err := os.ReadDir(filepath.Dir(path))
+		assert.NoError(t, err)
+		assert.Len(t, entries, 1)
+	}
+	t.Run("TestWithSnapshot", TestWithSnapshot)
+
+	TestWithSnapshotRestoreError := func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "cache.snapshot")
+		assert.NoError(t, os.WriteFile(path, []byte("invalid"), 0o644))
+
+		var handled error
+		cache := NewCache(
+			WithSnapshot(path, GobCodec[int, int]{}, 0),
+			WithErrorHandler[int, int](func(err error) { handled = err }),
+		)
+		defer cache.Close()
+
+		assert.True(t, errors.Is(handled, errInvalidSnapshot))
+	}
+	t.Run("TestWithSnapshotRestoreError", TestWithSnapshotRestoreError)
+}
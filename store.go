@@ -0,0 +1,312 @@
+package cache
+
+import (
+	"fmt"
+	"sync"
+
+	csmap "github.com/mhmtszr/concurrent-swiss-map"
+	"github.com/mhmtszr/concurrent-swiss-map/maphash"
+)
+
+const defaultShardCount = 32
+
+// The storage backend of a cache, see 'WithStore'.
+type StoreType int
+
+const (
+	// A concurrent swiss map, a good fit for most workloads.
+	StoreCSMap StoreType = iota
+
+	// A sync.Map, a good fit for keys that are written once and read many times.
+	//
+	// Every 'Put' allocates, because sync.Map stores values as interfaces.
+	StoreSyncMap
+
+	// A Go map per shard guarded by a RWMutex, a good fit for write heavy workloads.
+	StoreShardedMap
+)
+
+func (t StoreType) String() string {
+	switch t {
+	case StoreCSMap:
+		return "csmap"
+	case StoreSyncMap:
+		return "syncmap"
+	case StoreShardedMap:
+		return "sharded"
+	default:
+		return "unknown"
+	}
+}
+
+// Returns the StoreType of the name returned by 'String'.
+func parseStoreType(name string) (StoreType, error) {
+	for _, storeType := range []StoreType{StoreCSMap, StoreSyncMap, StoreShardedMap} {
+		if storeType.String() == name {
+			return storeType, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown store: %s", name)
+}
+
+// Used when 'WithStore' is not set, a variable so the tests can run against every store (see store_env.go).
+var defaultStoreType = StoreCSMap
+
+// The storage backend of the cache, defaults to 'StoreCSMap'.
+func WithStore[K comparable, V any](storeType StoreType) Option[K, V] {
+	return func(c *cache[K, V]) {
+		c.storeType = &storeType
+	}
+}
+
+// The count of shards of 'StoreCSMap' and 'StoreShardedMap', defaults to 32.
+//
+// More shards mean less lock contention between writers. 'StoreSyncMap' has no shards, it ignores
+// the shard count.
+func WithShardCount[K comparable, V any](shardCount int) Option[K, V] {
+	return func(c *cache[K, V]) {
+		c.shardCount = shardCount
+	}
+}
+
+// The count of items to allocate room for upfront, avoids growing the store while it fills up.
+//
+// 'StoreSyncMap' can't allocate room upfront, it ignores the initial capacity.
+func WithInitialCapacity[K comparable, V any](initialCapacity int) Option[K, V] {
+	return func(c *cache[K, V]) {
+		c.initialCapacity = initialCapacity
+	}
+}
+
+// A concurrent map, *csmap.CsMap implements it as is.
+type store[K comparable, V any] interface {
+	Load(key K) (V, bool)
+	Has(key K) bool
+	Store(key K, value V)
+
+	// Stores the value returned by fn when set is true, atomically with reading the previous value.
+	//
+	// fn may be called more than once.
+	SetIf(key K, fn func(previous V, found bool) (value V, set bool))
+
+	Delete(key K) bool
+
+	// Deletes the value when condition returns true, returns true when the value has been deleted.
+	//
+	// condition may be called more than once.
+	DeleteIf(key K, condition func(value V) bool) bool
+
+	// Calls fn for every value until it returns true, fn may change the store.
+	Range(fn func(key K, value V) (stop bool))
+
+	Clear()
+}
+
+func newStore[K comparable, V any](storeType StoreType, shardCount int, initialCapacity int) store[K, V] {
+	if shardCount <= 0 {
+		shardCount = defaultShardCount
+	}
+	initialCapacity = max(initialCapacity, 0)
+
+	switch storeType {
+	case StoreSyncMap:
+		return &syncMapStore[K, V]{}
+	case StoreShardedMap:
+		return newShardedStore[K, V](shardCount, initialCapacity)
+	default:
+		return csmap.New(
+			csmap.WithShardCount[K, V](uint64(shardCount)),
+			csmap.WithSize[K, V](uint64(initialCapacity)),
+		)
+	}
+}
+
+// Stores pointers to the values, so values don't have to be comparable for CompareAndSwap.
+type syncMapStore[K comparable, V any] struct {
+	m sync.Map
+}
+
+func (s *syncMapStore[K, V]) Load(key K) (V, bool) {
+	if p, found := s.m.Load(key); found {
+		return *p.(*V), true
+	}
+	var value V
+	return value, false
+}
+
+func (s *syncMapStore[K, V]) Has(key K) bool {
+	_, found := s.m.Load(key)
+	return found
+}
+
+func (s *syncMapStore[K, V]) Store(key K, value V) {
+	s.m.Store(key, &value)
+}
+
+func (s *syncMapStore[K, V]) SetIf(key K, fn func(previous V, found bool) (value V, set bool)) {
+	for {
+		var previous V
+		p, found := s.m.Load(key)
+		if found {
+			previous = *p.(*V)
+		}
+
+		value, set := fn(previous, found)
+		if !set {
+			return
+		}
+
+		if found {
+			if s.m.CompareAndSwap(key, p, &value) {
+				return
+			}
+		} else if _, loaded := s.m.LoadOrStore(key, &value); !loaded {
+			return
+		}
+	}
+}
+
+func (s *syncMapStore[K, V]) Delete(key K) bool {
+	_, found := s.m.LoadAndDelete(key)
+	return found
+}
+
+func (s *syncMapStore[K, V]) DeleteIf(key K, condition func(value V) bool) bool {
+	for {
+		p, found := s.m.Load(key)
+		if !found || !condition(*p.(*V)) {
+			return false
+		}
+		if s.m.CompareAndDelete(key, p) {
+			return true
+		}
+	}
+}
+
+func (s *syncMapStore[K, V]) Range(fn func(key K, value V) (stop bool)) {
+	s.m.Range(func(key, p any) bool {
+		return !fn(key.(K), *p.(*V))
+	})
+}
+
+func (s *syncMapStore[K, V]) Clear() {
+	s.m.Range(func(key, _ any) bool {
+		s.m.Delete(key)
+		return true
+	})
+}
+
+type shardedStore[K comparable, V any] struct {
+	hasher maphash.Hasher[K]
+	shards []mapShard[K, V]
+}
+
+type mapShard[K comparable, V any] struct {
+	mu    sync.RWMutex
+	items map[K]V
+}
+
+func newShardedStore[K comparable, V any](shardCount int, initialCapacity int) *shardedStore[K, V] {
+	s := &shardedStore[K, V]{
+		hasher: maphash.NewHasher[K](),
+		shards: make([]mapShard[K, V], shardCount),
+	}
+	for i := range s.shards {
+		s.shards[i].items = make(map[K]V, initialCapacity/shardCount)
+	}
+	return s
+}
+
+func (s *shardedStore[K, V]) shard(key K) *mapShard[K, V] {
+	return &s.shards[s.hasher.Hash(key)%uint64(len(s.shards))]
+}
+
+func (s *shardedStore[K, V]) Load(key K) (V, bool) {
+	shard := s.shard(key)
+	shard.mu.RLock()
+	defer shard.mu.RUnlock()
+
+	value, found := shard.items[key]
+	return value, found
+}
+
+func (s *shardedStore[K, V]) Has(key K) bool {
+	_, found := s.Load(key)
+	return found
+}
+
+func (s *shardedStore[K, V]) Store(key K, value V) {
+	shard := s.shard(key)
+	shard.mu.Lock()
+	defer shard.mu.Unlock()
+
+	shard.items[key] = value
+}
+
+func (s *shardedStore[K, V]) SetIf(key K, fn func(previous V, found bool) (value V, set bool)) {
+	shard := s.shard(key)
+	shard.mu.Lock()
+	defer shard.mu.Unlock()
+
+	previous, found := shard.items[key]
+	if value, set := fn(previous, found); set {
+		shard.items[key] = value
+	}
+}
+
+func (s *shardedStore[K, V]) Delete(key K) bool {
+	shard := s.shard(key)
+	shard.mu.Lock()
+	defer shard.mu.Unlock()
+
+	_, found := shard.items[key]
+	delete(shard.items, key)
+	return found
+}
+
+func (s *shardedStore[K, V]) DeleteIf(key K, condition func(value V) bool) bool {
+	shard := s.shard(key)
+	shard.mu.Lock()
+	defer shard.mu.Unlock()
+
+	value, found := shard.items[key]
+	if !found || !condition(value) {
+		return false
+	}
+	delete(shard.items, key)
+	return true
+}
+
+// Copies the items of a shard before calling fn, so fn can change the store.
+func (s *shardedStore[K, V]) Range(fn func(key K, value V) (stop bool)) {
+	type item struct {
+		key   K
+		value V
+	}
+
+	for i := range s.shards {
+		shard := &s.shards[i]
+
+		shard.mu.RLock()
+		items := make([]item, 0, len(shard.items))
+		for key, value := range shard.items {
+			items = append(items, item{key, value})
+		}
+		shard.mu.RUnlock()
+
+		for _, item := range items {
+			if fn(item.key, item.value) {
+				return
+			}
+		}
+	}
+}
+
+func (s *shardedStore[K, V]) Clear() {
+	for i := range s.shards {
+		shard := &s.shards[i]
+		shard.mu.Lock()
+		clear(shard.items)
+		shard.mu.Unlock()
+	}
+}
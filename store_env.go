@@ -0,0 +1,21 @@
+//go:build go_cache_store
+
+package cache
+
+import (
+	"fmt"
+	"os"
+)
+
+// Lets GO_CACHE_STORE (csmap, syncmap or sharded) select the default store when the tests are built
+// with '-tags go_cache_store', so the tests of every package that uses a cache run against that store.
+func init() {
+	if name := os.Getenv("GO_CACHE_STORE"); name != "" {
+		storeType, err := parseStoreType(name)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(2)
+		}
+		defaultStoreType = storeType
+	}
+}
@@ -0,0 +1,18 @@
+//go:build go_cache_store
+
+package cache
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStoreEnv(t *testing.T) {
+	name := os.Getenv("GO_CACHE_STORE")
+	if name == "" {
+		t.Skip("GO_CACHE_STORE is not set")
+	}
+	assert.Equal(t, name, defaultStoreType.String())
+}
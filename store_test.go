@@ -0,0 +1,155 @@
+package cache
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var storeTypes = []StoreType{StoreCSMap, StoreSyncMap, StoreShardedMap}
+
+func TestStore(t *testing.T) {
+	for _, storeType := range storeTypes {
+		t.Run(storeType.String(), func(t *testing.T) {
+			testStore(t, storeType)
+		})
+	}
+}
+
+func testStore(t *testing.T, storeType StoreType) {
+	TestStoreAndLoad := func(t *testing.T) {
+		s := newStore[int, []int](storeType, 4, 100)
+
+		s.Store(1, []int{1})
+
+		value, found := s.Load(1)
+		assert.True(t, found)
+		assert.Equal(t, []int{1}, value)
+		assert.True(t, s.Has(1))
+
+		_, found = s.Load(2)
+		assert.False(t, found)
+		assert.False(t, s.Has(2))
+
+		assert.True(t, s.Delete(1))
+		assert.False(t, s.Delete(1))
+		assert.False(t, s.Has(1))
+	}
+	t.Run("TestStoreAndLoad", TestStoreAndLoad)
+
+	TestSetIf := func(t *testing.T) {
+		s := newStore[int, []int](storeType, 0, 0)
+
+		s.SetIf(1, func(previous []int, found bool) ([]int, bool) {
+			assert.False(t, found)
+			return []int{1}, true
+		})
+		s.SetIf(1, func(previous []int, found bool) ([]int, bool) {
+			assert.True(t, found)
+			assert.Equal(t, []int{1}, previous)
+			return []int{2}, false
+		})
+
+		value, _ := s.Load(1)
+		assert.Equal(t, []int{1}, value)
+	}
+	t.Run("TestSetIf", TestSetIf)
+
+	TestSetIfConcurrently := func(t *testing.T) {
+		s := newStore[int, int](storeType, 0, 0)
+
+		var wg sync.WaitGroup
+		for range 50 {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for range 100 {
+					s.SetIf(1, func(previous int, _ bool) (int, bool) {
+						return previous + 1, true
+					})
+				}
+			}()
+		}
+		wg.Wait()
+
+		value, _ := s.Load(1)
+		assert.Equal(t, 5000, value)
+	}
+	t.Run("TestSetIfConcurrently", TestSetIfConcurrently)
+
+	TestDeleteIf := func(t *testing.T) {
+		s := newStore[int, int](storeType, 0, 0)
+		s.Store(1, 1)
+
+		assert.False(t, s.DeleteIf(1, func(value int) bool { return value == 2 }))
+		assert.True(t, s.Has(1))
+
+		assert.True(t, s.DeleteIf(1, func(value int) bool { return value == 1 }))
+		assert.False(t, s.Has(1))
+
+		assert.False(t, s.DeleteIf(1, func(int) bool { return true }))
+	}
+	t.Run("TestDeleteIf", TestDeleteIf)
+
+	TestRange := func(t *testing.T) {
+		s := newStore[int, int](storeType, 0, 0)
+		for i := range 100 {
+			s.Store(i, i)
+		}
+
+		seen := make(map[int]int)
+		s.Range(func(key int, value int) bool {
+			seen[key] = value
+			// changing the store while ranging must not deadlock
+			s.Delete(key)
+			return false
+		})
+		assert.Len(t, seen, 100)
+		assert.Equal(t, 50, seen[50])
+
+		s.Store(1, 1)
+		s.Store(2, 2)
+		count := 0
+		s.Range(func(int, int) bool {
+			count++
+			return true
+		})
+		assert.Equal(t, 1, count)
+	}
+	t.Run("TestRange", TestRange)
+
+	TestClear := func(t *testing.T) {
+		s := newStore[int, int](storeType, 0, 0)
+		for i := range 100 {
+			s.Store(i, i)
+		}
+
+		s.Clear()
+
+		count := 0
+		s.Range(func(int, int) bool {
+			count++
+			return false
+		})
+		assert.Zero(t, count)
+	}
+	t.Run("TestClear", TestClear)
+
+	TestCache := func(t *testing.T) {
+		cache := NewCache(
+			WithStore[int, int](storeType),
+			WithShardCount[int, int](8),
+			WithInitialCapacity[int, int](1000),
+		)
+		defer cache.Close()
+
+		cache.Put(1, 1)
+
+		value, found := cache.Get(1)
+		assert.True(t, found)
+		assert.Equal(t, 1, value)
+		assert.Equal(t, 1, cache.Count())
+	}
+	t.Run("TestCache", TestCache)
+}
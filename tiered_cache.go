@@ -0,0 +1,221 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+// Returned by the 'Load' function of a TieredCache that has no LoaderFunc.
+var ErrNoLoaderFunc = errors.New("no loader func")
+
+// A secondary (usually larger and slower) storage tier, e.g. a remote cache.
+type Store[K comparable, V any] interface {
+	// Get the value of a key, found is false when the key does not exist.
+	Get(ctx context.Context, key K) (value V, found bool, err error)
+
+	// Set the value of a key, a ttl of 0 means the value does not expire.
+	Set(ctx context.Context, key K, value V, ttl time.Duration) error
+
+	// Delete a key.
+	Delete(ctx context.Context, key K) error
+}
+
+type TieredOption[K comparable, V any] func(c *tieredCache[K, V])
+
+// A two-level cache with a (small) in-process L1 cache in front of a (larger) L2 store.
+type TieredCache[K comparable, V any] interface {
+	// Get an item from L1, on a miss it falls back to L2.
+	//
+	// Items found in L2 are put into L1, unless promotion is disabled.
+	Get(ctx context.Context, key K) (V, bool, error)
+
+	// Loads an item from L1, L2 or the LoaderFunc (in that order) and returns the value.
+	//
+	// Loaded items are put into L2 (unless write propagation is disabled) and L1. Just like 'LoadingCache',
+	// the LoaderFunc is called only once in a concurrent environment. Whenever L2 returns an error,
+	// the item is still put into L1 and the error is passed to the error handler.
+	//
+	// Returns ErrNoLoaderFunc when there is no LoaderFunc (see 'WithTieredLoader').
+	Load(ctx context.Context, key K) (V, error)
+
+	// Put an item into L2 (unless write propagation is disabled) and then into L1.
+	//
+	// Whenever L2 returns an error, the item is not put into L1.
+	Put(ctx context.Context, key K, value V) error
+
+	// Deletes an item from L2 (unless write propagation is disabled) and then from L1.
+	Delete(ctx context.Context, key K) error
+
+	// Returns the L1 cache.
+	L1() Cache[K, V]
+
+	// Closes the L1 cache.
+	Close()
+}
+
+// The function that loads items that are missing in both tiers.
+func WithTieredLoader[K comparable, V any](
+	loaderFunc LoaderFunc[K, V],
+) TieredOption[K, V] {
+	return func(c *tieredCache[K, V]) {
+		c.loaderFunc = loaderFunc
+	}
+}
+
+// The TTL of items that are written to L2, defaults to 0 (no expiration).
+func WithL2TTL[K comparable, V any](
+	ttl time.Duration,
+) TieredOption[K, V] {
+	return func(c *tieredCache[K, V]) {
+		c.l2TTL = ttl
+	}
+}
+
+// Whether items found in L2 are put into L1, defaults to true.
+func WithPromotion[K comparable, V any](
+	promote bool,
+) TieredOption[K, V] {
+	return func(c *tieredCache[K, V]) {
+		c.promote = promote
+	}
+}
+
+// Whether 'Put', 'Delete' and loaded items are written to L2, defaults to true.
+//
+// Disable it when L2 is kept up to date by another system and L1 is only a near cache.
+func WithWritePropagation[K comparable, V any](
+	propagate bool,
+) TieredOption[K, V] {
+	return func(c *tieredCache[K, V]) {
+		c.propagate = propagate
+	}
+}
+
+// Handles errors that don't fail the call (e.g. writing a loaded item to L2), defaults to logging them with slog.
+func WithTieredErrorHandler[K comparable, V any](
+	errorHandler func(err error),
+) TieredOption[K, V] {
+	return func(c *tieredCache[K, V]) {
+		c.errorHandler = errorHandler
+	}
+}
+
+type tieredCache[K comparable, V any] struct {
+	l1 Cache[K, V]
+	l2 Store[K, V]
+
+	mu         loaderMutex[K]
+	loaderFunc LoaderFunc[K, V]
+
+	l2TTL        time.Duration
+	promote      bool
+	propagate    bool
+	errorHandler func(err error)
+}
+
+func NewTieredCache[K comparable, V any](
+	l1 Cache[K, V],
+	l2 Store[K, V],
+	options ...TieredOption[K, V],
+) TieredCache[K, V] {
+	c := &tieredCache[K, V]{
+		l1:        l1,
+		l2:        l2,
+		promote:   true,
+		propagate: true,
+	}
+
+	for _, option := range options {
+		option(c)
+	}
+
+	return c
+}
+
+func (c *tieredCache[K, V]) Get(ctx context.Context, key K) (V, bool, error) {
+	if value, found := c.l1.Get(key); found {
+		return value, true, nil
+	}
+
+	value, found, err := c.l2.Get(ctx, key)
+	if err != nil || !found {
+		return value, false, err
+	}
+
+	if c.promote {
+		c.l1.Put(key, value)
+	}
+
+	return value, true, nil
+}
+
+func (c *tieredCache[K, V]) Load(ctx context.Context, key K) (V, error) {
+	if value, found := c.l1.Get(key); found {
+		return value, nil
+	}
+
+	unlock := c.mu.lock(key)
+	defer unlock()
+
+	value, found, err := c.Get(ctx, key)
+	if err != nil || found {
+		return value, err
+	}
+
+	if c.loaderFunc == nil {
+		return value, ErrNoLoaderFunc
+	}
+
+	value, err = c.loaderFunc(key)
+	if err != nil {
+		return value, err
+	}
+
+	if c.propagate {
+		if err := c.l2.Set(ctx, key, value, c.l2TTL); err != nil {
+			c.handleError(fmt.Errorf("set loaded %v: %w", key, err))
+		}
+	}
+	c.l1.Put(key, value)
+
+	return value, nil
+}
+
+func (c *tieredCache[K, V]) Put(ctx context.Context, key K, value V) error {
+	if c.propagate {
+		if err := c.l2.Set(ctx, key, value, c.l2TTL); err != nil {
+			return err
+		}
+	}
+	c.l1.Put(key, value)
+	return nil
+}
+
+func (c *tieredCache[K, V]) Delete(ctx context.Context, key K) error {
+	if c.propagate {
+		if err := c.l2.Delete(ctx, key); err != nil {
+			return err
+		}
+	}
+	c.l1.Delete(key)
+	return nil
+}
+
+func (c *tieredCache[K, V]) handleError(err error) {
+	if c.errorHandler != nil {
+		c.errorHandler(err)
+		return
+	}
+	slog.Error("go-cache", "error", err)
+}
+
+func (c *tieredCache[K, V]) L1() Cache[K, V] {
+	return c.l1
+}
+
+func (c *tieredCache[K, V]) Close() {
+	c.l1.Close()
+}
@@ -0,0 +1,181 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type failingStore[K comparable, V any] struct {
+	*MemoryStore[K, V]
+}
+
+func (*failingStore[K, V]) Set(context.Context, K, V, time.Duration) error {
+	return errors.New("set failed")
+}
+
+func TestTieredCache(t *testing.T) {
+	ctx := context.Background()
+
+	TestGetFromL2 := func(t *testing.T) {
+		l2 := NewMemoryStore[int, int](nil)
+		l2.Set(ctx, 1, 100, 0)
+
+		cache := NewTieredCache(NewCache[int, int](), l2)
+		defer cache.Close()
+
+		value, found, err := cache.Get(ctx, 1)
+		assert.NoError(t, err)
+		assert.True(t, found)
+		assert.Equal(t, 100, value)
+		assert.True(t, cache.L1().Has(1))
+
+		_, found, err = cache.Get(ctx, 2)
+		assert.NoError(t, err)
+		assert.False(t, found)
+	}
+	t.Run("TestGetFromL2", TestGetFromL2)
+
+	TestWithoutPromotion := func(t *testing.T) {
+		l2 := NewMemoryStore[int, int](nil)
+		l2.Set(ctx, 1, 100, 0)
+
+		cache := NewTieredCache(NewCache[int, int](), l2, WithPromotion[int, int](false))
+		defer cache.Close()
+
+		_, found, _ := cache.Get(ctx, 1)
+		assert.True(t, found)
+		assert.False(t, cache.L1().Has(1))
+	}
+	t.Run("TestWithoutPromotion", TestWithoutPromotion)
+
+	TestLoad := func(t *testing.T) {
+		counter := int64(0)
+		l2 := NewMemoryStore[int, int](nil)
+		l2.Set(ctx, 1, 100, 0)
+
+		cache := NewTieredCache(NewCache[int, int](), l2, WithTieredLoader(func(key int) (int, error) {
+			atomic.AddInt64(&counter, 1)
+			time.Sleep(time.Millisecond * 10)
+			return key * 2, nil
+		}))
+		defer cache.Close()
+
+		value, err := cache.Load(ctx, 1)
+		assert.NoError(t, err)
+		assert.Equal(t, 100, value)
+		assert.Zero(t, atomic.LoadInt64(&counter))
+
+		wg := new(sync.WaitGroup)
+		for i := 0; i < 5; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				value, err := cache.Load(ctx, 2)
+				assert.NoError(t, err)
+				assert.Equal(t, 4, value)
+			}()
+		}
+		wg.Wait()
+
+		assert.Equal(t, int64(1), atomic.LoadInt64(&counter))
+		assert.True(t, cache.L1().Has(2))
+
+		value, found, _ := l2.Get(ctx, 2)
+		assert.True(t, found)
+		assert.Equal(t, 4, value)
+	}
+	t.Run("TestLoad", TestLoad)
+
+	TestLoadWithoutLoader := func(t *testing.T) {
+		cache := NewTieredCache(NewCache[int, int](), NewMemoryStore[int, int](nil))
+		defer cache.Close()
+
+		_, err := cache.Load(ctx, 1)
+		assert.ErrorIs(t, err, ErrNoLoaderFunc)
+	}
+	t.Run("TestLoadWithoutLoader", TestLoadWithoutLoader)
+
+	TestPutAndDelete := func(t *testing.T) {
+		l2 := NewMemoryStore[int, int](nil)
+
+		cache := NewTieredCache(NewCache[int, int](), l2, WithL2TTL[int, int](time.Minute))
+		defer cache.Close()
+
+		assert.NoError(t, cache.Put(ctx, 1, 100))
+		assert.True(t, cache.L1().Has(1))
+		assert.Equal(t, 1, l2.Len())
+
+		assert.NoError(t, cache.Delete(ctx, 1))
+		assert.False(t, cache.L1().Has(1))
+		assert.Zero(t, l2.Len())
+	}
+	t.Run("TestPutAndDelete", TestPutAndDelete)
+
+	TestWithoutWritePropagation := func(t *testing.T) {
+		l2 := NewMemoryStore[int, int](nil)
+
+		cache := NewTieredCache(NewCache[int, int](), l2, WithWritePropagation[int, int](false))
+		defer cache.Close()
+
+		assert.NoError(t, cache.Put(ctx, 1, 100))
+		assert.True(t, cache.L1().Has(1))
+		assert.Zero(t, l2.Len())
+	}
+	t.Run("TestWithoutWritePropagation", TestWithoutWritePropagation)
+
+	TestPutError := func(t *testing.T) {
+		l2 := &failingStore[int, int]{NewMemoryStore[int, int](nil)}
+
+		cache := NewTieredCache[int, int](NewCache[int, int](), l2)
+		defer cache.Close()
+
+		assert.EqualError(t, cache.Put(ctx, 1, 100), "set failed")
+		assert.False(t, cache.L1().Has(1))
+	}
+	t.Run("TestPutError", TestPutError)
+
+	TestLoadWithoutWritePropagation := func(t *testing.T) {
+		l2 := NewMemoryStore[int, int](nil)
+
+		cache := NewTieredCache(
+			NewCache[int, int](),
+			l2,
+			WithTieredLoader(func(key int) (int, error) { return key, nil }),
+			WithWritePropagation[int, int](false),
+		)
+		defer cache.Close()
+
+		value, err := cache.Load(ctx, 1)
+		assert.NoError(t, err)
+		assert.Equal(t, 1, value)
+		assert.True(t, cache.L1().Has(1))
+		assert.Zero(t, l2.Len())
+	}
+	t.Run("TestLoadWithoutWritePropagation", TestLoadWithoutWritePropagation)
+
+	TestLoadL2Error := func(t *testing.T) {
+		l2 := &failingStore[int, int]{NewMemoryStore[int, int](nil)}
+
+		var handled error
+		cache := NewTieredCache(
+			NewCache[int, int](),
+			l2,
+			WithTieredLoader(func(key int) (int, error) { return key, nil }),
+			WithTieredErrorHandler[int, int](func(err error) { handled = err }),
+		)
+		defer cache.Close()
+
+		value, err := cache.Load(ctx, 1)
+		assert.NoError(t, err)
+		assert.Equal(t, 1, value)
+		assert.True(t, cache.L1().Has(1))
+		assert.ErrorContains(t, handled, "set failed")
+	}
+	t.Run("TestLoadL2Error", TestLoadL2Error)
+}
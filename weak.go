@@ -0,0 +1,130 @@
+package cache
+
+import (
+	"runtime"
+	"weak"
+)
+
+// Stores the values as weak pointers, so the cache does not keep values alive that are not used elsewhere.
+//
+// Values that have been reclaimed by the GC are a miss ('Load' loads them again) and are skipped
+// by 'Count' and 'ForEach'. Shortly after a value is reclaimed its item is removed and EventEvict
+// is published. A nil value is a miss as well.
+func WithWeakValues[K comparable, T any]() Option[K, *T] {
+	return func(c *cache[K, *T]) {
+		c.weakValues = func(key K, value *T) weakValue[*T] {
+			ref := &weakPointer[T]{p: weak.Make(value)}
+			if value != nil {
+				ref.cleanup = runtime.AddCleanup(value, c.reclaim, reclaimed[K, *T]{key, ref})
+			}
+			return ref
+		}
+	}
+}
+
+type weakValue[V any] interface {
+	value() (V, bool)
+	// Cancels the cleanup that removes the item once the value is reclaimed.
+	stop()
+}
+
+// Every stored entry has its own cleanup, so a value that is put many times doesn't pile up cleanups.
+type weakPointer[T any] struct {
+	p       weak.Pointer[T]
+	cleanup runtime.Cleanup
+}
+
+func (w *weakPointer[T]) value() (*T, bool) {
+	value := w.p.Value()
+	return value, value != nil
+}
+
+func (w *weakPointer[T]) stop() {
+	w.cleanup.Stop()
+}
+
+type reclaimed[K comparable, V any] struct {
+	key K
+	ref weakValue[V]
+}
+
+// Replaces the value of the entry by a weak pointer when 'WithWeakValues' is used.
+func (c *cache[K, V]) weaken(e entry[K, V]) entry[K, V] {
+	if c.weakValues == nil {
+		return e
+	}
+	e.weak = c.weakValues(e.key, e.value)
+	var empty V
+	e.value = empty
+	return e
+}
+
+// Removes the item of a reclaimed value, unless the item has been replaced in the meantime.
+func (c *cache[K, V]) reclaim(r reclaimed[K, V]) {
+	defer c.events.lockKey(r.key)()
+
+	deleted := c.data.DeleteIf(r.key, func(entry entry[K, V]) bool {
+		return entry.weak == r.ref
+	})
+	if deleted {
+		c.events.publish(Event[K, V]{Type: EventEvict, Key: r.key})
+	}
+}
+
+// Stops the cleanup of every entry that is replaced or removed, used with 'WithWeakValues'.
+type weakStore[K comparable, V any] struct {
+	store[K, entry[K, V]]
+}
+
+func (s weakStore[K, V]) Store(key K, value entry[K, V]) {
+	s.SetIf(key, func(entry[K, V], bool) (entry[K, V], bool) {
+		return value, true
+	})
+}
+
+func (s weakStore[K, V]) SetIf(key K, fn func(previous entry[K, V], found bool) (entry[K, V], bool)) {
+	var (
+		old, created entry[K, V]
+		replaced     bool
+	)
+	s.store.SetIf(key, func(previous entry[K, V], found bool) (entry[K, V], bool) {
+		value, set := fn(previous, found)
+		old, created, replaced = previous, value, found && set
+		return value, set
+	})
+	// a restamped entry keeps its weak value
+	if replaced && old.weak != created.weak {
+		stopCleanup(old)
+	}
+}
+
+func (s weakStore[K, V]) Delete(key K) bool {
+	return s.DeleteIf(key, func(entry[K, V]) bool {
+		return true
+	})
+}
+
+func (s weakStore[K, V]) DeleteIf(key K, condition func(value entry[K, V]) bool) bool {
+	var old entry[K, V]
+	deleted := s.store.DeleteIf(key, func(value entry[K, V]) bool {
+		old = value
+		return condition(value)
+	})
+	if deleted {
+		stopCleanup(old)
+	}
+	return deleted
+}
+
+func (s weakStore[K, V]) Clear() {
+	s.Range(func(key K, _ entry[K, V]) (stop bool) {
+		s.Delete(key)
+		return false
+	})
+}
+
+func stopCleanup[K comparable, V any](e entry[K, V]) {
+	if e.weak != nil {
+		e.weak.stop()
+	}
+}
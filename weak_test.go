@@ -0,0 +1,180 @@
+package cache
+
+import (
+	"runtime"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type stoppedValue[V any] struct {
+	v       V
+	stopped *atomic.Int32
+}
+
+func (w stoppedValue[V]) value() (V, bool) {
+	return w.v, true
+}
+
+func (w stoppedValue[V]) stop() {
+	w.stopped.Add(1)
+}
+
+// Large enough to not be batched by the tiny allocator, so it gets reclaimed on its own.
+type blob struct {
+	data [1024]byte
+}
+
+func TestWeakValues(t *testing.T) {
+	TestGet := func(t *testing.T) {
+		cache := NewCache(WithWeakValues[int, blob]())
+		defer cache.Close()
+
+		value := &blob{}
+		cache.Put(1, value)
+
+		actual, found := cache.Get(1)
+		assert.True(t, found)
+		assert.Same(t, value, actual)
+		assert.Equal(t, 1, cache.Count())
+
+		runtime.KeepAlive(value)
+	}
+	t.Run("TestGet", TestGet)
+
+	TestReclaimed := func(t *testing.T) {
+		cache := NewCache(WithWeakValues[int, blob]())
+		defer cache.Close()
+
+		eventchn, cancel := cache.Subscribe(10)
+		defer cancel()
+
+		cache.Put(1, &blob{})
+		assert.Equal(t, EventPut, (<-eventchn).Type)
+
+		runtime.GC()
+
+		_, found := cache.Get(1)
+		assert.False(t, found)
+		assert.Zero(t, cache.Count())
+		cache.ForEach(func(int, *blob) {
+			assert.Fail(t, "reclaimed values must be skipped")
+		})
+
+		assert.Eventually(t, func() bool {
+			runtime.GC()
+			select {
+			case event := <-eventchn:
+				return event.Type == EventEvict && event.Key == 1
+			default:
+				return false
+			}
+		}, time.Second, time.Millisecond*10)
+	}
+	t.Run("TestReclaimed", TestReclaimed)
+
+	TestNilValue := func(t *testing.T) {
+		cache := NewCache(WithWeakValues[int, blob]())
+		defer cache.Close()
+
+		cache.Put(1, nil)
+
+		assert.False(t, cache.Has(1))
+	}
+	t.Run("TestNilValue", TestNilValue)
+
+	TestReclaimReplacedItem := func(t *testing.T) {
+		cache := newCache(WithWeakValues[int, blob]())
+		defer cache.Close()
+
+		old := cache.weakValues(1, &blob{})
+
+		value := &blob{}
+		cache.Put(1, value)
+
+		cache.reclaim(reclaimed[int, *blob]{key: 1, ref: old})
+
+		actual, found := cache.Get(1)
+		assert.True(t, found)
+		assert.Same(t, value, actual)
+
+		runtime.KeepAlive(value)
+	}
+	t.Run("TestReclaimReplacedItem", TestReclaimReplacedItem)
+
+	TestLoad := func(t *testing.T) {
+		var calls atomic.Int32
+		cache := NewLoadingCache(func(key int) (*blob, error) {
+			calls.Add(1)
+			return &blob{}, nil
+		}, WithWeakValues[int, blob]())
+		defer cache.Close()
+
+		value, err := cache.Load(1)
+		assert.NoError(t, err)
+
+		_, err = cache.Load(1)
+		assert.NoError(t, err)
+		assert.Equal(t, int32(1), calls.Load())
+
+		runtime.KeepAlive(value)
+		value = nil
+		runtime.GC()
+
+		_, err = cache.Load(1)
+		assert.NoError(t, err)
+		assert.Equal(t, int32(2), calls.Load())
+	}
+	t.Run("TestLoad", TestLoad)
+
+	TestRestamp := func(t *testing.T) {
+		cache := NewCache(WithWeakValues[int, blob]())
+		defer cache.Close()
+
+		value := &blob{}
+		cache.Put(1, value)
+		cache.Policy().SetExpireAfterWrite(time.Minute, true)
+
+		actual, found := cache.Get(1)
+		assert.True(t, found)
+		assert.Same(t, value, actual)
+
+		runtime.KeepAlive(value)
+	}
+	t.Run("TestRestamp", TestRestamp)
+
+	TestStopCleanup := func(t *testing.T) {
+		cache := newCache(WithWeakValues[int, blob]())
+		defer cache.Close()
+
+		var stopped atomic.Int32
+		cache.weakValues = func(key int, value *blob) weakValue[*blob] {
+			return &stoppedValue[*blob]{value, &stopped}
+		}
+
+		value := &blob{}
+		for range 3 {
+			cache.Put(1, value)
+		}
+		// the cleanups of the replaced entries are stopped
+		assert.Equal(t, int32(2), stopped.Load())
+
+		cache.Policy().SetExpireAfterWrite(time.Minute, true)
+		assert.Equal(t, int32(2), stopped.Load())
+
+		cache.Delete(1)
+		assert.Equal(t, int32(3), stopped.Load())
+
+		cache.Put(1, value)
+		cache.Put(2, value)
+		cache.Clear()
+		assert.Equal(t, int32(5), stopped.Load())
+
+		cache.Put(1, value)
+		cache.Close()
+		assert.Equal(t, int32(6), stopped.Load())
+	}
+	t.Run("TestStopCleanup", TestStopCleanup)
+}
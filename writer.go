@@ -0,0 +1,252 @@
+package cache
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// The number of times a write-behind change is tried before it is dropped.
+const writeBehindAttempts = 3
+
+// The flush interval of write-behind when neither a batch size nor a flush interval is given.
+const defaultWriteBehindFlushInterval = time.Second
+
+// Writes changes made with 'Put' and 'Delete' to an external system (e.g. a database).
+//
+// Values stored by 'Load' and 'Reload' are not written, they originate from the external system.
+type Writer[K comparable, V any] interface {
+	// Write the value of the key.
+	Write(key K, value V) error
+
+	// Delete the key.
+	Delete(key K) error
+}
+
+// Write changes synchronously to the writer before they are applied to the cache.
+//
+// Whenever the writer returns an error, the cache is not changed. 'TryPut' and 'TryDelete'
+// return the error, 'Put' and 'Delete' pass it to the error handler.
+//
+// Changes of a key are serialized with the loads of that key, so the writer and the cache apply them
+// in the same order. The writer and the LoaderFunc may change other keys, but a change of the same key
+// waits until the callback has returned: calling 'Put' or 'Delete' of that key from the callback (instead
+// of another goroutine) deadlocks.
+func WithWriteThrough[K comparable, V any](
+	writer Writer[K, V],
+) Option[K, V] {
+	return func(c *cache[K, V]) {
+		c.writeThrough = writer
+		c.writeBehind = nil
+	}
+}
+
+// Write changes asynchronously to the writer after they are applied to the cache.
+//
+// Changes are coalesced per key (only the latest change is written) and flushed at every interval,
+// whenever batchSize changes are pending and when the cache is closed. A failing change is retried
+// on the next flush, after 3 failed attempts it is dropped and passed to the error handler.
+//
+// A batchSize or flushInterval of 0 disables that trigger, whenever both are disabled the flush
+// interval defaults to 1 second.
+func WithWriteBehind[K comparable, V any](
+	writer Writer[K, V],
+	batchSize int,
+	flushInterval time.Duration,
+) Option[K, V] {
+	if batchSize <= 0 && flushInterval <= 0 {
+		flushInterval = defaultWriteBehindFlushInterval
+	}
+	return func(c *cache[K, V]) {
+		c.writeBehind = &writeBehind[K, V]{
+			writer:        writer,
+			batchSize:     batchSize,
+			flushInterval: flushInterval,
+			pending:       make(map[K]*writeOp[V]),
+		}
+		c.writeThrough = nil
+	}
+}
+
+type writeOp[V any] struct {
+	value    V
+	delete   bool
+	attempts int
+}
+
+type writeBehind[K comparable, V any] struct {
+	writer        Writer[K, V]
+	batchSize     int
+	flushInterval time.Duration
+
+	mu      sync.Mutex
+	pending map[K]*writeOp[V]
+	closing bool
+
+	// serializes flushes, so changes of a key are written in order
+	flushMu sync.Mutex
+	stop    func()
+	wg      sync.WaitGroup
+}
+
+func (c *cache[K, V]) TryPut(key K, value V) error {
+	return c.tryPut(c.newEntry(key, value))
+}
+
+func (c *cache[K, V]) tryPut(created entry[K, V]) error {
+	key, value := created.key, created.value
+
+	// the writer and the cache apply concurrent changes of a key in the same order
+	if c.writeThrough != nil {
+		unlock := c.mu.lock(key)
+		defer unlock()
+	}
+
+	// Close can't clear the items or flush the pending writes in between checking 'closed' and applying the change
+	c.lifecycle.RLock()
+	defer c.lifecycle.RUnlock()
+
+	if c.closed.Load() {
+		return ErrClosed
+	}
+
+	if c.writeThrough != nil {
+		if err := c.writeThrough.Write(key, value); err != nil {
+			return err
+		}
+	}
+
+	c.storeEntry(created)
+	c.broadcastKey(key)
+
+	if c.writeBehind != nil {
+		c.enqueueWrite(key, &writeOp[V]{value: value})
+	}
+
+	return nil
+}
+
+func (c *cache[K, V]) TryDelete(key K) error {
+	if c.writeThrough != nil {
+		unlock := c.mu.lock(key)
+		defer unlock()
+	}
+
+	c.lifecycle.RLock()
+	defer c.lifecycle.RUnlock()
+
+	if c.closed.Load() {
+		return ErrClosed
+	}
+
+	if c.writeThrough != nil {
+		if err := c.writeThrough.Delete(key); err != nil {
+			return err
+		}
+	}
+
+	c.remove(key)
+	c.broadcastKey(key)
+
+	if c.writeBehind != nil {
+		c.enqueueWrite(key, &writeOp[V]{delete: true})
+	}
+
+	return nil
+}
+
+func (c *cache[K, V]) startWriteBehind() {
+	wb := c.writeBehind
+	if wb == nil || wb.flushInterval <= 0 {
+		return
+	}
+	wb.stop = c.clock.NewTicker(wb.flushInterval, func() {
+		c.flushWrites(false)
+	})
+}
+
+// Stops the periodic flush and flushes all pending changes.
+func (c *cache[K, V]) stopWriteBehind() {
+	wb := c.writeBehind
+	if wb == nil {
+		return
+	}
+	if wb.stop != nil {
+		wb.stop()
+	}
+
+	// no flush can be started once closing
+	wb.mu.Lock()
+	wb.closing = true
+	wb.mu.Unlock()
+
+	wb.wg.Wait()
+	c.flushWrites(true)
+}
+
+func (c *cache[K, V]) enqueueWrite(key K, op *writeOp[V]) {
+	wb := c.writeBehind
+
+	wb.mu.Lock()
+	wb.pending[key] = op
+	full := wb.batchSize > 0 && len(wb.pending) >= wb.batchSize && !wb.closing
+	if full {
+		wb.wg.Add(1)
+	}
+	wb.mu.Unlock()
+
+	if full {
+		go func() {
+			defer wb.wg.Done()
+			c.flushWrites(false)
+		}()
+	}
+}
+
+// Writes all pending changes, failed changes are retried on the next flush or right away when final.
+func (c *cache[K, V]) flushWrites(final bool) {
+	wb := c.writeBehind
+
+	wb.flushMu.Lock()
+	defer wb.flushMu.Unlock()
+
+	for {
+		wb.mu.Lock()
+		batch := wb.pending
+		wb.pending = make(map[K]*writeOp[V])
+		wb.mu.Unlock()
+
+		if len(batch) == 0 {
+			return
+		}
+
+		retry := false
+		for key, op := range batch {
+			if err := c.write(key, op); err != nil {
+				op.attempts++
+				if op.attempts >= writeBehindAttempts {
+					c.handleError(fmt.Errorf("write behind %v: %w", key, err))
+					continue
+				}
+				retry = true
+				wb.mu.Lock()
+				// a newer change of the key replaces the failed change
+				if _, found := wb.pending[key]; !found {
+					wb.pending[key] = op
+				}
+				wb.mu.Unlock()
+			}
+		}
+
+		if !final || !retry {
+			return
+		}
+	}
+}
+
+func (c *cache[K, V]) write(key K, op *writeOp[V]) error {
+	if op.delete {
+		return c.writeBehind.writer.Delete(key)
+	}
+	return c.writeBehind.writer.Write(key, op.value)
+}
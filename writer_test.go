@@ -0,0 +1,336 @@
+package cache
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/larscom/go-cache/cachetest"
+	"github.com/stretchr/testify/assert"
+)
+
+type mockWriter struct {
+	mu       sync.Mutex
+	values   map[int]int
+	writes   int
+	failures int
+}
+
+func newMockWriter() *mockWriter {
+	return &mockWriter{values: make(map[int]int)}
+}
+
+func (w *mockWriter) Write(key int, value int) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.writes++
+	if w.failures > 0 {
+		w.failures--
+		return errors.New("write failed")
+	}
+	w.values[key] = value
+	return nil
+}
+
+func (w *mockWriter) Delete(key int) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.writes++
+	if w.failures > 0 {
+		w.failures--
+		return errors.New("delete failed")
+	}
+	delete(w.values, key)
+	return nil
+}
+
+func (w *mockWriter) snapshot() (map[int]int, int) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	values := make(map[int]int, len(w.values))
+	for k, v := range w.values {
+		values[k] = v
+	}
+	return values, w.writes
+}
+
+type writerFunc func(key int, value int) error
+
+func (fn writerFunc) Write(key int, value int) error {
+	return fn(key, value)
+}
+
+func (fn writerFunc) Delete(key int) error {
+	return nil
+}
+
+func TestWriteThrough(t *testing.T) {
+	TestPutAndDelete := func(t *testing.T) {
+		writer := newMockWriter()
+		cache := NewCache(WithWriteThrough[int, int](writer))
+		defer cache.Close()
+
+		assert.NoError(t, cache.TryPut(1, 100))
+		cache.Put(2, 200)
+		assert.NoError(t, cache.TryDelete(1))
+
+		values, _ := writer.snapshot()
+		assert.Equal(t, map[int]int{2: 200}, values)
+		assert.Equal(t, 1, cache.Count())
+	}
+	t.Run("TestPutAndDelete", TestPutAndDelete)
+
+	TestError := func(t *testing.T) {
+		writer := newMockWriter()
+
+		var handled error
+		cache := NewCache(
+			WithWriteThrough[int, int](writer),
+			WithErrorHandler[int, int](func(err error) { handled = err }),
+		)
+		defer cache.Close()
+
+		cache.Put(1, 100)
+
+		writer.failures = 1
+		assert.EqualError(t, cache.TryPut(1, 200), "write failed")
+
+		writer.failures = 1
+		cache.Delete(1)
+		assert.ErrorContains(t, handled, "delete failed")
+
+		value, _ := cache.Get(1)
+		assert.Equal(t, 100, value)
+	}
+	t.Run("TestError", TestError)
+
+	TestLoadIsNotWritten := func(t *testing.T) {
+		writer := newMockWriter()
+		cache := NewLoadingCache(func(key int) (int, error) {
+			return key, nil
+		}, WithWriteThrough[int, int](writer))
+		defer cache.Close()
+
+		cache.Load(1)
+
+		_, writes := writer.snapshot()
+		assert.Zero(t, writes)
+	}
+	t.Run("TestLoadIsNotWritten", TestLoadIsNotWritten)
+
+	TestClosed := func(t *testing.T) {
+		cache := NewCache(WithWriteThrough[int, int](newMockWriter()))
+		cache.Close()
+
+		assert.ErrorIs(t, cache.TryPut(1, 100), ErrClosed)
+		assert.ErrorIs(t, cache.TryDelete(1), ErrClosed)
+	}
+	t.Run("TestClosed", TestClosed)
+
+	TestConcurrentPutsOfKey := func(t *testing.T) {
+		writer := newMockWriter()
+		cache := NewCache(WithWriteThrough[int, int](writer))
+		defer cache.Close()
+
+		var wg sync.WaitGroup
+		for i := range 50 {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				cache.Put(1, i)
+			}()
+		}
+		wg.Wait()
+
+		values, _ := writer.snapshot()
+		value, _ := cache.Get(1)
+		assert.Equal(t, values[1], value)
+	}
+	t.Run("TestConcurrentPutsOfKey", TestConcurrentPutsOfKey)
+
+	TestChangeFromWriter := func(t *testing.T) {
+		var cache Cache[int, int]
+		nested := make(chan struct{})
+
+		cache = NewCache(WithWriteThrough[int, int](writerFunc(func(key int, value int) error {
+			if key != 1 || value != 100 {
+				return nil
+			}
+
+			// other keys can be changed from the writer
+			cache.Put(2, 200)
+
+			// a change of the same key waits until the writer returns
+			go func() {
+				cache.Put(1, 300)
+				close(nested)
+			}()
+			select {
+			case <-nested:
+				t.Error("change of the same key did not wait for the writer")
+			case <-time.After(time.Millisecond * 50):
+			}
+			return nil
+		})))
+		defer cache.Close()
+
+		assert.NoError(t, cache.TryPut(1, 100))
+		<-nested
+
+		value, _ := cache.Get(1)
+		assert.Equal(t, 300, value)
+		value, _ = cache.Get(2)
+		assert.Equal(t, 200, value)
+	}
+	t.Run("TestChangeFromWriter", TestChangeFromWriter)
+}
+
+func TestWriteBehind(t *testing.T) {
+	TestFlushAtInterval := func(t *testing.T) {
+		writer := newMockWriter()
+		clock := cachetest.NewFakeClock(time.Now())
+
+		cache := NewCache(
+			WithWriteBehind[int, int](writer, 100, time.Second),
+			WithClock[int, int](clock),
+		)
+		defer cache.Close()
+
+		cache.Put(1, 100)
+		cache.Put(1, 200)
+		cache.Put(2, 200)
+		cache.Delete(2)
+
+		_, writes := writer.snapshot()
+		assert.Zero(t, writes)
+
+		clock.Advance(time.Second)
+
+		values, writes := writer.snapshot()
+		assert.Equal(t, map[int]int{1: 200}, values)
+		assert.Equal(t, 2, writes)
+	}
+	t.Run("TestFlushAtInterval", TestFlushAtInterval)
+
+	TestFlushOnBatchSize := func(t *testing.T) {
+		writer := newMockWriter()
+		cache := NewCache(WithWriteBehind[int, int](writer, 2, time.Hour))
+		defer cache.Close()
+
+		cache.Put(1, 100)
+		cache.Put(2, 200)
+
+		assert.Eventually(t, func() bool {
+			values, _ := writer.snapshot()
+			return len(values) == 2
+		}, time.Second, time.Millisecond)
+	}
+	t.Run("TestFlushOnBatchSize", TestFlushOnBatchSize)
+
+	TestFlushOnClose := func(t *testing.T) {
+		writer := newMockWriter()
+		cache := NewCache(WithWriteBehind[int, int](writer, 100, time.Hour))
+
+		cache.Put(1, 100)
+		cache.Close()
+
+		values, _ := writer.snapshot()
+		assert.Equal(t, map[int]int{1: 100}, values)
+	}
+	t.Run("TestFlushOnClose", TestFlushOnClose)
+
+	TestPutWhileClosing := func(t *testing.T) {
+		writer := newMockWriter()
+		cache := NewCache(WithWriteBehind[int, int](writer, 100, time.Hour))
+
+		var (
+			wg      sync.WaitGroup
+			mu      sync.Mutex
+			applied = make(map[int]int)
+		)
+		for i := range 10 {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for j := range 100 {
+					key := i*100 + j
+					if cache.TryPut(key, j) == nil {
+						mu.Lock()
+						applied[key] = j
+						mu.Unlock()
+					}
+				}
+			}()
+		}
+		cache.Close()
+		wg.Wait()
+
+		values, _ := writer.snapshot()
+		assert.Equal(t, applied, values)
+	}
+	t.Run("TestPutWhileClosing", TestPutWhileClosing)
+
+	TestDefaultFlushInterval := func(t *testing.T) {
+		writer := newMockWriter()
+		clock := cachetest.NewFakeClock(time.Now())
+
+		cache := NewCache(
+			WithWriteBehind[int, int](writer, 0, 0),
+			WithClock[int, int](clock),
+		)
+		defer cache.Close()
+
+		cache.Put(1, 100)
+
+		clock.Advance(defaultWriteBehindFlushInterval)
+
+		values, _ := writer.snapshot()
+		assert.Equal(t, map[int]int{1: 100}, values)
+	}
+	t.Run("TestDefaultFlushInterval", TestDefaultFlushInterval)
+
+	TestRetry := func(t *testing.T) {
+		writer := newMockWriter()
+		writer.failures = 2
+		clock := cachetest.NewFakeClock(time.Now())
+
+		cache := NewCache(
+			WithWriteBehind[int, int](writer, 100, time.Second),
+			WithClock[int, int](clock),
+		)
+		defer cache.Close()
+
+		cache.Put(1, 100)
+
+		clock.Advance(time.Second)
+		clock.Advance(time.Second)
+		values, _ := writer.snapshot()
+		assert.Empty(t, values)
+
+		clock.Advance(time.Second)
+		values, _ = writer.snapshot()
+		assert.Equal(t, map[int]int{1: 100}, values)
+	}
+	t.Run("TestRetry", TestRetry)
+
+	TestDropAfterAttempts := func(t *testing.T) {
+		writer := newMockWriter()
+		writer.failures = writeBehindAttempts
+
+		var handled error
+		cache := NewCache(
+			WithWriteBehind[int, int](writer, 100, time.Hour),
+			WithErrorHandler[int, int](func(err error) { handled = err }),
+		)
+
+		cache.Put(1, 100)
+		cache.Close()
+
+		values, writes := writer.snapshot()
+		assert.Empty(t, values)
+		assert.Equal(t, writeBehindAttempts, writes)
+		assert.ErrorContains(t, handled, "write failed")
+	}
+	t.Run("TestDropAfterAttempts", TestDropAfterAttempts)
+}